@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
 	"strings"
@@ -11,9 +12,18 @@ import (
 
 	"github.com/anatoly_dev/go-ws-notifications/config"
 	"github.com/anatoly_dev/go-ws-notifications/internal/application"
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+	"github.com/anatoly_dev/go-ws-notifications/internal/infrastructure/channelpolicy"
+	"github.com/anatoly_dev/go-ws-notifications/internal/infrastructure/diagnostics"
+	"github.com/anatoly_dev/go-ws-notifications/internal/infrastructure/directory"
+	"github.com/anatoly_dev/go-ws-notifications/internal/infrastructure/email"
 	"github.com/anatoly_dev/go-ws-notifications/internal/infrastructure/http"
 	"github.com/anatoly_dev/go-ws-notifications/internal/infrastructure/kafka"
+	"github.com/anatoly_dev/go-ws-notifications/internal/infrastructure/metrics"
 	"github.com/anatoly_dev/go-ws-notifications/internal/infrastructure/repository"
+	"github.com/anatoly_dev/go-ws-notifications/internal/infrastructure/tracing"
+	"github.com/anatoly_dev/go-ws-notifications/internal/infrastructure/webhook"
+	"github.com/anatoly_dev/go-ws-notifications/internal/infrastructure/webpush"
 	"github.com/anatoly_dev/go-ws-notifications/internal/infrastructure/websocket"
 	"github.com/anatoly_dev/go-ws-notifications/pkg/logger"
 )
@@ -21,11 +31,34 @@ import (
 type App struct {
 	cfg              *config.Config
 	logger           *logger.Logger
-	notificationRepo *repository.MemoryRepository
+	notificationRepo domain.NotificationRepository
+	scheduleStore    domain.ScheduleStore
+	recurringStore   domain.RecurringJobStore
+	preferenceStore  domain.PreferenceStore
+	broadcastStore   domain.BroadcastStore
+	offlineQueue     domain.OfflineQueue
+	pushStore        domain.PushSubscriptionStore
+	pushSender       domain.PushSender
+	vapidPublicKey   string
+	emailSender      domain.EmailSender
+	webhookStore     domain.WebhookStore
+	webhookLog       domain.WebhookDeliveryStore
+	groupDirectory   domain.GroupDirectory
+	channelSvc       *application.ChannelService
+	broadcastSvc     *application.BroadcastService
+	storageCloser    io.Closer
+	storageBackup    http.BackupProvider
+	deliveryTracker  *diagnostics.DeliveryTracker
 	wsService        *websocket.Service
 	notificationSvc  *application.NotificationService
 	server           *http.Server
 	kafkaConsumer    *kafka.Consumer
+	expirySvc        *application.ExpiryService
+	scheduler        *application.Scheduler
+	recurringSvc     *application.RecurringService
+	digestSvc        *application.DigestService
+	webhookSvc       *application.WebhookService
+	shutdownTracing  func(context.Context) error
 }
 
 func main() {
@@ -49,7 +82,20 @@ func NewApp() *App {
 	fmt.Printf("Загружена конфигурация Kafka: brokers=%v, topic=%s\n",
 		cfg.Kafka.Brokers, cfg.Kafka.Topic)
 
-	appLogger, err := logger.NewLogger("info", false)
+	loggerConfig := logger.Config{
+		Level:       cfg.Logging.Level,
+		Encoding:    cfg.Logging.Encoding,
+		OutputPaths: cfg.Logging.OutputPaths,
+		DebugUsers:  cfg.Logging.DebugUsers,
+	}
+	if cfg.Logging.Sampling.Enabled {
+		loggerConfig.Sampling = &logger.SamplingConfig{
+			Initial:    cfg.Logging.Sampling.Initial,
+			Thereafter: cfg.Logging.Sampling.Thereafter,
+		}
+	}
+
+	appLogger, err := logger.New(loggerConfig)
 	if err != nil {
 		panic("Ошибка инициализации логгера: " + err.Error())
 	}
@@ -62,23 +108,347 @@ func NewApp() *App {
 	}
 }
 
+func (a *App) InitializeTracing() error {
+	shutdown, err := tracing.Init(context.Background(), &tracing.Config{
+		Enabled:     a.cfg.Tracing.Enabled,
+		ServiceName: a.cfg.Tracing.ServiceName,
+		Endpoint:    a.cfg.Tracing.Endpoint,
+		Insecure:    a.cfg.Tracing.Insecure,
+		SampleRatio: a.cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		return err
+	}
+
+	a.shutdownTracing = shutdown
+
+	if a.cfg.Tracing.Enabled {
+		a.logger.WithField("endpoint", a.cfg.Tracing.Endpoint).Info("Экспорт трассировок OpenTelemetry включен")
+	}
+
+	return nil
+}
+
+func (a *App) InitializeStorage() error {
+	switch a.cfg.Storage.Driver {
+	case repository.DriverPostgres, repository.DriverSQLite:
+		db, err := repository.OpenSQLDatabase(&repository.SQLConfig{
+			Driver:          a.cfg.Storage.Driver,
+			DSN:             a.cfg.Storage.DSN,
+			MaxOpenConns:    a.cfg.Storage.MaxOpenConns,
+			MaxIdleConns:    a.cfg.Storage.MaxIdleConns,
+			ConnMaxLifetime: a.cfg.Storage.ConnMaxLifetime,
+		}, a.logger)
+		if err != nil {
+			return err
+		}
+		a.storageCloser = db
+		a.notificationRepo = repository.NewSQLRepository(db, a.logger)
+		a.scheduleStore = repository.NewSQLScheduleStore(db)
+		a.recurringStore = repository.NewSQLRecurringStore(db)
+		a.preferenceStore = repository.NewSQLPreferenceStore(db)
+		a.broadcastStore = repository.NewSQLBroadcastStore(db)
+		a.offlineQueue = repository.NewSQLOfflineQueue(db)
+		a.pushStore = repository.NewSQLPushStore(db)
+		a.webhookStore = repository.NewSQLWebhookStore(db)
+		a.webhookLog = repository.NewSQLWebhookDeliveryStore(db)
+	case repository.DriverBolt:
+		db, err := repository.OpenBoltDatabase(&repository.BoltConfig{
+			Path: a.cfg.Storage.Path,
+		}, a.logger)
+		if err != nil {
+			return err
+		}
+		a.storageCloser = db
+		a.storageBackup = db
+
+		if a.notificationRepo, err = repository.NewBoltRepository(db, a.logger); err != nil {
+			return err
+		}
+		if a.scheduleStore, err = repository.NewBoltScheduleStore(db); err != nil {
+			return err
+		}
+		if a.recurringStore, err = repository.NewBoltRecurringStore(db); err != nil {
+			return err
+		}
+		if a.preferenceStore, err = repository.NewBoltPreferenceStore(db); err != nil {
+			return err
+		}
+		if a.broadcastStore, err = repository.NewBoltBroadcastStore(db); err != nil {
+			return err
+		}
+		if a.offlineQueue, err = repository.NewBoltOfflineQueue(db); err != nil {
+			return err
+		}
+		if a.pushStore, err = repository.NewBoltPushStore(db); err != nil {
+			return err
+		}
+		if a.webhookStore, err = repository.NewBoltWebhookStore(db); err != nil {
+			return err
+		}
+		if a.webhookLog, err = repository.NewBoltWebhookDeliveryStore(db); err != nil {
+			return err
+		}
+	default:
+		repo := repository.NewMemoryRepository(&repository.RetentionConfig{
+			MaxAge:             a.cfg.Storage.Retention.MaxAge,
+			MaxPerUser:         a.cfg.Storage.Retention.MaxPerUser,
+			MaxTotal:           a.cfg.Storage.Retention.MaxTotal,
+			CompactionInterval: a.cfg.Storage.Retention.CompactionInterval,
+		}, a.logger)
+		repo.StartCompaction()
+		a.notificationRepo = repo
+		a.storageCloser = repo
+		a.scheduleStore = repository.NewMemoryScheduleStore()
+		a.recurringStore = repository.NewMemoryRecurringStore()
+		a.preferenceStore = repository.NewMemoryPreferenceStore()
+		a.broadcastStore = repository.NewMemoryBroadcastStore()
+		a.offlineQueue = repository.NewMemoryOfflineQueue()
+		a.pushStore = repository.NewMemoryPushStore()
+		a.webhookStore = repository.NewMemoryWebhookStore()
+		a.webhookLog = repository.NewMemoryWebhookDeliveryStore()
+	}
+
+	a.logger.WithField("driver", a.cfg.Storage.Driver).Info("Хранилище уведомлений инициализировано")
+
+	return nil
+}
+
+func (a *App) InitializeDirectory() error {
+	switch a.cfg.Directory.Driver {
+	case directory.DriverFile:
+		fileDirectory, err := directory.NewFileDirectory(a.cfg.Directory.Path, a.logger)
+		if err != nil {
+			return err
+		}
+		a.groupDirectory = fileDirectory
+	case directory.DriverHTTP:
+		a.groupDirectory = directory.NewHTTPDirectory(a.cfg.Directory.URL, a.cfg.Directory.Timeout, a.cfg.Directory.CacheTTL)
+	default:
+		a.groupDirectory = directory.NewStaticDirectory(a.cfg.Directory.Groups)
+	}
+
+	a.logger.WithField("driver", a.cfg.Directory.Driver).Info("Каталог групп инициализирован")
+
+	return nil
+}
+
+func (a *App) InitializePush() error {
+	if !a.cfg.Push.Enabled {
+		return nil
+	}
+
+	keys, err := webpush.ParseVAPIDKeys(a.cfg.Push.VAPIDPrivateKey, a.cfg.Push.VAPIDPublicKey)
+	if err != nil {
+		return err
+	}
+
+	a.pushSender = webpush.NewSender(keys, a.cfg.Push.Subject, a.cfg.Push.Timeout)
+	a.vapidPublicKey = keys.PublicKey()
+
+	a.logger.WithField("minPriority", a.cfg.Push.MinPriority).Info("Web Push включен")
+
+	return nil
+}
+
+func (a *App) InitializeEmail() {
+	if !a.cfg.Email.Enabled {
+		return
+	}
+
+	a.emailSender = email.NewSMTPSender(&email.Config{
+		Host:     a.cfg.Email.Host,
+		Port:     a.cfg.Email.Port,
+		Username: a.cfg.Email.Username,
+		Password: a.cfg.Email.Password,
+		From:     a.cfg.Email.From,
+		TLS:      a.cfg.Email.TLS,
+		Timeout:  a.cfg.Email.Timeout,
+	})
+
+	a.logger.WithFields(map[string]interface{}{
+		"host": a.cfg.Email.Host,
+		"port": a.cfg.Email.Port,
+	}).Info("Доставка по почте включена")
+}
+
+func (a *App) newDeliveryChains() *application.DeliveryChains {
+	chains := &application.DeliveryChains{
+		ByType: make(map[domain.NotificationType][]domain.DeliveryStep, len(a.cfg.Delivery.Chains)),
+		Always: a.cfg.Delivery.Always,
+	}
+
+	for key, steps := range a.cfg.Delivery.Chains {
+		chain := make([]domain.DeliveryStep, 0, len(steps))
+		for _, step := range steps {
+			chain = append(chain, domain.DeliveryStep{Channel: step.Channel, After: step.After})
+		}
+
+		if key == "default" {
+			chains.Default = chain
+		} else {
+			chains.ByType[domain.NotificationType(key)] = chain
+		}
+	}
+
+	return chains
+}
+
+func (a *App) newChannelPolicy() domain.ChannelPolicy {
+	if a.cfg.Channels.Policy != channelpolicy.DriverRules {
+		return channelpolicy.NewAllowAllPolicy()
+	}
+
+	rules := make([]channelpolicy.Rule, 0, len(a.cfg.Channels.Rules))
+	for _, rule := range a.cfg.Channels.Rules {
+		rules = append(rules, channelpolicy.Rule{
+			Pattern: rule.Pattern,
+			Users:   rule.Users,
+			Groups:  rule.Groups,
+		})
+	}
+
+	return channelpolicy.NewRulePolicy(rules, a.groupDirectory)
+}
+
 func (a *App) InitializeServices() {
-	a.notificationRepo = repository.NewMemoryRepository(a.logger)
+	a.deliveryTracker = diagnostics.NewDeliveryTracker(&diagnostics.TrackerConfig{
+		MaxNotifications: a.cfg.Diagnostics.MaxNotifications,
+		MaxEvents:        a.cfg.Diagnostics.MaxEvents,
+		UserHistory:      a.cfg.Diagnostics.UserHistory,
+	})
 
 	wsConfig := &websocket.Config{
-		ReadBufferSize:  a.cfg.WebSocket.ReadBufferSize,
-		WriteBufferSize: a.cfg.WebSocket.WriteBufferSize,
-		PongWait:        int(a.cfg.WebSocket.PongWait.Seconds()),
-		PingPeriod:      int(a.cfg.WebSocket.PingPeriod.Seconds()),
-		MaxMessageSize:  a.cfg.WebSocket.MaxMessageSize,
+		ReadBufferSize:      a.cfg.WebSocket.ReadBufferSize,
+		WriteBufferSize:     a.cfg.WebSocket.WriteBufferSize,
+		PongWait:            int(a.cfg.WebSocket.PongWait.Seconds()),
+		PingPeriod:          int(a.cfg.WebSocket.PingPeriod.Seconds()),
+		MaxMessageSize:      a.cfg.WebSocket.MaxMessageSize,
+		DrainRetryMin:       a.cfg.WebSocket.DrainRetryMin,
+		DrainRetryMax:       a.cfg.WebSocket.DrainRetryMax,
+		MaxChannels:         a.cfg.WebSocket.MaxChannels,
+		SendBufferSize:      a.cfg.WebSocket.SendBufferSize,
+		BackpressurePolicy:  a.cfg.WebSocket.BackpressurePolicy,
+		LongPollTimeout:     a.cfg.WebSocket.LongPollTimeout,
+		LongPollIdleTimeout: a.cfg.WebSocket.LongPollIdleTimeout,
 	}
-	a.wsService = websocket.NewService(wsConfig, a.logger)
+	a.wsService = websocket.NewService(wsConfig, a.deliveryTracker, a.logger)
 
-	a.notificationSvc = application.NewNotificationService(a.notificationRepo, a.wsService, a.logger)
+	recorder := metrics.NewRecorder()
+
+	defaultTTLs := make(map[domain.NotificationType]time.Duration, len(a.cfg.Notifications.DefaultTTL))
+	for notificationType, ttl := range a.cfg.Notifications.DefaultTTL {
+		defaultTTLs[domain.NotificationType(notificationType)] = ttl
+	}
 
-	wsHandler := http.NewWSHandler(a.wsService, wsConfig, a.logger)
+	digestRules := make([]application.DigestRule, 0, len(a.cfg.Notifications.Digest))
+	for _, rule := range a.cfg.Notifications.Digest {
+		types := make([]domain.NotificationType, 0, len(rule.Types))
+		for _, notificationType := range rule.Types {
+			types = append(types, domain.NotificationType(notificationType))
+		}
+
+		digestRules = append(digestRules, application.DigestRule{
+			Name:        rule.Name,
+			Types:       types,
+			MaxPriority: rule.MaxPriority,
+			Window:      rule.Window,
+			MaxCount:    rule.MaxCount,
+			Title:       rule.Title,
+			Content:     rule.Content,
+		})
+	}
 
-	a.server = http.NewServer(a.cfg, wsHandler, a.logger)
+	a.digestSvc = application.NewDigestService(digestRules, a.deliveryTracker, a.cfg.Notifications.SchedulerInterval, recorder, a.logger)
+
+	pushSvc := application.NewPushService(
+		a.pushStore,
+		a.pushSender,
+		a.vapidPublicKey,
+		a.cfg.Push.MinPriority,
+		a.cfg.Push.TTL,
+		a.deliveryTracker,
+		recorder,
+		a.logger,
+	)
+
+	a.webhookSvc = application.NewWebhookService(
+		a.webhookStore,
+		a.webhookLog,
+		webhook.NewSender(a.cfg.Webhooks.Timeout),
+		&application.WebhookConfig{
+			MaxAttempts:      a.cfg.Webhooks.MaxAttempts,
+			BackoffMin:       a.cfg.Webhooks.BackoffMin,
+			BackoffMax:       a.cfg.Webhooks.BackoffMax,
+			BreakerThreshold: a.cfg.Webhooks.BreakerThreshold,
+			BreakerCooldown:  a.cfg.Webhooks.BreakerCooldown,
+			Concurrency:      a.cfg.Webhooks.Concurrency,
+			Interval:         a.cfg.Webhooks.Interval,
+			LogRetention:     a.cfg.Webhooks.LogRetention,
+		},
+		a.deliveryTracker,
+		recorder,
+		a.logger,
+	)
+
+	a.notificationSvc = application.NewNotificationService(
+		a.notificationRepo,
+		a.scheduleStore,
+		a.preferenceStore,
+		a.groupDirectory,
+		a.deliveryTracker,
+		defaultTTLs,
+		a.cfg.Notifications.MutedPolicy,
+		a.cfg.Notifications.QuietHoursBypassPriority,
+		a.digestSvc,
+		[]domain.DeliveryChannel{
+			websocket.NewTransportChannel(a.wsService, domain.ChannelRealtime),
+			websocket.NewTransportChannel(a.wsService, domain.ChannelWebSocket),
+			websocket.NewTransportChannel(a.wsService, domain.ChannelSSE),
+			websocket.NewTransportChannel(a.wsService, domain.ChannelLongPoll),
+			pushSvc,
+			a.webhookSvc,
+			application.NewEmailChannel(a.emailSender, a.preferenceStore, a.deliveryTracker, recorder, a.logger),
+		},
+		a.newDeliveryChains(),
+		recorder,
+		a.logger,
+	)
+
+	preferenceSvc := application.NewPreferenceService(a.preferenceStore, a.emailSender, a.logger)
+
+	a.channelSvc = application.NewChannelService(a.newChannelPolicy(), a.wsService, recorder, a.logger)
+
+	a.broadcastSvc = application.NewBroadcastService(a.broadcastStore, a.wsService, a.cfg.Notifications.BroadcastTTL, recorder, a.logger)
+	a.wsService.AddConnectHandler(a.broadcastSvc.DeliverActive)
+
+	offlineSvc := application.NewOfflineService(a.offlineQueue, a.notificationRepo, a.wsService, a.logger)
+	a.wsService.SetSpillHandler(offlineSvc.Spill)
+	a.wsService.SetRefillHandler(offlineSvc.Redeliver)
+	a.wsService.SetWriteHandler(a.notificationSvc.MarkWritten)
+	a.wsService.AddConnectHandler(offlineSvc.Redeliver)
+
+	a.expirySvc = application.NewExpiryService(a.notificationRepo, a.wsService, a.cfg.Notifications.ExpiryCheckInterval, a.logger)
+
+	a.scheduler = application.NewScheduler(a.scheduleStore, a.notificationSvc, a.cfg.Notifications.SchedulerInterval, a.logger)
+
+	a.recurringSvc = application.NewRecurringService(a.recurringStore, a.notificationSvc, a.wsService, a.cfg.Notifications.SchedulerInterval, a.logger)
+
+	commandHandler := application.NewCommandHandler(a.notificationSvc, preferenceSvc, a.channelSvc, a.wsService, a.logger)
+	a.wsService.SetInboundHandler(commandHandler.Handle)
+
+	wsHandler := http.NewWSHandler(a.wsService, wsConfig, a.deliveryTracker, a.logger)
+
+	resumeSvc := application.NewResumeService(a.notificationRepo, a.wsService, a.logger)
+	sseHandler := http.NewSSEHandler(a.wsService, resumeSvc, wsConfig, a.deliveryTracker, a.logger)
+
+	longPollHandler := http.NewLongPollHandler(a.wsService, wsConfig, a.deliveryTracker, a.logger)
+
+	apiHandler := http.NewAPIHandler(a.notificationSvc, preferenceSvc, pushSvc, a.webhookSvc, a.logger)
+
+	adminHandler := http.NewAdminHandler(a.deliveryTracker, a.recurringSvc, a.broadcastSvc, a.channelSvc, a.webhookSvc, a.storageBackup, a.logger)
+
+	a.server = http.NewServer(a.cfg, wsHandler, sseHandler, longPollHandler, apiHandler, adminHandler, a.logger)
 }
 
 func (a *App) InitializeKafka() error {
@@ -109,7 +479,7 @@ func (a *App) InitializeKafka() error {
 		return nil
 	}
 
-	kafkaHandler := application.NewKafkaHandler(a.notificationSvc, a.logger)
+	kafkaHandler := application.NewKafkaHandler(a.notificationSvc, a.channelSvc, a.broadcastSvc, a.deliveryTracker, a.logger)
 
 	if err := a.kafkaConsumer.Subscribe(a.cfg.Kafka.Topic, kafkaHandler.HandleMessage); err != nil {
 		a.logger.WithError(err).Warn("Не удалось подписаться на топик Kafka, продолжаем без консьюмера")
@@ -134,6 +504,12 @@ func isDockerEnvironment() bool {
 }
 
 func (a *App) StartServer() {
+	a.expirySvc.Start()
+	a.scheduler.Start()
+	a.recurringSvc.Start()
+	a.digestSvc.Start()
+	a.webhookSvc.Start()
+
 	go func() {
 		if err := a.server.Start(); err != nil {
 			a.logger.WithError(err).Error("Ошибка запуска HTTP сервера")
@@ -150,9 +526,11 @@ func (a *App) WaitForShutdown() {
 	sig := <-sigChan
 	a.logger.WithField("signal", sig.String()).Info("Получен сигнал остановки")
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), a.cfg.Server.ShutdownTimeout)
 	defer cancel()
 
+	a.Drain(ctx)
+
 	if err := a.server.Stop(ctx); err != nil {
 		a.logger.WithError(err).Error("Ошибка остановки HTTP сервера")
 	}
@@ -160,11 +538,70 @@ func (a *App) WaitForShutdown() {
 	a.logger.Info("Сервис успешно остановлен")
 }
 
+func (a *App) Drain(ctx context.Context) {
+	a.logger.Info("Перевод узла в режим дренирования")
+
+	a.server.SetReady(false)
+
+	if a.cfg.Server.DrainDelay > 0 {
+		select {
+		case <-time.After(a.cfg.Server.DrainDelay):
+		case <-ctx.Done():
+		}
+	}
+
+	if a.kafkaConsumer != nil {
+		a.kafkaConsumer.Close()
+		a.kafkaConsumer = nil
+	}
+
+	// Накопленные дайджесты отправляются, пока клиенты ещё подключены
+	a.digestSvc.Flush()
+
+	if err := a.wsService.Drain(ctx); err != nil {
+		a.logger.WithError(err).Warn("Не все WebSocket соединения закрыты до истечения таймаута")
+	}
+}
+
 func (a *App) Cleanup() {
+	if a.expirySvc != nil {
+		a.expirySvc.Stop()
+	}
+
+	if a.scheduler != nil {
+		a.scheduler.Stop()
+	}
+
+	if a.recurringSvc != nil {
+		a.recurringSvc.Stop()
+	}
+
+	if a.digestSvc != nil {
+		a.digestSvc.Stop()
+	}
+
+	if a.webhookSvc != nil {
+		a.webhookSvc.Stop()
+	}
+
 	if a.kafkaConsumer != nil {
 		a.kafkaConsumer.Close()
 	}
 
+	if a.storageCloser != nil {
+		if err := a.storageCloser.Close(); err != nil {
+			a.logger.WithError(err).Error("Ошибка закрытия хранилища")
+		}
+	}
+
+	if a.shutdownTracing != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := a.shutdownTracing(ctx); err != nil {
+			a.logger.WithError(err).Error("Ошибка остановки экспорта трассировок")
+		}
+		cancel()
+	}
+
 	if a.logger != nil {
 		a.logger.Sync()
 	}
@@ -173,6 +610,27 @@ func (a *App) Cleanup() {
 func (a *App) Run() {
 	defer a.Cleanup()
 
+	if err := a.InitializeTracing(); err != nil {
+		a.logger.WithError(err).Fatal("Ошибка инициализации трассировки")
+		return
+	}
+
+	if err := a.InitializeStorage(); err != nil {
+		a.logger.WithError(err).Fatal("Ошибка инициализации хранилища")
+		return
+	}
+
+	if err := a.InitializeDirectory(); err != nil {
+		a.logger.WithError(err).Fatal("Ошибка инициализации каталога групп")
+		return
+	}
+
+	if err := a.InitializePush(); err != nil {
+		a.logger.WithError(err).Fatal("Ошибка инициализации Web Push")
+		return
+	}
+
+	a.InitializeEmail()
 	a.InitializeServices()
 
 	if err := a.InitializeKafka(); err != nil {
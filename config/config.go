@@ -2,23 +2,37 @@ package config
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
 )
 
 type Config struct {
-	Server    ServerConfig    `mapstructure:"server"`
-	Kafka     KafkaConfig     `mapstructure:"kafka"`
-	WebSocket WebSocketConfig `mapstructure:"websocket"`
-	TLS       TLSConfig       `mapstructure:"tls"`
+	Server        ServerConfig        `mapstructure:"server"`
+	Kafka         KafkaConfig         `mapstructure:"kafka"`
+	WebSocket     WebSocketConfig     `mapstructure:"websocket"`
+	TLS           TLSConfig           `mapstructure:"tls"`
+	Tracing       TracingConfig       `mapstructure:"tracing"`
+	Logging       LoggingConfig       `mapstructure:"logging"`
+	Diagnostics   DiagnosticsConfig   `mapstructure:"diagnostics"`
+	Storage       StorageConfig       `mapstructure:"storage"`
+	Notifications NotificationsConfig `mapstructure:"notifications"`
+	Directory     DirectoryConfig     `mapstructure:"directory"`
+	Channels      ChannelsConfig      `mapstructure:"channels"`
+	Push          PushConfig          `mapstructure:"push"`
+	Webhooks      WebhooksConfig      `mapstructure:"webhooks"`
+	Email         EmailConfig         `mapstructure:"email"`
+	Delivery      DeliveryConfig      `mapstructure:"delivery"`
 }
 
 type ServerConfig struct {
-	Port         int           `mapstructure:"port"`
-	ReadTimeout  time.Duration `mapstructure:"read_timeout"`
-	WriteTimeout time.Duration `mapstructure:"write_timeout"`
-	MetricsPort  int           `mapstructure:"metrics_port"`
+	Port            int           `mapstructure:"port"`
+	ReadTimeout     time.Duration `mapstructure:"read_timeout"`
+	WriteTimeout    time.Duration `mapstructure:"write_timeout"`
+	MetricsPort     int           `mapstructure:"metrics_port"`
+	DrainDelay      time.Duration `mapstructure:"drain_delay"`
+	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"`
 }
 
 type KafkaConfig struct {
@@ -29,11 +43,18 @@ type KafkaConfig struct {
 }
 
 type WebSocketConfig struct {
-	ReadBufferSize  int           `mapstructure:"read_buffer_size"`
-	WriteBufferSize int           `mapstructure:"write_buffer_size"`
-	PongWait        time.Duration `mapstructure:"pong_wait"`
-	PingPeriod      time.Duration `mapstructure:"ping_period"`
-	MaxMessageSize  int64         `mapstructure:"max_message_size"`
+	ReadBufferSize      int           `mapstructure:"read_buffer_size"`
+	WriteBufferSize     int           `mapstructure:"write_buffer_size"`
+	PongWait            time.Duration `mapstructure:"pong_wait"`
+	PingPeriod          time.Duration `mapstructure:"ping_period"`
+	MaxMessageSize      int64         `mapstructure:"max_message_size"`
+	DrainRetryMin       time.Duration `mapstructure:"drain_retry_min"`
+	DrainRetryMax       time.Duration `mapstructure:"drain_retry_max"`
+	MaxChannels         int           `mapstructure:"max_channels"`
+	SendBufferSize      int           `mapstructure:"send_buffer_size"`
+	BackpressurePolicy  string        `mapstructure:"backpressure_policy"` // disconnect, drop_oldest, drop_lowest_priority или spill
+	LongPollTimeout     time.Duration `mapstructure:"long_poll_timeout"`
+	LongPollIdleTimeout time.Duration `mapstructure:"long_poll_idle_timeout"`
 }
 
 type TLSConfig struct {
@@ -42,6 +63,146 @@ type TLSConfig struct {
 	KeyFile  string `mapstructure:"key_file"`
 }
 
+type TracingConfig struct {
+	Enabled     bool    `mapstructure:"enabled"`
+	ServiceName string  `mapstructure:"service_name"`
+	Endpoint    string  `mapstructure:"endpoint"`
+	Insecure    bool    `mapstructure:"insecure"`
+	SampleRatio float64 `mapstructure:"sample_ratio"`
+}
+
+type LoggingConfig struct {
+	Level       string         `mapstructure:"level"`
+	Encoding    string         `mapstructure:"encoding"`
+	OutputPaths []string       `mapstructure:"output_paths"`
+	Sampling    SamplingConfig `mapstructure:"sampling"`
+	DebugUsers  []string       `mapstructure:"debug_users"`
+}
+
+type SamplingConfig struct {
+	Enabled    bool `mapstructure:"enabled"`
+	Initial    int  `mapstructure:"initial"`
+	Thereafter int  `mapstructure:"thereafter"`
+}
+
+// DirectoryConfig задаёт источник состава групп получателей.
+type DirectoryConfig struct {
+	Driver   string              `mapstructure:"driver"` // static, file или http
+	Groups   map[string][]string `mapstructure:"groups"`
+	Path     string              `mapstructure:"path"`
+	URL      string              `mapstructure:"url"`
+	Timeout  time.Duration       `mapstructure:"timeout"`
+	CacheTTL time.Duration       `mapstructure:"cache_ttl"`
+}
+
+// ChannelsConfig задаёт политику доступа к каналам подписки.
+type ChannelsConfig struct {
+	Policy string              `mapstructure:"policy"` // allow_all или rules
+	Rules  []ChannelRuleConfig `mapstructure:"rules"`
+}
+
+type ChannelRuleConfig struct {
+	Pattern string   `mapstructure:"pattern"`
+	Users   []string `mapstructure:"users"`
+	Groups  []string `mapstructure:"groups"`
+}
+
+// PushConfig задаёт доставку Web Push пользователям без активного соединения.
+type PushConfig struct {
+	Enabled         bool          `mapstructure:"enabled"`
+	VAPIDPublicKey  string        `mapstructure:"vapid_public_key"`
+	VAPIDPrivateKey string        `mapstructure:"vapid_private_key"`
+	Subject         string        `mapstructure:"subject"` // контакт владельца: mailto: или https:
+	MinPriority     int           `mapstructure:"min_priority"`
+	TTL             time.Duration `mapstructure:"ttl"`
+	Timeout         time.Duration `mapstructure:"timeout"`
+}
+
+// WebhooksConfig задаёт доставку уведомлений на адреса внешних подписчиков.
+type WebhooksConfig struct {
+	Timeout          time.Duration `mapstructure:"timeout"`
+	MaxAttempts      int           `mapstructure:"max_attempts"`
+	BackoffMin       time.Duration `mapstructure:"backoff_min"`
+	BackoffMax       time.Duration `mapstructure:"backoff_max"`
+	BreakerThreshold int           `mapstructure:"breaker_threshold"` // неудач подряд до размыкания
+	BreakerCooldown  time.Duration `mapstructure:"breaker_cooldown"`
+	Concurrency      int           `mapstructure:"concurrency"`
+	Interval         time.Duration `mapstructure:"interval"`
+	LogRetention     time.Duration `mapstructure:"log_retention"`
+}
+
+// EmailConfig задаёт отправку уведомлений по почте через SMTP.
+type EmailConfig struct {
+	Enabled  bool          `mapstructure:"enabled"`
+	Host     string        `mapstructure:"host"`
+	Port     int           `mapstructure:"port"`
+	Username string        `mapstructure:"username"`
+	Password string        `mapstructure:"password"`
+	From     string        `mapstructure:"from"`
+	TLS      string        `mapstructure:"tls"` // none, starttls или tls
+	Timeout  time.Duration `mapstructure:"timeout"`
+}
+
+// DeliveryConfig задаёт цепочки каналов доставки.
+type DeliveryConfig struct {
+	// Цепочки по типу уведомления; default - для остальных типов
+	Chains map[string][]DeliveryStepConfig `mapstructure:"chains"`
+	// Каналы, получающие каждое сохранённое уведомление вне цепочки;
+	// по умолчанию webhook
+	Always []string `mapstructure:"always"`
+}
+
+type DeliveryStepConfig struct {
+	Channel string        `mapstructure:"channel"`
+	After   time.Duration `mapstructure:"after"`
+}
+
+type DiagnosticsConfig struct {
+	MaxNotifications int `mapstructure:"max_notifications"`
+	MaxEvents        int `mapstructure:"max_events"`
+	UserHistory      int `mapstructure:"user_history"`
+}
+
+type StorageConfig struct {
+	// memory, postgres, sqlite или bolt
+	Driver          string          `mapstructure:"driver"`
+	DSN             string          `mapstructure:"dsn"`
+	Path            string          `mapstructure:"path"`
+	MaxOpenConns    int             `mapstructure:"max_open_conns"`
+	MaxIdleConns    int             `mapstructure:"max_idle_conns"`
+	ConnMaxLifetime time.Duration   `mapstructure:"conn_max_lifetime"`
+	Retention       RetentionConfig `mapstructure:"retention"`
+}
+
+// RetentionConfig задаёт ограничения хранилища в памяти (driver: memory).
+type RetentionConfig struct {
+	MaxAge             time.Duration `mapstructure:"max_age"`
+	MaxPerUser         int           `mapstructure:"max_per_user"`
+	MaxTotal           int           `mapstructure:"max_total"`
+	CompactionInterval time.Duration `mapstructure:"compaction_interval"`
+}
+
+type NotificationsConfig struct {
+	// Время жизни по умолчанию для типов уведомлений без явного ttl/expires_at
+	DefaultTTL               map[string]time.Duration `mapstructure:"default_ttl"`
+	ExpiryCheckInterval      time.Duration            `mapstructure:"expiry_check_interval"`
+	SchedulerInterval        time.Duration            `mapstructure:"scheduler_interval"`
+	MutedPolicy              string                   `mapstructure:"muted_policy"`                // silent или drop
+	QuietHoursBypassPriority int                      `mapstructure:"quiet_hours_bypass_priority"` // доставляются и в тихие часы
+	Digest                   []DigestRuleConfig       `mapstructure:"digest"`
+	BroadcastTTL             time.Duration            `mapstructure:"broadcast_ttl"`
+}
+
+type DigestRuleConfig struct {
+	Name        string        `mapstructure:"name"`
+	Types       []string      `mapstructure:"types"`
+	MaxPriority int           `mapstructure:"max_priority"`
+	Window      time.Duration `mapstructure:"window"`
+	MaxCount    int           `mapstructure:"max_count"`
+	Title       string        `mapstructure:"title"`
+	Content     string        `mapstructure:"content"`
+}
+
 func LoadConfig(configPath string) (*Config, error) {
 	viper.SetConfigName("config")
 	viper.SetConfigType("yaml")
@@ -89,6 +250,10 @@ func validateConfig(config *Config) error {
 		config.Server.WriteTimeout = 15 * time.Second
 	}
 
+	if config.Server.ShutdownTimeout == 0 {
+		config.Server.ShutdownTimeout = 30 * time.Second
+	}
+
 	if config.WebSocket.ReadBufferSize <= 0 {
 		config.WebSocket.ReadBufferSize = 1024
 	}
@@ -110,5 +275,339 @@ func validateConfig(config *Config) error {
 		config.WebSocket.MaxMessageSize = 512000
 	}
 
+	if config.WebSocket.DrainRetryMin == 0 {
+		config.WebSocket.DrainRetryMin = time.Second
+	}
+
+	if config.WebSocket.DrainRetryMax < config.WebSocket.DrainRetryMin {
+		config.WebSocket.DrainRetryMax = config.WebSocket.DrainRetryMin + 10*time.Second
+	}
+
+	if config.WebSocket.MaxChannels <= 0 {
+		config.WebSocket.MaxChannels = 100
+	}
+
+	if config.WebSocket.SendBufferSize <= 0 {
+		config.WebSocket.SendBufferSize = 256
+	}
+
+	switch config.WebSocket.BackpressurePolicy {
+	case "":
+		config.WebSocket.BackpressurePolicy = "disconnect"
+	case "disconnect", "drop_oldest", "drop_lowest_priority", "spill":
+	default:
+		return fmt.Errorf("неизвестная политика переполнения буфера: %s", config.WebSocket.BackpressurePolicy)
+	}
+
+	if config.WebSocket.LongPollTimeout <= 0 {
+		config.WebSocket.LongPollTimeout = 25 * time.Second
+	}
+
+	// Сессия не должна истекать между двумя запросами, удерживаемыми по максимуму
+	if config.WebSocket.LongPollIdleTimeout <= config.WebSocket.LongPollTimeout {
+		config.WebSocket.LongPollIdleTimeout = 2 * config.WebSocket.LongPollTimeout
+	}
+
+	if config.Logging.Level == "" {
+		config.Logging.Level = "info"
+	}
+
+	if config.Logging.Encoding == "" {
+		config.Logging.Encoding = "console"
+	}
+
+	if config.Logging.Encoding != "console" && config.Logging.Encoding != "json" {
+		return fmt.Errorf("неизвестный формат логов: %s", config.Logging.Encoding)
+	}
+
+	if config.Logging.Sampling.Initial <= 0 {
+		config.Logging.Sampling.Initial = 100
+	}
+
+	if config.Logging.Sampling.Thereafter <= 0 {
+		config.Logging.Sampling.Thereafter = 100
+	}
+
+	if config.Storage.Driver == "" {
+		config.Storage.Driver = "memory"
+	}
+
+	switch config.Storage.Driver {
+	case "memory":
+	case "postgres", "sqlite":
+		if config.Storage.DSN == "" {
+			return fmt.Errorf("не указана строка подключения к хранилищу %s", config.Storage.Driver)
+		}
+	case "bolt":
+		if config.Storage.Path == "" {
+			return fmt.Errorf("не указан путь к файлу хранилища bolt")
+		}
+	default:
+		return fmt.Errorf("неизвестный драйвер хранилища: %s", config.Storage.Driver)
+	}
+
+	if config.Storage.Retention.CompactionInterval <= 0 {
+		config.Storage.Retention.CompactionInterval = time.Minute
+	}
+
+	for notificationType := range config.Notifications.DefaultTTL {
+		switch notificationType {
+		case "system", "alert", "message":
+		default:
+			return fmt.Errorf("неизвестный тип уведомления в default_ttl: %s", notificationType)
+		}
+	}
+
+	if config.Notifications.ExpiryCheckInterval <= 0 {
+		config.Notifications.ExpiryCheckInterval = 30 * time.Second
+	}
+
+	if config.Notifications.SchedulerInterval <= 0 {
+		config.Notifications.SchedulerInterval = time.Second
+	}
+
+	if config.Notifications.BroadcastTTL <= 0 {
+		config.Notifications.BroadcastTTL = 24 * time.Hour
+	}
+
+	switch config.Notifications.MutedPolicy {
+	case "":
+		config.Notifications.MutedPolicy = "silent"
+	case "silent", "drop":
+	default:
+		return fmt.Errorf("неизвестная политика заглушённых уведомлений: %s", config.Notifications.MutedPolicy)
+	}
+
+	if config.Notifications.QuietHoursBypassPriority <= 0 {
+		config.Notifications.QuietHoursBypassPriority = 4
+	}
+
+	if config.Notifications.QuietHoursBypassPriority > 5 {
+		return fmt.Errorf("приоритет обхода тихих часов должен быть от 1 до 5")
+	}
+
+	switch config.Directory.Driver {
+	case "":
+		config.Directory.Driver = "static"
+	case "static":
+	case "file":
+		if config.Directory.Path == "" {
+			return fmt.Errorf("не указан путь к файлу групп")
+		}
+	case "http":
+		if config.Directory.URL == "" {
+			return fmt.Errorf("не указан адрес сервиса групп")
+		}
+	default:
+		return fmt.Errorf("неизвестный источник групп: %s", config.Directory.Driver)
+	}
+
+	if config.Directory.Timeout <= 0 {
+		config.Directory.Timeout = 5 * time.Second
+	}
+
+	switch config.Channels.Policy {
+	case "":
+		config.Channels.Policy = "allow_all"
+	case "allow_all", "rules":
+	default:
+		return fmt.Errorf("неизвестная политика доступа к каналам: %s", config.Channels.Policy)
+	}
+
+	for _, rule := range config.Channels.Rules {
+		if rule.Pattern == "" {
+			return fmt.Errorf("не указан шаблон канала в правиле доступа")
+		}
+	}
+
+	if config.Push.Enabled {
+		if config.Push.VAPIDPrivateKey == "" {
+			return fmt.Errorf("не указан закрытый VAPID-ключ")
+		}
+		if !strings.HasPrefix(config.Push.Subject, "mailto:") && !strings.HasPrefix(config.Push.Subject, "https://") {
+			return fmt.Errorf("VAPID subject должен начинаться с mailto: или https://")
+		}
+	}
+
+	if config.Push.MinPriority <= 0 {
+		config.Push.MinPriority = 4
+	}
+
+	if config.Push.MinPriority > 5 {
+		return fmt.Errorf("порог приоритета Web Push должен быть от 1 до 5")
+	}
+
+	if config.Push.TTL <= 0 {
+		config.Push.TTL = 24 * time.Hour
+	}
+
+	if config.Push.Timeout <= 0 {
+		config.Push.Timeout = 10 * time.Second
+	}
+
+	if config.Webhooks.Timeout <= 0 {
+		config.Webhooks.Timeout = 10 * time.Second
+	}
+
+	if config.Webhooks.MaxAttempts <= 0 {
+		config.Webhooks.MaxAttempts = 8
+	}
+
+	if config.Webhooks.BackoffMin <= 0 {
+		config.Webhooks.BackoffMin = 10 * time.Second
+	}
+
+	if config.Webhooks.BackoffMax <= 0 {
+		config.Webhooks.BackoffMax = time.Hour
+	}
+
+	if config.Webhooks.BackoffMax < config.Webhooks.BackoffMin {
+		config.Webhooks.BackoffMax = config.Webhooks.BackoffMin
+	}
+
+	if config.Webhooks.BreakerThreshold <= 0 {
+		config.Webhooks.BreakerThreshold = 5
+	}
+
+	if config.Webhooks.BreakerCooldown <= 0 {
+		config.Webhooks.BreakerCooldown = time.Minute
+	}
+
+	if config.Webhooks.Concurrency <= 0 {
+		config.Webhooks.Concurrency = 8
+	}
+
+	if config.Webhooks.Interval <= 0 {
+		config.Webhooks.Interval = time.Second
+	}
+
+	if config.Webhooks.LogRetention <= 0 {
+		config.Webhooks.LogRetention = 7 * 24 * time.Hour
+	}
+
+	if config.Email.Enabled {
+		if config.Email.Host == "" {
+			return fmt.Errorf("не указан адрес SMTP-сервера")
+		}
+		if config.Email.From == "" {
+			return fmt.Errorf("не указан адрес отправителя писем")
+		}
+	}
+
+	switch config.Email.TLS {
+	case "":
+		config.Email.TLS = "none"
+	case "none", "starttls", "tls":
+	default:
+		return fmt.Errorf("неизвестный режим TLS для SMTP: %s", config.Email.TLS)
+	}
+
+	if config.Email.Port <= 0 {
+		config.Email.Port = 25
+	}
+
+	if config.Email.Timeout <= 0 {
+		config.Email.Timeout = 10 * time.Second
+	}
+
+	if err := validateDelivery(&config.Delivery); err != nil {
+		return err
+	}
+
+	digestNames := make(map[string]bool, len(config.Notifications.Digest))
+	for i := range config.Notifications.Digest {
+		rule := &config.Notifications.Digest[i]
+
+		if rule.Name == "" || digestNames[rule.Name] {
+			return fmt.Errorf("правило дайджеста должно иметь уникальное имя: %q", rule.Name)
+		}
+		digestNames[rule.Name] = true
+
+		for _, notificationType := range rule.Types {
+			switch notificationType {
+			case "system", "alert", "message":
+			default:
+				return fmt.Errorf("неизвестный тип уведомления в правиле дайджеста %s: %s", rule.Name, notificationType)
+			}
+		}
+
+		if rule.Window <= 0 {
+			rule.Window = time.Minute
+		}
+	}
+
+	if config.Diagnostics.MaxNotifications <= 0 {
+		config.Diagnostics.MaxNotifications = 10000
+	}
+
+	if config.Diagnostics.MaxEvents <= 0 {
+		config.Diagnostics.MaxEvents = 32
+	}
+
+	if config.Diagnostics.UserHistory <= 0 {
+		config.Diagnostics.UserHistory = 50
+	}
+
+	if config.Tracing.ServiceName == "" {
+		config.Tracing.ServiceName = "notification-service"
+	}
+
+	if config.Tracing.SampleRatio <= 0 || config.Tracing.SampleRatio > 1 {
+		config.Tracing.SampleRatio = 1
+	}
+
 	return nil
 }
+
+func validateDelivery(delivery *DeliveryConfig) error {
+	if delivery.Chains == nil {
+		delivery.Chains = make(map[string][]DeliveryStepConfig)
+	}
+
+	if _, ok := delivery.Chains["default"]; !ok {
+		delivery.Chains["default"] = []DeliveryStepConfig{{Channel: "realtime"}, {Channel: "webpush"}}
+	}
+
+	if delivery.Always == nil {
+		delivery.Always = []string{"webhook"}
+	}
+
+	for key, chain := range delivery.Chains {
+		switch key {
+		case "default", "system", "alert", "message":
+		default:
+			return fmt.Errorf("неизвестный тип уведомления в цепочках доставки: %s", key)
+		}
+
+		if len(chain) == 0 {
+			return fmt.Errorf("пустая цепочка доставки %s", key)
+		}
+
+		for _, step := range chain {
+			if !isKnownChannel(step.Channel) {
+				return fmt.Errorf("неизвестный канал доставки в цепочке %s: %s", key, step.Channel)
+			}
+			if step.After < 0 {
+				return fmt.Errorf("отрицательная задержка шага %s в цепочке %s", step.Channel, key)
+			}
+		}
+	}
+
+	// Вне цепочки уведомление получают только подписчики-сервисы: остальные
+	// каналы адресованы пользователю и должны учитывать его настройки
+	for _, channel := range delivery.Always {
+		if channel != "webhook" {
+			return fmt.Errorf("в delivery.always допускается только канал webhook: %s", channel)
+		}
+	}
+
+	return nil
+}
+
+func isKnownChannel(name string) bool {
+	switch name {
+	case "realtime", "websocket", "sse", "long_poll", "webpush", "webhook", "email":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,157 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+	"github.com/anatoly_dev/go-ws-notifications/pkg/logger"
+	"github.com/google/uuid"
+)
+
+// BroadcastService публикует объявления для всех подключённых клиентов,
+// подходящих под аудиторию, и хранит их до истечения, чтобы доставить
+// клиентам, подключившимся позже.
+type BroadcastService struct {
+	store      domain.BroadcastStore
+	broker     domain.BroadcastBroker
+	defaultTTL time.Duration
+	metrics    domain.Metrics
+	logger     *logger.Logger
+}
+
+func NewBroadcastService(
+	store domain.BroadcastStore,
+	broker domain.BroadcastBroker,
+	defaultTTL time.Duration,
+	metrics domain.Metrics,
+	logger *logger.Logger,
+) *BroadcastService {
+	return &BroadcastService{
+		store:      store,
+		broker:     broker,
+		defaultTTL: defaultTTL,
+		metrics:    metrics,
+		logger:     logger.WithField("source", "broadcast_service"),
+	}
+}
+
+// Send сохраняет объявление и отправляет его подходящим подключённым
+// клиентам. Возвращает количество клиентов, получивших объявление сразу.
+func (s *BroadcastService) Send(ctx context.Context, broadcast *domain.Broadcast) (int, error) {
+	now := time.Now()
+
+	if broadcast.ID == "" {
+		broadcast.ID = uuid.New().String()
+	}
+
+	if broadcast.CreatedAt.IsZero() {
+		broadcast.CreatedAt = now
+	}
+
+	if err := broadcast.Validate(); err != nil {
+		return 0, err
+	}
+
+	// Без срока действия объявление доставлялось бы при каждом подключении бесконечно
+	if broadcast.ExpiresAt == nil {
+		ttl := time.Duration(broadcast.TTL) * time.Second
+		if ttl == 0 {
+			ttl = s.defaultTTL
+		}
+		expiresAt := broadcast.CreatedAt.Add(ttl)
+		broadcast.ExpiresAt = &expiresAt
+	}
+
+	if broadcast.IsExpired(now) {
+		return 0, domain.ErrExpired
+	}
+
+	log := s.logger.WithField("broadcastID", broadcast.ID)
+
+	if deleted, err := s.store.DeleteExpired(ctx, now); err != nil {
+		log.WithError(err).Error("Ошибка удаления истёкших объявлений")
+	} else if deleted > 0 {
+		log.WithField("count", deleted).Debug("Удалены истёкшие объявления")
+	}
+
+	if err := s.store.Save(ctx, broadcast); err != nil {
+		return 0, fmt.Errorf("ошибка сохранения объявления: %w", err)
+	}
+
+	frame, err := encodeBroadcast(broadcast)
+	if err != nil {
+		return 0, err
+	}
+
+	delivered := s.broker.BroadcastToAudience(broadcast.Audience, frame)
+	s.metrics.BroadcastSent()
+	s.metrics.BroadcastDeliveries("live", delivered)
+
+	log.WithFields(map[string]interface{}{
+		"clients":   delivered,
+		"expiresAt": broadcast.ExpiresAt,
+	}).Info("Объявление опубликовано")
+
+	return delivered, nil
+}
+
+func (s *BroadcastService) List(ctx context.Context) ([]*domain.Broadcast, error) {
+	return s.store.FindActive(ctx, time.Now())
+}
+
+// Delete отзывает объявление: клиенты, подключившиеся после этого, его не получат.
+func (s *BroadcastService) Delete(ctx context.Context, id string) error {
+	if err := s.store.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	s.logger.WithField("broadcastID", id).Info("Объявление отозвано")
+	return nil
+}
+
+// DeliverActive отправляет только что подключившемуся клиенту действующие
+// объявления, подходящие под его атрибуты. Клиент сам отбрасывает уже
+// показанные объявления по id.
+func (s *BroadcastService) DeliverActive(ctx context.Context, userID string, attributes domain.ClientAttributes) {
+	log := s.logger.WithField("userID", userID)
+
+	broadcasts, err := s.store.FindActive(ctx, time.Now())
+	if err != nil {
+		log.WithError(err).Error("Ошибка поиска действующих объявлений")
+		return
+	}
+
+	for _, broadcast := range broadcasts {
+		if !broadcast.Audience.Matches(attributes) {
+			continue
+		}
+
+		frame, err := encodeBroadcast(broadcast)
+		if err != nil {
+			log.WithError(err).Error("Ошибка сериализации объявления")
+			continue
+		}
+
+		if err := s.broker.SendToUser(userID, frame); err != nil {
+			log.WithError(err).Debug("Не удалось доставить объявление при подключении")
+			return
+		}
+
+		s.metrics.BroadcastDeliveries("sticky", 1)
+	}
+}
+
+func encodeBroadcast(broadcast *domain.Broadcast) ([]byte, error) {
+	frame, err := json.Marshal(domain.BroadcastEvent{
+		Event:     domain.EventBroadcast,
+		Broadcast: broadcast,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ошибка сериализации объявления: %w", err)
+	}
+
+	return frame, nil
+}
@@ -0,0 +1,108 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+	"github.com/anatoly_dev/go-ws-notifications/pkg/logger"
+	"github.com/google/uuid"
+)
+
+// ChannelService управляет подписками сокетов на именованные каналы
+// (например, страница заказа или текущий инцидент) и публикацией в них.
+type ChannelService struct {
+	policy  domain.ChannelPolicy
+	broker  domain.ChannelBroker
+	metrics domain.Metrics
+	logger  *logger.Logger
+}
+
+func NewChannelService(policy domain.ChannelPolicy, broker domain.ChannelBroker, metrics domain.Metrics, logger *logger.Logger) *ChannelService {
+	return &ChannelService{
+		policy:  policy,
+		broker:  broker,
+		metrics: metrics,
+		logger:  logger.WithField("source", "channel_service"),
+	}
+}
+
+func (s *ChannelService) Subscribe(ctx context.Context, userID string, connectionID string, channel string) error {
+	if err := domain.ValidateChannelName(channel); err != nil {
+		return err
+	}
+
+	log := s.logger.WithFields(map[string]interface{}{
+		"userID":  userID,
+		"channel": channel,
+	})
+
+	if err := s.policy.Authorize(ctx, userID, channel); err != nil {
+		if errors.Is(err, domain.ErrUnauthorized) {
+			s.metrics.ChannelSubscribeDenied()
+			log.Debug("Подписка на канал запрещена политикой доступа")
+		} else {
+			log.WithError(err).Error("Ошибка проверки доступа к каналу")
+		}
+		return err
+	}
+
+	if err := s.broker.Subscribe(userID, connectionID, channel); err != nil {
+		return err
+	}
+
+	log.Debug("Сокет подписан на канал")
+	return nil
+}
+
+func (s *ChannelService) Unsubscribe(ctx context.Context, userID string, connectionID string, channel string) error {
+	if err := domain.ValidateChannelName(channel); err != nil {
+		return err
+	}
+
+	return s.broker.Unsubscribe(userID, connectionID, channel)
+}
+
+func (s *ChannelService) Subscriptions(ctx context.Context, userID string) []string {
+	return s.broker.Subscriptions(userID)
+}
+
+// Publish отправляет сообщение всем сокетам, подписанным на канал, и
+// возвращает их количество. Сообщение не сохраняется: сокеты, подписавшиеся
+// позже, его не получат.
+func (s *ChannelService) Publish(ctx context.Context, message *domain.ChannelMessage) (int, error) {
+	if err := domain.ValidateChannelName(message.Channel); err != nil {
+		return 0, err
+	}
+
+	if message.ID == "" {
+		message.ID = uuid.New().String()
+	}
+
+	if message.CreatedAt.IsZero() {
+		message.CreatedAt = time.Now()
+	}
+
+	frame, err := json.Marshal(domain.ChannelEvent{
+		Event:   domain.EventChannelMessage,
+		Channel: message.Channel,
+		Message: message,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("ошибка сериализации сообщения канала: %w", err)
+	}
+
+	delivered := s.broker.PublishToChannel(message.Channel, frame)
+	s.metrics.ChannelMessagePublished()
+
+	s.logger.WithFields(map[string]interface{}{
+		"channel":     message.Channel,
+		"messageID":   message.ID,
+		"subscribers": delivered,
+	}).Debug("Сообщение опубликовано в канал")
+
+	return delivered, nil
+}
@@ -0,0 +1,160 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+	"github.com/anatoly_dev/go-ws-notifications/pkg/logger"
+)
+
+const (
+	ActionAck            = "ack"
+	ActionRead           = "read"
+	ActionGetPreferences = "get_preferences"
+	ActionSetPreference  = "set_preference"
+	ActionSetQuietHours  = "set_quiet_hours"
+	ActionSetEmail       = "set_email"
+	ActionVerifyEmail    = "verify_email"
+	ActionSubscribe      = "subscribe"
+	ActionUnsubscribe    = "unsubscribe"
+)
+
+// Command - сообщение, присланное клиентом по WebSocket.
+type Command struct {
+	Action     string                  `json:"action"`
+	ID         string                  `json:"id,omitempty"`
+	Type       domain.NotificationType `json:"type,omitempty"`
+	Category   string                  `json:"category,omitempty"`
+	Enabled    *bool                   `json:"enabled,omitempty"`
+	QuietHours *domain.QuietHours      `json:"quiet_hours,omitempty"` // null отключает тихие часы
+	Email      string                  `json:"email,omitempty"`
+	Code       string                  `json:"code,omitempty"` // код подтверждения адреса email
+	Channel    string                  `json:"channel,omitempty"`
+}
+
+type commandResponse struct {
+	Event       string              `json:"event"`
+	Action      string              `json:"action,omitempty"`
+	ID          string              `json:"id,omitempty"`
+	Error       string              `json:"error,omitempty"`
+	Preferences *domain.Preferences `json:"preferences,omitempty"`
+	Channel     string              `json:"channel,omitempty"`
+}
+
+type CommandHandler struct {
+	notificationService domain.NotificationService
+	preferenceService   domain.PreferenceService
+	channelService      domain.ChannelService
+	wsService           domain.WebSocketService
+	logger              *logger.Logger
+}
+
+func NewCommandHandler(
+	notificationService domain.NotificationService,
+	preferenceService domain.PreferenceService,
+	channelService domain.ChannelService,
+	wsService domain.WebSocketService,
+	logger *logger.Logger,
+) *CommandHandler {
+	return &CommandHandler{
+		notificationService: notificationService,
+		preferenceService:   preferenceService,
+		channelService:      channelService,
+		wsService:           wsService,
+		logger:              logger,
+	}
+}
+
+func (h *CommandHandler) Handle(ctx context.Context, userID string, connectionID string, message []byte) {
+	log := h.logger.WithField("userID", userID)
+
+	var command Command
+	if err := json.Unmarshal(message, &command); err != nil {
+		log.WithError(err).Debug("Некорректная команда от клиента")
+		h.reply(userID, commandResponse{Event: "error", Error: domain.ErrInvalidInput.Error()})
+		return
+	}
+
+	log = log.WithFields(map[string]interface{}{
+		"action":         command.Action,
+		"notificationID": command.ID,
+	})
+	log.Debug("Получена команда от клиента")
+
+	var err error
+	switch command.Action {
+	case ActionAck:
+		if command.ID == "" {
+			err = domain.ErrInvalidInput
+			break
+		}
+		err = h.notificationService.Ack(ctx, command.ID, userID)
+	case ActionRead:
+		if command.ID == "" {
+			err = domain.ErrInvalidInput
+			break
+		}
+		err = h.notificationService.MarkAsRead(ctx, command.ID, userID)
+	case ActionGetPreferences, ActionSetPreference, ActionSetQuietHours, ActionSetEmail, ActionVerifyEmail:
+		var preferences *domain.Preferences
+		preferences, err = h.handlePreferences(ctx, userID, command)
+		if err == nil {
+			h.reply(userID, commandResponse{Event: "preferences", Action: command.Action, Preferences: preferences})
+		}
+	case ActionSubscribe:
+		err = h.channelService.Subscribe(ctx, userID, connectionID, command.Channel)
+		if err == nil {
+			h.reply(userID, commandResponse{Event: "subscribed", Action: command.Action, Channel: command.Channel})
+		}
+	case ActionUnsubscribe:
+		err = h.channelService.Unsubscribe(ctx, userID, connectionID, command.Channel)
+		if err == nil {
+			h.reply(userID, commandResponse{Event: "unsubscribed", Action: command.Action, Channel: command.Channel})
+		}
+	default:
+		err = domain.ErrInvalidInput
+	}
+
+	if err != nil {
+		log.WithError(err).Debug("Ошибка выполнения команды клиента")
+		h.reply(userID, commandResponse{Event: "error", Action: command.Action, ID: command.ID, Channel: command.Channel, Error: err.Error()})
+	}
+}
+
+// handlePreferences возвращает настройки пользователя или меняет одну из них:
+// {"action":"set_preference","type":"message","enabled":false} либо с "category".
+func (h *CommandHandler) handlePreferences(ctx context.Context, userID string, command Command) (*domain.Preferences, error) {
+	switch command.Action {
+	case ActionGetPreferences:
+		return h.preferenceService.Get(ctx, userID)
+	case ActionSetQuietHours:
+		return h.preferenceService.SetQuietHours(ctx, userID, command.QuietHours)
+	case ActionSetEmail:
+		return h.preferenceService.SetEmail(ctx, userID, command.Email)
+	case ActionVerifyEmail:
+		return h.preferenceService.VerifyEmail(ctx, userID, command.Code)
+	}
+
+	if command.Enabled == nil {
+		return nil, domain.ErrInvalidInput
+	}
+
+	switch {
+	case command.Type != "":
+		return h.preferenceService.SetType(ctx, userID, command.Type, *command.Enabled)
+	case command.Category != "":
+		return h.preferenceService.SetCategory(ctx, userID, command.Category, *command.Enabled)
+	}
+
+	return nil, domain.ErrInvalidInput
+}
+
+func (h *CommandHandler) reply(userID string, response commandResponse) {
+	message, err := json.Marshal(response)
+	if err != nil {
+		return
+	}
+
+	_ = h.wsService.SendToUser(userID, message)
+}
@@ -0,0 +1,258 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+)
+
+// DeliveryChains задаёт, через какие каналы и в каком порядке доставляются уведомления.
+type DeliveryChains struct {
+	// Default - цепочка для типов без собственной
+	Default []domain.DeliveryStep
+	ByType  map[domain.NotificationType][]domain.DeliveryStep
+	// Always получают каждое сохранённое уведомление независимо от цепочки,
+	// настроек и подключения пользователя
+	Always []string
+}
+
+func (c *DeliveryChains) chain(notificationType domain.NotificationType) []domain.DeliveryStep {
+	if chain, ok := c.ByType[notificationType]; ok {
+		return chain
+	}
+	return c.Default
+}
+
+// deliverAlways передаёт уведомление каналам, не зависящим от цепочки.
+func (s *NotificationService) deliverAlways(ctx context.Context, notification *domain.Notification) {
+	for _, name := range s.chains.Always {
+		err := s.channels[name].Deliver(ctx, notification, nil)
+		if err != nil && !errors.Is(err, domain.ErrChannelUnavailable) {
+			s.logger.WithError(err).WithFields(map[string]interface{}{
+				"notificationID": notification.ID,
+				"channel":        name,
+			}).Warn("Ошибка передачи уведомления в канал доставки")
+		}
+	}
+}
+
+// deliver проходит цепочку доставки с шага from до первой успешной
+// доставки. Задержки шагов отсчитываются от base; на шаге, время которого
+// к now ещё не наступило, обход прерывается и продолжается планировщиком
+// через DeliverFallback.
+func (s *NotificationService) deliver(ctx context.Context, notification *domain.Notification, frame []byte, from int, base time.Time, now time.Time) error {
+	chain := s.chains.chain(notification.Type)
+
+	var firstErr error
+	failedChannel := ""
+
+	for i := from; i < len(chain); i++ {
+		step := chain[i]
+
+		if sendAt := base.Add(step.After); sendAt.After(now) {
+			scheduled, err := s.scheduleFallback(ctx, notification, i, sendAt)
+			if err != nil {
+				return err
+			}
+			if scheduled {
+				// Недоставленное уведомление ещё может дойти по резервному каналу
+				return firstErr
+			}
+			break
+		}
+
+		err := s.deliverVia(ctx, notification, frame, step.Channel)
+		if err == nil {
+			return nil
+		}
+
+		if firstErr == nil {
+			firstErr = err
+			failedChannel = step.Channel
+		}
+	}
+
+	if firstErr == nil {
+		firstErr = domain.ErrChannelUnavailable
+	}
+
+	s.recordFailure(notification, deliveryFailureReason(firstErr, failedChannel))
+	return firstErr
+}
+
+// deliverVia отправляет уведомление через канал и отмечает, каким каналом
+// оно доставлено.
+func (s *NotificationService) deliverVia(ctx context.Context, notification *domain.Notification, frame []byte, name string) error {
+	log := s.logger.WithFields(map[string]interface{}{
+		"notificationID": notification.ID,
+		"userID":         notification.UserID,
+		"channel":        name,
+	})
+
+	channel, ok := s.channels[name]
+	if !ok {
+		return fmt.Errorf("неизвестный канал доставки %s", name)
+	}
+
+	err := channel.Deliver(ctx, notification, frame)
+	switch {
+	case isUndeliverable(err):
+		log.WithError(err).Debug("Канал доставки недоступен получателю")
+		return err
+	case err != nil:
+		log.WithError(err).Error("Ошибка доставки уведомления")
+		return err
+	}
+
+	s.metrics.NotificationDelivered(notification.Type, name)
+	s.tracker.Track(notification.ID, notification.UserID, domain.StageDelivered, name)
+
+	// Соединение только поставило фрейм в буфер: доставку отметит MarkWritten
+	if !domain.IsConnectionChannel(name) {
+		s.markDelivered(ctx, notification)
+	}
+
+	log.Debug("Уведомление доставлено")
+	return nil
+}
+
+// MarkWritten отмечает доставленным уведомление, фрейм которого записан
+// в соединение клиента.
+func (s *NotificationService) MarkWritten(notification *domain.Notification) {
+	s.markDelivered(context.Background(), notification)
+}
+
+func (s *NotificationService) markDelivered(ctx context.Context, notification *domain.Notification) {
+	if err := s.repository.MarkDelivered(ctx, notification.ID, time.Now()); err != nil {
+		// Без отметки резервный шаг может продублировать доставку, но не потерять её
+		s.logger.WithError(err).WithField("notificationID", notification.ID).Warn("Ошибка отметки доставки уведомления")
+	}
+}
+
+// scheduleFallback откладывает шаг цепочки до sendAt. Шаг не планируется,
+// если уведомление истечёт раньше.
+func (s *NotificationService) scheduleFallback(ctx context.Context, notification *domain.Notification, index int, sendAt time.Time) (bool, error) {
+	log := s.logger.WithFields(map[string]interface{}{
+		"notificationID": notification.ID,
+		"userID":         notification.UserID,
+		"sendAt":         sendAt,
+	})
+
+	if notification.ExpiresAt != nil && !sendAt.Before(*notification.ExpiresAt) {
+		log.Debug("Уведомление истечёт до резервного шага доставки")
+		return false, nil
+	}
+
+	stored := *notification
+	err := s.schedule.Add(ctx, &domain.ScheduledNotification{
+		ID:           fallbackID(notification.ID, index),
+		UserID:       notification.UserID,
+		SendAt:       sendAt,
+		Notification: &stored,
+		CreatedAt:    time.Now(),
+		FallbackStep: index + 1,
+	})
+	if err != nil {
+		log.WithError(err).Error("Ошибка сохранения резервного шага доставки")
+		s.recordFailure(notification, "storage")
+		s.tracker.Track(notification.ID, notification.UserID, domain.StageFailed, "storage: "+err.Error())
+		return false, err
+	}
+
+	channel := s.chains.chain(notification.Type)[index].Channel
+	s.tracker.Track(notification.ID, notification.UserID, domain.StageScheduled, "fallback "+channel+": "+sendAt.UTC().Format(time.RFC3339))
+
+	log.WithField("channel", channel).Debug("Запланирован резервный шаг доставки")
+	return true, nil
+}
+
+// DeliverFallback выполняет отложенный шаг, если уведомление так и не
+// дошло до пользователя: оно не прочитано, не истекло и не отмечено
+// доставленным ни одним узлом - ни каналом цепочки, ни при досылке после
+// переподключения. Ошибки каналов не возвращаются, чтобы недоступный канал
+// не задерживал остальное расписание.
+func (s *NotificationService) DeliverFallback(ctx context.Context, item *domain.ScheduledNotification) error {
+	log := s.logger.WithFields(map[string]interface{}{
+		"notificationID": item.Notification.ID,
+		"userID":         item.UserID,
+	})
+
+	index := item.FallbackStep - 1
+	chain := s.chains.chain(item.Notification.Type)
+	if index < 0 || index >= len(chain) || chain[index].After <= 0 {
+		log.Warn("Резервный шаг не соответствует текущей цепочке доставки")
+		return nil
+	}
+
+	notification, err := s.repository.FindByID(ctx, item.Notification.ID)
+	if errors.Is(err, domain.ErrNotFound) {
+		log.Debug("Уведомление удалено до резервного шага доставки")
+		return nil
+	}
+	if err != nil {
+		log.WithError(err).Error("Ошибка поиска уведомления")
+		return err
+	}
+
+	switch {
+	case notification.IsRead:
+		log.Debug("Уведомление прочитано, резервный шаг пропущен")
+		return nil
+	case notification.IsExpired(time.Now()):
+		log.Debug("Уведомление истекло, резервный шаг пропущен")
+		return nil
+	case notification.DeliveredAt != nil:
+		log.Debug("Уведомление уже доставлено, резервный шаг пропущен")
+		return nil
+	}
+
+	frame, err := s.encode(notification, nil)
+	if err != nil {
+		log.WithError(err).Error("Ошибка сериализации уведомления")
+		return nil
+	}
+
+	// Шаг выполняется в момент, на который он запланирован, даже если
+	// планировщик забрал его раньше
+	now := time.Now()
+	if item.SendAt.After(now) {
+		now = item.SendAt
+	}
+
+	err = s.deliver(ctx, notification, frame, index, item.SendAt.Add(-chain[index].After), now)
+	if err != nil && !isUndeliverable(err) {
+		log.WithError(err).Warn("Уведомление не доставлено резервными каналами")
+	}
+
+	return nil
+}
+
+func fallbackID(notificationID string, index int) string {
+	return notificationID + ":fallback:" + strconv.Itoa(index)
+}
+
+// isUndeliverable сообщает, что уведомление не доставлено по причине,
+// не связанной с ошибкой канала: получатель не подключён, канал ему
+// недоступен или соединение не приняло фрейм.
+func isUndeliverable(err error) bool {
+	return errors.Is(err, domain.ErrUserNotConnected) || errors.Is(err, domain.ErrChannelUnavailable) ||
+		errors.Is(err, domain.ErrMessageDropped) || errors.Is(err, domain.ErrMessageSpilled)
+}
+
+func deliveryFailureReason(err error, channel string) string {
+	switch {
+	case errors.Is(err, domain.ErrUserNotConnected):
+		return "user_not_connected"
+	case errors.Is(err, domain.ErrChannelUnavailable):
+		return "no_channel"
+	case errors.Is(err, domain.ErrMessageDropped):
+		return "backpressure"
+	case errors.Is(err, domain.ErrMessageSpilled):
+		return "spilled"
+	}
+	return channel
+}
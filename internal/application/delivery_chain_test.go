@@ -0,0 +1,438 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+	"github.com/anatoly_dev/go-ws-notifications/internal/infrastructure/diagnostics"
+	"github.com/anatoly_dev/go-ws-notifications/internal/infrastructure/metrics"
+	"github.com/anatoly_dev/go-ws-notifications/internal/infrastructure/repository"
+	"github.com/anatoly_dev/go-ws-notifications/pkg/logger"
+)
+
+// fakeChannel отвечает заданной ошибкой и считает вызовы.
+type fakeChannel struct {
+	name  string
+	err   error
+	mutex sync.Mutex
+	calls int
+}
+
+func (c *fakeChannel) Name() string {
+	return c.name
+}
+
+func (c *fakeChannel) Deliver(ctx context.Context, notification *domain.Notification, frame []byte) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.calls++
+	return c.err
+}
+
+func (c *fakeChannel) Calls() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.calls
+}
+
+type chainFixture struct {
+	service    *NotificationService
+	repository *repository.MemoryRepository
+	schedule   *repository.MemoryScheduleStore
+	tracker    *diagnostics.DeliveryTracker
+}
+
+func newChainFixture(t *testing.T, chain []domain.DeliveryStep, channels ...domain.DeliveryChannel) *chainFixture {
+	t.Helper()
+
+	log, err := logger.NewLogger("error", false)
+	if err != nil {
+		t.Fatalf("logger.NewLogger: %v", err)
+	}
+
+	recorder := metrics.NewRecorder()
+	tracker := diagnostics.NewDeliveryTracker(&diagnostics.TrackerConfig{MaxNotifications: 100, MaxEvents: 50, UserHistory: 10})
+	notifications := repository.NewMemoryRepository(nil, log)
+	schedule := repository.NewMemoryScheduleStore()
+
+	service := NewNotificationService(
+		notifications,
+		schedule,
+		repository.NewMemoryPreferenceStore(),
+		nil,
+		tracker,
+		nil,
+		"",
+		0,
+		NewDigestService(nil, tracker, time.Minute, recorder, log),
+		channels,
+		&DeliveryChains{Default: chain},
+		recorder,
+		log,
+	)
+
+	return &chainFixture{
+		service:    service,
+		repository: notifications,
+		schedule:   schedule,
+		tracker:    tracker,
+	}
+}
+
+func (f *chainFixture) stages(t *testing.T, notificationID string) map[domain.DeliveryStage][]string {
+	t.Helper()
+
+	stages := map[domain.DeliveryStage][]string{}
+
+	timeline, err := f.tracker.Timeline(notificationID)
+	if errors.Is(err, domain.ErrNotFound) {
+		return stages
+	}
+	if err != nil {
+		t.Fatalf("Timeline: %v", err)
+	}
+
+	for _, event := range timeline.Events {
+		stages[event.Stage] = append(stages[event.Stage], event.Details)
+	}
+
+	return stages
+}
+
+func TestNotificationServiceDeliver(t *testing.T) {
+	base := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	expiresSoon := base.Add(time.Minute)
+
+	tests := []struct {
+		name      string
+		chain     []domain.DeliveryStep
+		errs      map[string]error // ответы каналов
+		expiresAt *time.Time
+		now       time.Time
+		wantErr   error
+		wantCalls map[string]int
+		// wantDelivered - канал в хронологии как delivered, "" - не доставлено
+		wantDelivered string
+		// wantScheduled - время резервного шага, нулевое - шаг не запланирован
+		wantScheduled time.Time
+		wantStep      int
+	}{
+		{
+			name: "first step succeeds",
+			chain: []domain.DeliveryStep{
+				{Channel: domain.ChannelRealtime},
+				{Channel: domain.ChannelEmail, After: 5 * time.Minute},
+			},
+			now:           base,
+			wantCalls:     map[string]int{domain.ChannelRealtime: 1, domain.ChannelEmail: 0},
+			wantDelivered: domain.ChannelRealtime,
+		},
+		{
+			name: "falls back to next channel",
+			chain: []domain.DeliveryStep{
+				{Channel: domain.ChannelWebSocket},
+				{Channel: domain.ChannelWebPush},
+			},
+			errs:          map[string]error{domain.ChannelWebSocket: domain.ErrUserNotConnected},
+			now:           base,
+			wantCalls:     map[string]int{domain.ChannelWebSocket: 1, domain.ChannelWebPush: 1},
+			wantDelivered: domain.ChannelWebPush,
+		},
+		{
+			name: "skips failing channel",
+			chain: []domain.DeliveryStep{
+				{Channel: domain.ChannelWebPush},
+				{Channel: domain.ChannelRealtime},
+			},
+			errs:          map[string]error{domain.ChannelWebPush: errors.New("push service unavailable")},
+			now:           base,
+			wantCalls:     map[string]int{domain.ChannelWebPush: 1, domain.ChannelRealtime: 1},
+			wantDelivered: domain.ChannelRealtime,
+		},
+		{
+			name: "dropped frame falls back",
+			chain: []domain.DeliveryStep{
+				{Channel: domain.ChannelRealtime},
+				{Channel: domain.ChannelWebPush},
+			},
+			errs:          map[string]error{domain.ChannelRealtime: domain.ErrMessageDropped},
+			now:           base,
+			wantCalls:     map[string]int{domain.ChannelRealtime: 1, domain.ChannelWebPush: 1},
+			wantDelivered: domain.ChannelWebPush,
+		},
+		{
+			name: "spilled frame schedules after step",
+			chain: []domain.DeliveryStep{
+				{Channel: domain.ChannelRealtime},
+				{Channel: domain.ChannelEmail, After: 5 * time.Minute},
+			},
+			errs:          map[string]error{domain.ChannelRealtime: domain.ErrMessageSpilled},
+			now:           base,
+			wantErr:       domain.ErrMessageSpilled,
+			wantCalls:     map[string]int{domain.ChannelRealtime: 1, domain.ChannelEmail: 0},
+			wantScheduled: base.Add(5 * time.Minute),
+			wantStep:      2,
+		},
+		{
+			name: "schedules after step",
+			chain: []domain.DeliveryStep{
+				{Channel: domain.ChannelRealtime},
+				{Channel: domain.ChannelEmail, After: 5 * time.Minute},
+			},
+			errs:          map[string]error{domain.ChannelRealtime: domain.ErrUserNotConnected},
+			now:           base,
+			wantErr:       domain.ErrUserNotConnected,
+			wantCalls:     map[string]int{domain.ChannelRealtime: 1, domain.ChannelEmail: 0},
+			wantScheduled: base.Add(5 * time.Minute),
+			wantStep:      2,
+		},
+		{
+			name: "runs after step that is already due",
+			chain: []domain.DeliveryStep{
+				{Channel: domain.ChannelRealtime},
+				{Channel: domain.ChannelEmail, After: 5 * time.Minute},
+			},
+			errs:          map[string]error{domain.ChannelRealtime: domain.ErrUserNotConnected},
+			now:           base.Add(5 * time.Minute),
+			wantCalls:     map[string]int{domain.ChannelRealtime: 1, domain.ChannelEmail: 1},
+			wantDelivered: domain.ChannelEmail,
+		},
+		{
+			name: "does not schedule past expiry",
+			chain: []domain.DeliveryStep{
+				{Channel: domain.ChannelRealtime},
+				{Channel: domain.ChannelEmail, After: 5 * time.Minute},
+			},
+			errs:      map[string]error{domain.ChannelRealtime: domain.ErrUserNotConnected},
+			expiresAt: &expiresSoon,
+			now:       base,
+			wantErr:   domain.ErrUserNotConnected,
+			wantCalls: map[string]int{domain.ChannelRealtime: 1, domain.ChannelEmail: 0},
+		},
+		{
+			name: "no channel available",
+			chain: []domain.DeliveryStep{
+				{Channel: domain.ChannelWebPush},
+				{Channel: domain.ChannelEmail},
+			},
+			errs: map[string]error{
+				domain.ChannelWebPush: domain.ErrChannelUnavailable,
+				domain.ChannelEmail:   domain.ErrChannelUnavailable,
+			},
+			now:       base,
+			wantErr:   domain.ErrChannelUnavailable,
+			wantCalls: map[string]int{domain.ChannelWebPush: 1, domain.ChannelEmail: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			channels := map[string]*fakeChannel{}
+			list := []domain.DeliveryChannel{}
+			for _, step := range tt.chain {
+				channel := &fakeChannel{name: step.Channel, err: tt.errs[step.Channel]}
+				channels[step.Channel] = channel
+				list = append(list, channel)
+			}
+
+			fixture := newChainFixture(t, tt.chain, list...)
+			notification := &domain.Notification{
+				ID:        "n1",
+				UserID:    "user1",
+				Type:      domain.TypeMessage,
+				Title:     "title",
+				Content:   "content",
+				CreatedAt: base,
+				ExpiresAt: tt.expiresAt,
+			}
+
+			stored := *notification
+			if err := fixture.repository.Save(context.Background(), &stored); err != nil {
+				t.Fatalf("Save: %v", err)
+			}
+
+			err := fixture.service.deliver(context.Background(), notification, []byte("{}"), 0, base, tt.now)
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("deliver = %v, want nil", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("deliver = %v, want %v", err, tt.wantErr)
+			}
+
+			for name, want := range tt.wantCalls {
+				if got := channels[name].Calls(); got != want {
+					t.Errorf("канал %s вызван %d раз, want %d", name, got, want)
+				}
+			}
+
+			stages := fixture.stages(t, notification.ID)
+			delivered := stages[domain.StageDelivered]
+			switch {
+			case tt.wantDelivered == "" && len(delivered) > 0:
+				t.Errorf("delivered = %q, want none", delivered)
+			case tt.wantDelivered != "" && (len(delivered) != 1 || delivered[0] != tt.wantDelivered):
+				t.Errorf("delivered = %q, want [%s]", delivered, tt.wantDelivered)
+			}
+
+			saved, err := fixture.repository.FindByID(context.Background(), notification.ID)
+			if err != nil {
+				t.Fatalf("FindByID: %v", err)
+			}
+			// Соединение только ставит фрейм в буфер: доставку отмечает запись фрейма
+			wantMarked := tt.wantDelivered != "" && !domain.IsConnectionChannel(tt.wantDelivered)
+			if (saved.DeliveredAt != nil) != wantMarked {
+				t.Errorf("DeliveredAt = %v, want delivered = %v", saved.DeliveredAt, wantMarked)
+			}
+
+			item, err := fixture.schedule.FindByID(context.Background(), fallbackID(notification.ID, 1))
+			if tt.wantScheduled.IsZero() {
+				if err == nil {
+					t.Errorf("запланирован резервный шаг на %v, want none", item.SendAt)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("резервный шаг не запланирован: %v", err)
+			}
+			if !item.SendAt.Equal(tt.wantScheduled) {
+				t.Errorf("SendAt = %v, want %v", item.SendAt, tt.wantScheduled)
+			}
+			if item.FallbackStep != tt.wantStep {
+				t.Errorf("FallbackStep = %d, want %d", item.FallbackStep, tt.wantStep)
+			}
+			if len(stages[domain.StageScheduled]) != 1 {
+				t.Errorf("scheduled = %q, want one event", stages[domain.StageScheduled])
+			}
+		})
+	}
+}
+
+func TestNotificationServiceDeliverFallback(t *testing.T) {
+	chain := []domain.DeliveryStep{
+		{Channel: domain.ChannelRealtime},
+		{Channel: domain.ChannelEmail, After: 5 * time.Minute},
+	}
+	deliveredAt := time.Now().Add(-time.Minute)
+	expired := time.Now().Add(-time.Second)
+
+	tests := []struct {
+		name      string
+		prepare   func(notification *domain.Notification)
+		wantEmail int
+	}{
+		{
+			name:      "not delivered",
+			prepare:   func(*domain.Notification) {},
+			wantEmail: 1,
+		},
+		{
+			name:      "delivered meanwhile",
+			prepare:   func(n *domain.Notification) { n.DeliveredAt = &deliveredAt },
+			wantEmail: 0,
+		},
+		{
+			name:      "read",
+			prepare:   func(n *domain.Notification) { n.IsRead = true },
+			wantEmail: 0,
+		},
+		{
+			name:      "expired",
+			prepare:   func(n *domain.Notification) { n.ExpiresAt = &expired },
+			wantEmail: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			realtime := &fakeChannel{name: domain.ChannelRealtime, err: domain.ErrUserNotConnected}
+			email := &fakeChannel{name: domain.ChannelEmail}
+			fixture := newChainFixture(t, chain, realtime, email)
+
+			sendAt := time.Now().Add(5 * time.Minute)
+			notification := &domain.Notification{
+				ID:        "n1",
+				UserID:    "user1",
+				Type:      domain.TypeMessage,
+				Title:     "title",
+				Content:   "content",
+				CreatedAt: time.Now(),
+			}
+			tt.prepare(notification)
+			if err := fixture.repository.Save(context.Background(), notification); err != nil {
+				t.Fatalf("Save: %v", err)
+			}
+
+			err := fixture.service.DeliverFallback(context.Background(), &domain.ScheduledNotification{
+				ID:           fallbackID(notification.ID, 1),
+				UserID:       notification.UserID,
+				SendAt:       sendAt,
+				Notification: notification,
+				FallbackStep: 2,
+			})
+			if err != nil {
+				t.Fatalf("DeliverFallback: %v", err)
+			}
+
+			if got := email.Calls(); got != tt.wantEmail {
+				t.Errorf("email вызван %d раз, want %d", got, tt.wantEmail)
+			}
+			if got := realtime.Calls(); got != 0 {
+				t.Errorf("realtime вызван %d раз, want 0", got)
+			}
+		})
+	}
+}
+
+func TestNotificationServiceMarkWritten(t *testing.T) {
+	chain := []domain.DeliveryStep{
+		{Channel: domain.ChannelRealtime},
+		{Channel: domain.ChannelEmail, After: 5 * time.Minute},
+	}
+	realtime := &fakeChannel{name: domain.ChannelRealtime}
+	email := &fakeChannel{name: domain.ChannelEmail}
+	fixture := newChainFixture(t, chain, realtime, email)
+
+	notification := &domain.Notification{
+		ID:        "n1",
+		UserID:    "user1",
+		Type:      domain.TypeMessage,
+		Title:     "title",
+		Content:   "content",
+		CreatedAt: time.Now(),
+	}
+	stored := *notification
+	if err := fixture.repository.Save(context.Background(), &stored); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	fixture.service.MarkWritten(notification)
+
+	saved, err := fixture.repository.FindByID(context.Background(), notification.ID)
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	if saved.DeliveredAt == nil {
+		t.Fatal("DeliveredAt не установлен после записи фрейма")
+	}
+
+	err = fixture.service.DeliverFallback(context.Background(), &domain.ScheduledNotification{
+		ID:           fallbackID(notification.ID, 1),
+		UserID:       notification.UserID,
+		SendAt:       time.Now(),
+		Notification: notification,
+		FallbackStep: 2,
+	})
+	if err != nil {
+		t.Fatalf("DeliverFallback: %v", err)
+	}
+	if got := email.Calls(); got != 0 {
+		t.Errorf("email вызван %d раз после записи фрейма, want 0", got)
+	}
+}
@@ -0,0 +1,280 @@
+package application
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+	"github.com/anatoly_dev/go-ws-notifications/pkg/logger"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultDigestTitle   = "New notifications"
+	defaultDigestContent = "You have {count} new notifications"
+)
+
+// DigestRule описывает, какие уведомления собираются в дайджест: по типу
+// и приоритету не выше MaxPriority. Дайджест отправляется через Window после
+// первого уведомления или сразу по достижении MaxCount.
+type DigestRule struct {
+	Name        string
+	Types       []domain.NotificationType
+	MaxPriority int
+	Window      time.Duration
+	MaxCount    int
+	Title       string
+	Content     string // {count} заменяется числом уведомлений
+}
+
+func (r *DigestRule) matches(notification *domain.Notification) bool {
+	if notification.Priority > r.MaxPriority {
+		return false
+	}
+
+	if len(r.Types) == 0 {
+		return true
+	}
+
+	for _, notificationType := range r.Types {
+		if notificationType == notification.Type {
+			return true
+		}
+	}
+
+	return false
+}
+
+type digestKey struct {
+	userID string
+	rule   string
+}
+
+type digestBuffer struct {
+	rule          *DigestRule
+	userID        string
+	notifications []*domain.Notification
+	startedAt     time.Time
+}
+
+// DigestService накапливает низкоприоритетные уведомления пользователя и
+// отправляет их одним сводным уведомлением со ссылками на исходные.
+// Исходные уведомления сохраняются в хранилище до попадания в буфер, а
+// сводка - при отправке, после чего проходит цепочку доставки NotificationService.
+type DigestService struct {
+	rules    []DigestRule
+	tracker  domain.DeliveryTracker
+	interval time.Duration
+	metrics  domain.Metrics
+	logger   *logger.Logger
+	buffers  map[digestKey]*digestBuffer
+	mutex    sync.Mutex
+	stop     chan struct{}
+	wg       sync.WaitGroup
+
+	// deliver сохраняет и доставляет дайджест через NotificationService;
+	// summary - уведомление создано дайджестом и ещё не сохранено
+	deliver func(ctx context.Context, notification *domain.Notification, summary bool) error
+}
+
+func NewDigestService(
+	rules []DigestRule,
+	tracker domain.DeliveryTracker,
+	interval time.Duration,
+	metrics domain.Metrics,
+	logger *logger.Logger,
+) *DigestService {
+	return &DigestService{
+		rules:    rules,
+		tracker:  tracker,
+		interval: interval,
+		metrics:  metrics,
+		logger:   logger.WithField("source", "digest_service"),
+		buffers:  make(map[digestKey]*digestBuffer),
+		stop:     make(chan struct{}),
+	}
+}
+
+func (s *DigestService) Start() {
+	if len(s.rules) == 0 {
+		return
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				s.FlushDue(time.Now())
+			}
+		}
+	}()
+}
+
+// Stop останавливает таймер и отправляет все накопленные дайджесты.
+func (s *DigestService) Stop() {
+	close(s.stop)
+	s.wg.Wait()
+
+	s.Flush()
+}
+
+// Flush отправляет все накопленные дайджесты, не дожидаясь окончания окна.
+func (s *DigestService) Flush() {
+	s.mutex.Lock()
+	buffers := make([]*digestBuffer, 0, len(s.buffers))
+	for key, buffer := range s.buffers {
+		buffers = append(buffers, buffer)
+		delete(s.buffers, key)
+	}
+	s.mutex.Unlock()
+
+	for _, buffer := range buffers {
+		s.emit(buffer, "shutdown")
+	}
+}
+
+// Add помещает уведомление в буфер дайджеста, если для него есть правило.
+// Возвращает false, если уведомление нужно отправить сразу.
+func (s *DigestService) Add(notification *domain.Notification) bool {
+	rule := s.match(notification)
+	if rule == nil {
+		return false
+	}
+
+	key := digestKey{userID: notification.UserID, rule: rule.Name}
+
+	s.mutex.Lock()
+	buffer, ok := s.buffers[key]
+	if !ok {
+		buffer = &digestBuffer{
+			rule:      rule,
+			userID:    notification.UserID,
+			startedAt: time.Now(),
+		}
+		s.buffers[key] = buffer
+	}
+	buffer.notifications = append(buffer.notifications, notification)
+
+	full := rule.MaxCount > 0 && len(buffer.notifications) >= rule.MaxCount
+	if full {
+		delete(s.buffers, key)
+	}
+	s.mutex.Unlock()
+
+	s.metrics.NotificationDigested(rule.Name)
+	s.tracker.Track(notification.ID, notification.UserID, domain.StageDigested, rule.Name)
+
+	if full {
+		s.emit(buffer, "count")
+	}
+
+	return true
+}
+
+func (s *DigestService) FlushDue(now time.Time) {
+	s.mutex.Lock()
+	due := []*digestBuffer{}
+	for key, buffer := range s.buffers {
+		if !now.Before(buffer.startedAt.Add(buffer.rule.Window)) {
+			due = append(due, buffer)
+			delete(s.buffers, key)
+		}
+	}
+	s.mutex.Unlock()
+
+	for _, buffer := range due {
+		s.emit(buffer, "window")
+	}
+}
+
+func (s *DigestService) match(notification *domain.Notification) *DigestRule {
+	for i := range s.rules {
+		if s.rules[i].matches(notification) {
+			return &s.rules[i]
+		}
+	}
+	return nil
+}
+
+func (s *DigestService) emit(buffer *digestBuffer, reason string) {
+	log := s.logger.WithFields(map[string]interface{}{
+		"userID": buffer.userID,
+		"rule":   buffer.rule.Name,
+		"reason": reason,
+	})
+
+	now := time.Now()
+	notifications := make([]*domain.Notification, 0, len(buffer.notifications))
+	for _, notification := range buffer.notifications {
+		if !notification.IsExpired(now) {
+			notifications = append(notifications, notification)
+		}
+	}
+
+	if len(notifications) == 0 {
+		return
+	}
+
+	// Одиночное уведомление отправляется как есть, без сводки
+	notification := notifications[0]
+	if len(notifications) > 1 {
+		notification = newDigestNotification(buffer.rule, buffer.userID, notifications, now)
+	}
+
+	s.metrics.DigestEmitted(buffer.rule.Name, reason)
+
+	err := s.deliver(context.Background(), notification, len(notifications) > 1)
+	if err != nil {
+		if isUndeliverable(err) {
+			log.Debug("Дайджест сохранён, но сейчас не доставлен")
+			return
+		}
+		log.WithError(err).Error("Ошибка отправки дайджеста")
+		return
+	}
+
+	log.WithField("count", len(notifications)).Debug("Дайджест отправлен")
+}
+
+func newDigestNotification(rule *DigestRule, userID string, notifications []*domain.Notification, now time.Time) *domain.Notification {
+	title := rule.Title
+	if title == "" {
+		title = defaultDigestTitle
+	}
+
+	content := rule.Content
+	if content == "" {
+		content = defaultDigestContent
+	}
+
+	last := notifications[len(notifications)-1]
+	digest := &domain.Notification{
+		ID:         uuid.New().String(),
+		UserID:     userID,
+		Type:       last.Type,
+		Title:      title,
+		Content:    strings.ReplaceAll(content, "{count}", strconv.Itoa(len(notifications))),
+		CreatedAt:  now,
+		GroupedIDs: make([]string, 0, len(notifications)),
+	}
+
+	for _, notification := range notifications {
+		digest.GroupedIDs = append(digest.GroupedIDs, notification.ID)
+		if notification.Priority > digest.Priority {
+			digest.Priority = notification.Priority
+		}
+	}
+
+	return digest
+}
@@ -0,0 +1,75 @@
+package application
+
+import (
+	"context"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+	"github.com/anatoly_dev/go-ws-notifications/pkg/logger"
+)
+
+// EmailChannel отправляет уведомление письмом на адрес из настроек пользователя.
+type EmailChannel struct {
+	sender      domain.EmailSender
+	preferences domain.PreferenceStore
+	tracker     domain.DeliveryTracker
+	metrics     domain.Metrics
+	logger      *logger.Logger
+}
+
+// NewEmailChannel создаёт канал; при sender == nil доставка по почте выключена.
+func NewEmailChannel(
+	sender domain.EmailSender,
+	preferences domain.PreferenceStore,
+	tracker domain.DeliveryTracker,
+	metrics domain.Metrics,
+	logger *logger.Logger,
+) *EmailChannel {
+	return &EmailChannel{
+		sender:      sender,
+		preferences: preferences,
+		tracker:     tracker,
+		metrics:     metrics,
+		logger:      logger.WithField("source", "email_channel"),
+	}
+}
+
+func (c *EmailChannel) Name() string {
+	return domain.ChannelEmail
+}
+
+func (c *EmailChannel) Deliver(ctx context.Context, notification *domain.Notification, frame []byte) error {
+	if c.sender == nil {
+		return domain.ErrChannelUnavailable
+	}
+
+	log := c.logger.WithFields(map[string]interface{}{
+		"notificationID": notification.ID,
+		"userID":         notification.UserID,
+	})
+
+	preferences, err := c.preferences.Get(ctx, notification.UserID)
+	if err != nil {
+		log.WithError(err).Error("Ошибка чтения настроек пользователя")
+		return err
+	}
+
+	if preferences.Email == "" {
+		return domain.ErrChannelUnavailable
+	}
+
+	err = c.sender.Send(ctx, &domain.EmailMessage{
+		To:      preferences.Email,
+		Subject: notification.Title,
+		Body:    notification.Content,
+	})
+	if err != nil {
+		c.metrics.EmailMessage("failed")
+		log.WithError(err).Error("Ошибка отправки письма")
+		c.tracker.Track(notification.ID, notification.UserID, domain.StageFailed, "email: "+err.Error())
+		return err
+	}
+
+	c.metrics.EmailMessage("sent")
+	c.tracker.Track(notification.ID, notification.UserID, domain.StageEmailed, "")
+	return nil
+}
@@ -0,0 +1,104 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+	"github.com/anatoly_dev/go-ws-notifications/pkg/logger"
+)
+
+const expiryBatchSize = 500
+
+// ExpiryService периодически удаляет истёкшие уведомления из хранилища
+// и сообщает подключённым клиентам, что уведомление нужно убрать.
+type ExpiryService struct {
+	repository domain.NotificationRepository
+	wsService  domain.WebSocketService
+	interval   time.Duration
+	logger     *logger.Logger
+	stop       chan struct{}
+	wg         sync.WaitGroup
+}
+
+func NewExpiryService(
+	repository domain.NotificationRepository,
+	wsService domain.WebSocketService,
+	interval time.Duration,
+	logger *logger.Logger,
+) *ExpiryService {
+	return &ExpiryService{
+		repository: repository,
+		wsService:  wsService,
+		interval:   interval,
+		logger:     logger.WithField("source", "expiry_service"),
+		stop:       make(chan struct{}),
+	}
+}
+
+func (s *ExpiryService) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				s.PurgeExpired(context.Background(), time.Now())
+			}
+		}
+	}()
+}
+
+func (s *ExpiryService) Stop() {
+	close(s.stop)
+	s.wg.Wait()
+}
+
+func (s *ExpiryService) PurgeExpired(ctx context.Context, now time.Time) {
+	for {
+		expired, err := s.repository.FindExpired(ctx, now, expiryBatchSize)
+		if err != nil {
+			s.logger.WithError(err).Error("Ошибка поиска истёкших уведомлений")
+			return
+		}
+
+		for _, notification := range expired {
+			if err := s.repository.Delete(ctx, notification.ID); err != nil {
+				s.logger.WithError(err).WithField("notificationID", notification.ID).Error("Ошибка удаления истёкшего уведомления")
+				return
+			}
+
+			s.notifyRemoved(notification)
+		}
+
+		if len(expired) > 0 {
+			s.logger.WithField("count", len(expired)).Debug("Удалены истёкшие уведомления")
+		}
+
+		if len(expired) < expiryBatchSize {
+			return
+		}
+	}
+}
+
+func (s *ExpiryService) notifyRemoved(notification *domain.Notification) {
+	message, err := json.Marshal(domain.NotificationEvent{
+		Event:  domain.EventNotificationRemoved,
+		ID:     notification.ID,
+		Reason: "expired",
+	})
+	if err != nil {
+		return
+	}
+
+	// Пользователь может быть не подключён - тогда удаление из хранилища достаточно
+	_ = s.wsService.SendToUser(notification.UserID, message)
+}
@@ -1,48 +1,169 @@
 package application
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 
 	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
 	"github.com/anatoly_dev/go-ws-notifications/pkg/logger"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type KafkaHandler struct {
 	notificationService domain.NotificationService
+	channelService      domain.ChannelService
+	broadcastService    domain.BroadcastService
+	tracker             domain.DeliveryTracker
 	logger              *logger.Logger
 }
 
-func NewKafkaHandler(notificationService domain.NotificationService, logger *logger.Logger) *KafkaHandler {
+func NewKafkaHandler(
+	notificationService domain.NotificationService,
+	channelService domain.ChannelService,
+	broadcastService domain.BroadcastService,
+	tracker domain.DeliveryTracker,
+	logger *logger.Logger,
+) *KafkaHandler {
 	return &KafkaHandler{
 		notificationService: notificationService,
+		channelService:      channelService,
+		broadcastService:    broadcastService,
+		tracker:             tracker,
 		logger:              logger,
 	}
 }
 
-func (h *KafkaHandler) HandleMessage(message []byte) error {
-	ctx := h.logger.WithField("source", "kafka_handler")
+func (h *KafkaHandler) HandleMessage(ctx context.Context, message []byte) error {
+	ctx, span := tracer.Start(ctx, "KafkaHandler.HandleMessage")
+	defer span.End()
 
-	ctx.Info("Получено новое сообщение из Kafka")
+	log := h.logger.WithField("source", "kafka_handler")
+
+	log.Debug("Получено новое сообщение из Kafka")
+
+	// Сообщения с полем channel публикуются в канал, а с kind: broadcast -
+	// всем подключённым клиентам; остальные адресованы пользователям
+	var envelope struct {
+		Kind    string `json:"kind"`
+		Channel string `json:"channel"`
+	}
+	if err := json.Unmarshal(message, &envelope); err != nil {
+		log.WithError(err).Error("Ошибка десериализации сообщения из Kafka")
+		recordSpanError(span, err)
+		return err
+	}
+
+	if envelope.Kind == domain.KindBroadcast {
+		return h.handleBroadcast(ctx, message)
+	}
+
+	if envelope.Channel != "" {
+		return h.handleChannelMessage(ctx, message)
+	}
 
 	var notification domain.Notification
 	if err := json.Unmarshal(message, &notification); err != nil {
-		ctx.WithError(err).Error("Ошибка десериализации сообщения из Kafka")
+		log.WithError(err).Error("Ошибка десериализации сообщения из Kafka")
+		recordSpanError(span, err)
 		return err
 	}
 
-	ctx = ctx.WithFields(map[string]interface{}{
+	// Идентификатор назначается здесь, чтобы этап consumed попал в хронологию доставки
+	if notification.ID == "" {
+		notification.ID = uuid.New().String()
+	}
+
+	h.tracker.Track(notification.ID, notification.UserID, domain.StageConsumed, "")
+
+	span.SetAttributes(
+		attribute.String("notification.id", notification.ID),
+		attribute.String("notification.user_id", notification.UserID),
+	)
+
+	log = log.WithFields(map[string]interface{}{
 		"notificationID": notification.ID,
 		"userID":         notification.UserID,
 		"type":           notification.Type,
 	})
 
-	ctx.Info("Обработка уведомления из Kafka")
+	log.Debug("Обработка уведомления из Kafka")
+
+	if err := h.notificationService.Send(ctx, &notification); err != nil {
+		if errors.Is(err, domain.ErrExpired) {
+			log.Debug("Пропущено истёкшее уведомление из Kafka")
+			return nil
+		}
+		if errors.Is(err, domain.ErrMuted) {
+			log.Debug("Уведомление из Kafka отброшено настройками пользователя")
+			return nil
+		}
+		log.WithError(err).Error("Ошибка отправки уведомления")
+		recordSpanError(span, err)
+		return err
+	}
+
+	log.Debug("Уведомление из Kafka успешно обработано")
+	return nil
+}
 
-	if err := h.notificationService.Send(&notification); err != nil {
-		ctx.WithError(err).Error("Ошибка отправки уведомления")
+func (h *KafkaHandler) handleChannelMessage(ctx context.Context, message []byte) error {
+	ctx, span := tracer.Start(ctx, "KafkaHandler.handleChannelMessage")
+	defer span.End()
+
+	log := h.logger.WithField("source", "kafka_handler")
+
+	var channelMessage domain.ChannelMessage
+	if err := json.Unmarshal(message, &channelMessage); err != nil {
+		log.WithError(err).Error("Ошибка десериализации сообщения канала из Kafka")
+		recordSpanError(span, err)
+		return err
+	}
+
+	span.SetAttributes(attribute.String("channel", channelMessage.Channel))
+
+	delivered, err := h.channelService.Publish(ctx, &channelMessage)
+	if err != nil {
+		log.WithError(err).WithField("channel", channelMessage.Channel).Error("Ошибка публикации сообщения в канал")
+		recordSpanError(span, err)
+		return err
+	}
+
+	log.WithFields(map[string]interface{}{
+		"channel":     channelMessage.Channel,
+		"subscribers": delivered,
+	}).Debug("Сообщение канала из Kafka опубликовано")
+	return nil
+}
+
+func (h *KafkaHandler) handleBroadcast(ctx context.Context, message []byte) error {
+	ctx, span := tracer.Start(ctx, "KafkaHandler.handleBroadcast")
+	defer span.End()
+
+	log := h.logger.WithField("source", "kafka_handler")
+
+	var broadcast domain.Broadcast
+	if err := json.Unmarshal(message, &broadcast); err != nil {
+		log.WithError(err).Error("Ошибка десериализации объявления из Kafka")
+		recordSpanError(span, err)
+		return err
+	}
+
+	delivered, err := h.broadcastService.Send(ctx, &broadcast)
+	if errors.Is(err, domain.ErrExpired) {
+		log.WithField("broadcastID", broadcast.ID).Debug("Пропущено истёкшее объявление из Kafka")
+		return nil
+	}
+	if err != nil {
+		log.WithError(err).Error("Ошибка публикации объявления")
+		recordSpanError(span, err)
 		return err
 	}
 
-	ctx.Info("Уведомление из Kafka успешно обработано")
+	log.WithFields(map[string]interface{}{
+		"broadcastID": broadcast.ID,
+		"clients":     delivered,
+	}).Debug("Объявление из Kafka опубликовано")
 	return nil
 }
@@ -1,34 +1,93 @@
 package application
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
 	"github.com/anatoly_dev/go-ws-notifications/pkg/logger"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type NotificationService struct {
-	repository domain.NotificationRepository
-	wsService  domain.WebSocketService
-	logger     *logger.Logger
+	repository          domain.NotificationRepository
+	schedule            domain.ScheduleStore
+	preferences         domain.PreferenceStore
+	directory           domain.GroupDirectory
+	tracker             domain.DeliveryTracker
+	defaultTTLs         map[domain.NotificationType]time.Duration
+	mutedPolicy         string
+	quietBypassPriority int // приоритет, с которого уведомления доставляются и в тихие часы
+	digest              *DigestService
+	channels            map[string]domain.DeliveryChannel
+	chains              *DeliveryChains
+	metrics             domain.Metrics
+	logger              *logger.Logger
 }
 
 func NewNotificationService(
 	repository domain.NotificationRepository,
-	wsService domain.WebSocketService,
+	schedule domain.ScheduleStore,
+	preferences domain.PreferenceStore,
+	directory domain.GroupDirectory,
+	tracker domain.DeliveryTracker,
+	defaultTTLs map[domain.NotificationType]time.Duration,
+	mutedPolicy string,
+	quietBypassPriority int,
+	digest *DigestService,
+	channels []domain.DeliveryChannel,
+	chains *DeliveryChains,
+	metrics domain.Metrics,
 	logger *logger.Logger,
 ) *NotificationService {
-	return &NotificationService{
-		repository: repository,
-		wsService:  wsService,
-		logger:     logger,
+	byName := make(map[string]domain.DeliveryChannel, len(channels))
+	for _, channel := range channels {
+		byName[channel.Name()] = channel
 	}
+
+	s := &NotificationService{
+		repository:          repository,
+		schedule:            schedule,
+		preferences:         preferences,
+		directory:           directory,
+		tracker:             tracker,
+		defaultTTLs:         defaultTTLs,
+		mutedPolicy:         mutedPolicy,
+		quietBypassPriority: quietBypassPriority,
+		digest:              digest,
+		channels:            byName,
+		chains:              chains,
+		metrics:             metrics,
+		logger:              logger,
+	}
+	digest.deliver = s.deliverDigest
+
+	return s
 }
 
-func (s *NotificationService) Send(notification *domain.Notification) error {
-	ctx := s.logger.WithFields(map[string]interface{}{
+func (s *NotificationService) Send(ctx context.Context, notification *domain.Notification) error {
+	return s.send(ctx, notification, time.Time{})
+}
+
+// SendScheduled отправляет наступившее отложенное уведомление. CreatedAt
+// сохраняется, а TTL отсчитывается от фактической отправки sentAt.
+func (s *NotificationService) SendScheduled(ctx context.Context, notification *domain.Notification, sentAt time.Time) error {
+	notification.ClearSchedule()
+	return s.send(ctx, notification, sentAt)
+}
+
+// send отправляет уведомление; нулевой sentAt означает отправку в момент
+// создания.
+func (s *NotificationService) send(ctx context.Context, notification *domain.Notification, sentAt time.Time) error {
+	ctx, span := tracer.Start(ctx, "NotificationService.Send")
+	defer span.End()
+
+	log := s.logger.WithFields(map[string]interface{}{
 		"notificationID": notification.ID,
 		"userID":         notification.UserID,
 		"type":           notification.Type,
@@ -41,59 +100,467 @@ func (s *NotificationService) Send(notification *domain.Notification) error {
 	if notification.CreatedAt.IsZero() {
 		notification.CreatedAt = time.Now()
 	}
+	if sentAt.IsZero() {
+		sentAt = notification.CreatedAt
+	}
+
+	// Отметку доставки ставит только сервис
+	notification.DeliveredAt = nil
+
+	span.SetAttributes(
+		attribute.String("notification.id", notification.ID),
+		attribute.String("notification.user_id", notification.UserID),
+		attribute.String("notification.type", string(notification.Type)),
+	)
+
+	if notification.Recipients != nil {
+		err := s.fanOut(ctx, notification)
+		if err != nil {
+			log.WithError(err).Error("Ошибка рассылки уведомления получателям")
+			recordSpanError(span, err)
+		}
+		return err
+	}
 
 	err := notification.Validate()
 	if err != nil {
-		ctx.WithError(err).Error("Ошибка валидации уведомления")
+		log.WithError(err).Error("Ошибка валидации уведомления")
+		s.recordFailure(notification, "validation")
+		s.tracker.Track(notification.ID, notification.UserID, domain.StageFailed, "validation: "+err.Error())
+		recordSpanError(span, err)
+		return err
+	}
+
+	s.tracker.Track(notification.ID, notification.UserID, domain.StageValidated, "")
+
+	now := time.Now()
+	sendAt, err := notification.ResolveSendAt(now)
+	if err != nil {
+		log.WithError(err).Error("Ошибка валидации времени отложенной доставки")
+		s.recordFailure(notification, "validation")
+		s.tracker.Track(notification.ID, notification.UserID, domain.StageFailed, "validation: "+err.Error())
+		recordSpanError(span, err)
+		return err
+	}
+
+	if sendAt.After(now) {
+		err = s.scheduleAt(ctx, notification, sendAt, now, "")
+		if err != nil {
+			recordSpanError(span, err)
+		}
+		return err
+	}
+
+	notification.ApplyExpiry(sentAt, s.defaultTTLs[notification.Type])
+	if notification.IsExpired(time.Now()) {
+		log.Debug("Уведомление истекло до отправки")
+		s.recordFailure(notification, "expired")
+		s.tracker.Track(notification.ID, notification.UserID, domain.StageFailed, "expired")
+		return domain.ErrExpired
+	}
+
+	preferences, err := s.preferences.Get(ctx, notification.UserID)
+	if err != nil {
+		log.WithError(err).Error("Ошибка чтения настроек пользователя")
+		s.recordFailure(notification, "storage")
+		s.tracker.Track(notification.ID, notification.UserID, domain.StageFailed, "preferences: "+err.Error())
+		recordSpanError(span, err)
 		return err
 	}
 
-	err = s.repository.Save(notification)
+	muted := !preferences.Allows(notification)
+	if muted && s.mutedPolicy == domain.MutedDrop {
+		log.Debug("Уведомление отброшено настройками пользователя")
+		s.recordFailure(notification, "muted")
+		s.tracker.Track(notification.ID, notification.UserID, domain.StageMuted, domain.MutedDrop)
+		return domain.ErrMuted
+	}
+
+	if !muted && preferences.QuietHours != nil && notification.Priority < s.quietBypassPriority {
+		if until, active := preferences.QuietHours.Until(now); active {
+			log.Debug("Уведомление отложено до окончания тихих часов")
+			err = s.scheduleAt(ctx, notification, until, now, "quiet_hours")
+			if err != nil {
+				recordSpanError(span, err)
+			}
+			return err
+		}
+	}
+
+	replaced, err := s.findCollapsed(ctx, notification)
 	if err != nil {
-		ctx.WithError(err).Error("Ошибка сохранения уведомления")
+		log.WithError(err).Error("Ошибка поиска уведомления по ключу схлопывания")
+		s.recordFailure(notification, "storage")
+		s.tracker.Track(notification.ID, notification.UserID, domain.StageFailed, "storage: "+err.Error())
+		recordSpanError(span, err)
 		return err
 	}
 
-	message, err := json.Marshal(notification)
+	err = s.save(ctx, notification)
 	if err != nil {
-		ctx.WithError(err).Error("Ошибка сериализации уведомления")
+		log.WithError(err).Error("Ошибка сохранения уведомления")
+		s.recordFailure(notification, "storage")
+		s.tracker.Track(notification.ID, notification.UserID, domain.StageFailed, "storage: "+err.Error())
+		recordSpanError(span, err)
 		return err
 	}
 
-	err = s.wsService.SendToUser(notification.UserID, message)
+	s.tracker.Track(notification.ID, notification.UserID, domain.StageSaved, "")
+
+	if replaced != nil {
+		// Новое уведомление уже сохранено, поэтому при ошибке удаления останется дубль, но не потеря
+		if err := s.repository.Delete(ctx, replaced.ID); err != nil && !errors.Is(err, domain.ErrNotFound) {
+			log.WithError(err).WithField("replacedID", replaced.ID).Warn("Ошибка удаления заменённого уведомления")
+		}
+		s.tracker.Track(replaced.ID, replaced.UserID, domain.StageReplaced, notification.ID)
+	}
+
+	// Подписчики-сервисы получают уведомление независимо от настроек и подключения пользователя
+	s.deliverAlways(ctx, notification)
+
+	if muted {
+		log.Debug("Уведомление сохранено без отправки по настройкам пользователя")
+		s.tracker.Track(notification.ID, notification.UserID, domain.StageMuted, domain.MutedSilent)
+		return nil
+	}
+
+	if replaced == nil && s.digest.Add(notification) {
+		log.Debug("Уведомление добавлено в дайджест")
+		return nil
+	}
+
+	message, err := s.encode(notification, replaced)
 	if err != nil {
-		ctx.WithError(err).Error("Ошибка отправки уведомления через WebSocket")
+		log.WithError(err).Error("Ошибка сериализации уведомления")
+		s.recordFailure(notification, "serialization")
+		recordSpanError(span, err)
+		return err
+	}
+
+	err = s.deliver(ctx, notification, message, 0, now, now)
+	if err != nil {
+		log.WithError(err).Debug("Уведомление не доставлено ни одним каналом")
+		recordSpanError(span, err)
 		return err
 	}
 
-	ctx.Info("Уведомление успешно отправлено")
 	return nil
 }
 
-func (s *NotificationService) MarkAsRead(id string, userID string) error {
-	ctx := s.logger.WithFields(map[string]interface{}{
+// scheduleAt откладывает доставку до sendAt. Для send_at TTL отсчитывается от
+// фактической отправки, поэтому здесь проверяется только уже известный expires_at.
+func (s *NotificationService) scheduleAt(ctx context.Context, notification *domain.Notification, sendAt time.Time, now time.Time, reason string) error {
+	log := s.logger.WithFields(map[string]interface{}{
+		"notificationID": notification.ID,
+		"userID":         notification.UserID,
+		"sendAt":         sendAt,
+	})
+
+	if notification.ExpiresAt != nil && !sendAt.Before(*notification.ExpiresAt) {
+		log.Debug("Уведомление истечёт до времени отложенной доставки")
+		s.recordFailure(notification, "expired")
+		s.tracker.Track(notification.ID, notification.UserID, domain.StageFailed, "expired")
+		return domain.ErrExpired
+	}
+
+	err := s.schedule.Add(ctx, &domain.ScheduledNotification{
+		ID:           notification.ID,
+		UserID:       notification.UserID,
+		SendAt:       sendAt,
+		Notification: notification,
+		CreatedAt:    now,
+	})
+	if err != nil {
+		log.WithError(err).Error("Ошибка сохранения отложенного уведомления")
+		s.recordFailure(notification, "storage")
+		s.tracker.Track(notification.ID, notification.UserID, domain.StageFailed, "storage: "+err.Error())
+		return err
+	}
+
+	details := sendAt.UTC().Format(time.RFC3339)
+	if reason != "" {
+		details = reason + ": " + details
+	}
+	s.tracker.Track(notification.ID, notification.UserID, domain.StageScheduled, details)
+
+	log.Debug("Уведомление запланировано")
+	return nil
+}
+
+// History возвращает сохранённые уведомления пользователя, включая
+// собранные в дайджесты и заглушённые.
+func (s *NotificationService) History(ctx context.Context, userID string) ([]*domain.Notification, error) {
+	return s.repository.FindByUserID(ctx, userID)
+}
+
+// ListScheduled не показывает резервные шаги цепочек доставки: их
+// планирует сервис, а не пользователь.
+func (s *NotificationService) ListScheduled(ctx context.Context, userID string) ([]*domain.ScheduledNotification, error) {
+	items, err := s.schedule.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	scheduled := make([]*domain.ScheduledNotification, 0, len(items))
+	for _, item := range items {
+		if item.FallbackStep == 0 {
+			scheduled = append(scheduled, item)
+		}
+	}
+
+	return scheduled, nil
+}
+
+func (s *NotificationService) CancelScheduled(ctx context.Context, id string, userID string) error {
+	log := s.logger.WithFields(map[string]interface{}{
 		"notificationID": id,
 		"userID":         userID,
 	})
 
-	notification, err := s.repository.FindByID(id)
+	item, err := s.schedule.FindByID(ctx, id)
 	if err != nil {
-		ctx.WithError(err).Error("Ошибка поиска уведомления")
+		return err
+	}
+
+	if item.FallbackStep > 0 {
+		return domain.ErrNotFound
+	}
+
+	if item.UserID != userID {
+		log.Error("Попытка отменить чужое отложенное уведомление")
+		return domain.ErrUnauthorized
+	}
+
+	if err := s.schedule.Remove(ctx, id); err != nil {
+		log.WithError(err).Error("Ошибка отмены отложенного уведомления")
+		return err
+	}
+
+	s.tracker.Track(id, userID, domain.StageCancelled, "")
+
+	log.Info("Отложенное уведомление отменено")
+	return nil
+}
+
+// fanOut рассылает копию уведомления каждому получателю из recipients.
+// Идентификаторы копий выводятся из исходного, поэтому повторная обработка
+// того же сообщения перезаписывает копии, а не создаёт новые.
+func (s *NotificationService) fanOut(ctx context.Context, notification *domain.Notification) error {
+	userIDs, err := s.resolveRecipients(ctx, notification)
+	if err != nil {
+		s.recordFailure(notification, "validation")
+		s.tracker.Track(notification.ID, notification.UserID, domain.StageFailed, "recipients: "+err.Error())
+		return err
+	}
+
+	s.tracker.Track(notification.ID, notification.UserID, domain.StageFannedOut, strconv.Itoa(len(userIDs)))
+
+	// Ошибка одного получателя не возвращается: повторная обработка
+	// сообщения заново отправила бы фрейм всем, кто его уже получил.
+	// Send сам учитывает сбой в метриках и истории доставки копии.
+	failed := 0
+	for _, userID := range userIDs {
+		recipientCopy := *notification
+		recipientCopy.ID = uuid.NewSHA1(uuid.NameSpaceURL, []byte(notification.ID+"/"+userID)).String()
+		recipientCopy.UserID = userID
+		recipientCopy.Recipients = nil
+
+		err := s.Send(ctx, &recipientCopy)
+		if err != nil && !isFinalSendError(err) {
+			failed++
+			s.logger.WithError(err).WithFields(map[string]interface{}{
+				"notificationID": notification.ID,
+				"copyID":         recipientCopy.ID,
+				"userID":         userID,
+			}).Warn("Уведомление не отправлено получателю рассылки")
+		}
+	}
+
+	s.logger.WithFields(map[string]interface{}{
+		"notificationID": notification.ID,
+		"recipients":     len(userIDs),
+		"failed":         failed,
+	}).Debug("Уведомление разослано получателям")
+
+	return nil
+}
+
+// resolveRecipients раскрывает группы через каталог и возвращает
+// получателей без повторов в порядке первого упоминания.
+func (s *NotificationService) resolveRecipients(ctx context.Context, notification *domain.Notification) ([]string, error) {
+	recipients := notification.Recipients
+
+	groups := recipients.Groups
+	if recipients.All {
+		groups = []string{domain.GroupAll}
+	}
+
+	seen := map[string]bool{}
+	userIDs := []string{}
+	add := func(userID string) {
+		if userID != "" && !seen[userID] {
+			seen[userID] = true
+			userIDs = append(userIDs, userID)
+		}
+	}
+
+	add(notification.UserID)
+	for _, userID := range recipients.UserIDs {
+		add(userID)
+	}
+
+	for _, group := range groups {
+		members, err := s.directory.Members(ctx, group)
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, fmt.Errorf("%w: неизвестная группа %s", domain.ErrInvalidInput, group)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		for _, userID := range members {
+			add(userID)
+		}
+	}
+
+	if len(userIDs) == 0 {
+		return nil, fmt.Errorf("%w: не указаны получатели", domain.ErrInvalidInput)
+	}
+
+	return userIDs, nil
+}
+
+// findCollapsed возвращает непрочитанное уведомление, которое заменит новое
+// уведомление с тем же collapse_key, или nil.
+func (s *NotificationService) findCollapsed(ctx context.Context, notification *domain.Notification) (*domain.Notification, error) {
+	if notification.CollapseKey == "" {
+		return nil, nil
+	}
+
+	previous, err := s.repository.FindUnreadByCollapseKey(ctx, notification.UserID, notification.CollapseKey)
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Повторная доставка того же сообщения ничего не заменяет
+	if previous.ID == notification.ID {
+		return nil, nil
+	}
+
+	return previous, nil
+}
+
+// encode формирует фрейм для клиента: само уведомление или событие замены
+// ранее доставленного уведомления.
+func (s *NotificationService) encode(notification *domain.Notification, replaced *domain.Notification) ([]byte, error) {
+	if replaced == nil {
+		return json.Marshal(notification)
+	}
+
+	return json.Marshal(domain.NotificationEvent{
+		Event:        domain.EventNotificationReplaced,
+		ID:           replaced.ID,
+		Notification: notification,
+	})
+}
+
+// deliverDigest доставляет дайджест, как обычное уведомление: сводка
+// сохраняется в историю и проходит цепочку доставки. Одиночное уведомление
+// из дайджеста уже сохранено в Send.
+func (s *NotificationService) deliverDigest(ctx context.Context, notification *domain.Notification, summary bool) error {
+	if summary {
+		if err := s.save(ctx, notification); err != nil {
+			s.logger.WithError(err).WithField("notificationID", notification.ID).Error("Ошибка сохранения дайджеста")
+			s.recordFailure(notification, "storage")
+			s.tracker.Track(notification.ID, notification.UserID, domain.StageFailed, "storage: "+err.Error())
+			return err
+		}
+		s.tracker.Track(notification.ID, notification.UserID, domain.StageSaved, "")
+	}
+
+	message, err := s.encode(notification, nil)
+	if err != nil {
+		s.recordFailure(notification, "serialization")
+		return err
+	}
+
+	now := time.Now()
+	return s.deliver(ctx, notification, message, 0, now, now)
+}
+
+func (s *NotificationService) save(ctx context.Context, notification *domain.Notification) error {
+	ctx, span := tracer.Start(ctx, "NotificationRepository.Save")
+	defer span.End()
+
+	if err := s.repository.Save(ctx, notification); err != nil {
+		recordSpanError(span, err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *NotificationService) recordFailure(notification *domain.Notification, reason string) {
+	s.metrics.NotificationFailed(notification.Type, reason)
+}
+
+// Ack отмечает в истории доставки, что клиент получил уведомление.
+func (s *NotificationService) Ack(ctx context.Context, id string, userID string) error {
+	log := s.logger.WithFields(map[string]interface{}{
+		"notificationID": id,
+		"userID":         userID,
+	})
+
+	notification, err := s.repository.FindByID(ctx, id)
+	if err != nil {
+		log.WithError(err).Debug("Подтверждение неизвестного уведомления")
+		return err
+	}
+
+	if notification.UserID != userID {
+		log.Warn("Попытка подтвердить чужое уведомление")
+		return domain.ErrUnauthorized
+	}
+
+	s.tracker.Track(id, userID, domain.StageAcked, "")
+	return nil
+}
+
+func (s *NotificationService) MarkAsRead(ctx context.Context, id string, userID string) error {
+	ctx, span := tracer.Start(ctx, "NotificationService.MarkAsRead")
+	defer span.End()
+
+	log := s.logger.WithFields(map[string]interface{}{
+		"notificationID": id,
+		"userID":         userID,
+	})
+
+	notification, err := s.repository.FindByID(ctx, id)
+	if err != nil {
+		log.WithError(err).Error("Ошибка поиска уведомления")
+		recordSpanError(span, err)
 		return err
 	}
 
 	if notification.UserID != userID {
-		ctx.Error("Попытка отметить чужое уведомление как прочитанное")
+		log.Error("Попытка отметить чужое уведомление как прочитанное")
+		recordSpanError(span, domain.ErrUnauthorized)
 		return domain.ErrUnauthorized
 	}
 
 	notification.IsRead = true
-	err = s.repository.Update(notification)
+	err = s.repository.Update(ctx, notification)
 	if err != nil {
-		ctx.WithError(err).Error("Ошибка обновления статуса уведомления")
+		log.WithError(err).Error("Ошибка обновления статуса уведомления")
+		recordSpanError(span, err)
 		return err
 	}
 
-	ctx.Info("Уведомление отмечено как прочитанное")
+	s.tracker.Track(id, userID, domain.StageRead, "")
+
+	log.Info("Уведомление отмечено как прочитанное")
 	return nil
 }
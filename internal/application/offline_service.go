@@ -0,0 +1,115 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+	"github.com/anatoly_dev/go-ws-notifications/pkg/logger"
+)
+
+// OfflineService сохраняет уведомления, не поместившиеся в буфер медленного
+// клиента, и доставляет их, когда буфер освободится, или при следующем
+// подключении пользователя.
+type OfflineService struct {
+	queue      domain.OfflineQueue
+	repository domain.NotificationRepository
+	wsService  domain.WebSocketService
+	logger     *logger.Logger
+}
+
+func NewOfflineService(
+	queue domain.OfflineQueue,
+	repository domain.NotificationRepository,
+	wsService domain.WebSocketService,
+	logger *logger.Logger,
+) *OfflineService {
+	return &OfflineService{
+		queue:      queue,
+		repository: repository,
+		wsService:  wsService,
+		logger:     logger.WithField("source", "offline_service"),
+	}
+}
+
+func (s *OfflineService) Spill(notification *domain.Notification) {
+	log := s.logger.WithFields(map[string]interface{}{
+		"notificationID": notification.ID,
+		"userID":         notification.UserID,
+	})
+
+	if err := s.queue.Push(context.Background(), notification); err != nil {
+		log.WithError(err).Error("Ошибка сохранения уведомления в офлайн-очередь")
+		return
+	}
+
+	log.Debug("Уведомление перенесено в офлайн-очередь")
+}
+
+// Redeliver отправляет пользователю накопленные уведомления, кроме истёкших
+// и уже прочитанных или удалённых из хранилища. Вызывается при подключении
+// и когда буфер клиента после переполнения освободился.
+func (s *OfflineService) Redeliver(ctx context.Context, userID string, attributes domain.ClientAttributes) {
+	log := s.logger.WithField("userID", userID)
+
+	notifications, err := s.queue.PopAll(ctx, userID)
+	if err != nil {
+		log.WithError(err).Error("Ошибка чтения офлайн-очереди")
+		return
+	}
+
+	now := time.Now()
+	sent := 0
+	for i, notification := range notifications {
+		if notification.IsExpired(now) || !s.stillRelevant(ctx, notification) {
+			continue
+		}
+
+		message, err := json.Marshal(notification)
+		if err != nil {
+			log.WithError(err).Error("Ошибка сериализации уведомления из офлайн-очереди")
+			continue
+		}
+
+		if err := s.wsService.SendNotification(ctx, notification, message); err != nil {
+			// Пользователь успел отключиться - оставшееся дождётся следующего подключения.
+			// Перенесённое соединением в очередь уведомление уже в ней.
+			rest := notifications[i:]
+			if errors.Is(err, domain.ErrMessageSpilled) {
+				rest = rest[1:]
+			}
+			s.requeue(ctx, rest)
+			break
+		}
+		sent++
+	}
+
+	if sent > 0 {
+		log.WithField("count", sent).Info("Доставлены уведомления из офлайн-очереди")
+	}
+}
+
+// stillRelevant проверяет по хранилищу, что уведомление не прочитано и не
+// удалено.
+func (s *OfflineService) stillRelevant(ctx context.Context, notification *domain.Notification) bool {
+	stored, err := s.repository.FindByID(ctx, notification.ID)
+	if errors.Is(err, domain.ErrNotFound) {
+		return false
+	}
+	if err != nil {
+		// При недоступном хранилище лучше отправить повторно, чем потерять
+		return true
+	}
+
+	return !stored.IsRead
+}
+
+func (s *OfflineService) requeue(ctx context.Context, notifications []*domain.Notification) {
+	for _, notification := range notifications {
+		if err := s.queue.Push(ctx, notification); err != nil {
+			s.logger.WithError(err).WithField("notificationID", notification.ID).Error("Ошибка возврата уведомления в офлайн-очередь")
+		}
+	}
+}
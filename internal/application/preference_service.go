@@ -0,0 +1,277 @@
+package application
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+	"github.com/anatoly_dev/go-ws-notifications/pkg/logger"
+)
+
+const (
+	emailCodeTTL         = 15 * time.Minute
+	emailCodeCooldown    = time.Minute
+	emailCodeMaxAttempts = 5
+)
+
+// PreferenceService сериализует изменения настроек, чтобы одновременные
+// обновления через REST и WebSocket не перезаписывали друг друга. Адрес
+// канала email принимается только после подтверждения кодом, отправленным
+// на этот адрес, иначе сервис можно было бы использовать для рассылки
+// на чужие адреса.
+type PreferenceService struct {
+	store  domain.PreferenceStore
+	sender domain.EmailSender
+	logger *logger.Logger
+	mutex  sync.Mutex
+}
+
+// NewPreferenceService создаёт сервис; при sender == nil адрес email задать нельзя.
+func NewPreferenceService(store domain.PreferenceStore, sender domain.EmailSender, logger *logger.Logger) *PreferenceService {
+	return &PreferenceService{
+		store:  store,
+		sender: sender,
+		logger: logger,
+	}
+}
+
+func (s *PreferenceService) Get(ctx context.Context, userID string) (*domain.Preferences, error) {
+	preferences, err := s.store.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return preferences.Public(), nil
+}
+
+func (s *PreferenceService) Replace(ctx context.Context, preferences *domain.Preferences) error {
+	for notificationType := range preferences.Types {
+		if !domain.IsKnownType(notificationType) {
+			return fmt.Errorf("%w: неизвестный тип уведомления %s", domain.ErrInvalidInput, notificationType)
+		}
+	}
+
+	if preferences.QuietHours != nil {
+		if err := preferences.QuietHours.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if preferences.Types == nil {
+		preferences.Types = make(map[domain.NotificationType]bool)
+	}
+	if preferences.Categories == nil {
+		preferences.Categories = make(map[string]bool)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// Адрес email меняется только через SetEmail и VerifyEmail
+	stored, err := s.store.Get(ctx, preferences.UserID)
+	if err != nil {
+		return err
+	}
+	preferences.Email = stored.Email
+	preferences.PendingEmail = stored.PendingEmail
+
+	if err := s.save(ctx, preferences); err != nil {
+		return err
+	}
+
+	*preferences = *preferences.Public()
+	return nil
+}
+
+func (s *PreferenceService) SetType(ctx context.Context, userID string, notificationType domain.NotificationType, enabled bool) (*domain.Preferences, error) {
+	if !domain.IsKnownType(notificationType) {
+		return nil, fmt.Errorf("%w: неизвестный тип уведомления %s", domain.ErrInvalidInput, notificationType)
+	}
+
+	return s.update(ctx, userID, func(preferences *domain.Preferences) error {
+		preferences.Types[notificationType] = enabled
+		return nil
+	})
+}
+
+func (s *PreferenceService) SetCategory(ctx context.Context, userID string, category string, enabled bool) (*domain.Preferences, error) {
+	if category == "" {
+		return nil, fmt.Errorf("%w: не указана категория", domain.ErrInvalidInput)
+	}
+
+	return s.update(ctx, userID, func(preferences *domain.Preferences) error {
+		preferences.Categories[category] = enabled
+		return nil
+	})
+}
+
+func (s *PreferenceService) SetQuietHours(ctx context.Context, userID string, quietHours *domain.QuietHours) (*domain.Preferences, error) {
+	if quietHours != nil {
+		if err := quietHours.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.update(ctx, userID, func(preferences *domain.Preferences) error {
+		preferences.QuietHours = quietHours
+		return nil
+	})
+}
+
+func (s *PreferenceService) SetEmail(ctx context.Context, userID string, email string) (*domain.Preferences, error) {
+	if email == "" {
+		return s.update(ctx, userID, func(preferences *domain.Preferences) error {
+			preferences.Email = ""
+			preferences.PendingEmail = nil
+			return nil
+		})
+	}
+
+	if err := domain.ValidateEmail(email); err != nil {
+		return nil, err
+	}
+
+	if s.sender == nil {
+		return nil, fmt.Errorf("%w: доставка по почте выключена", domain.ErrInvalidInput)
+	}
+
+	code, err := emailCode()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка генерации кода подтверждения: %w", err)
+	}
+
+	now := time.Now()
+	verification := &domain.EmailVerification{
+		Address:   email,
+		CodeHash:  emailCodeHash(email, code),
+		SentAt:    now,
+		ExpiresAt: now.Add(emailCodeTTL),
+	}
+
+	preferences, err := s.update(ctx, userID, func(preferences *domain.Preferences) error {
+		if pending := preferences.PendingEmail; pending != nil && now.Before(pending.SentAt.Add(emailCodeCooldown)) {
+			return fmt.Errorf("%w: код подтверждения уже отправлен, повторите позже", domain.ErrInvalidInput)
+		}
+
+		preferences.PendingEmail = verification
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Письмо отправляется без блокировки, чтобы медленный SMTP-сервер не задерживал другие изменения настроек
+	err = s.sender.Send(ctx, &domain.EmailMessage{
+		To:      email,
+		Subject: "Код подтверждения адреса",
+		Body:    fmt.Sprintf("Код подтверждения адреса для уведомлений: %s\nКод действует %d минут.", code, int(emailCodeTTL.Minutes())),
+	})
+	if err != nil {
+		s.logger.WithError(err).WithField("userID", userID).Error("Ошибка отправки кода подтверждения адреса")
+
+		// Неотправленный код не должен мешать повторной попытке
+		_, _ = s.update(ctx, userID, func(preferences *domain.Preferences) error {
+			if preferences.PendingEmail != nil && preferences.PendingEmail.CodeHash == verification.CodeHash {
+				preferences.PendingEmail = nil
+			}
+			return nil
+		})
+		return nil, err
+	}
+
+	s.logger.WithField("userID", userID).Info("Отправлен код подтверждения адреса")
+	return preferences, nil
+}
+
+// VerifyEmail подтверждает ожидающий адрес кодом из письма. После
+// emailCodeMaxAttempts неверных кодов адрес нужно задать заново.
+func (s *PreferenceService) VerifyEmail(ctx context.Context, userID string, code string) (*domain.Preferences, error) {
+	var verifyErr error
+
+	preferences, err := s.update(ctx, userID, func(preferences *domain.Preferences) error {
+		pending := preferences.PendingEmail
+		if pending == nil || !time.Now().Before(pending.ExpiresAt) {
+			preferences.PendingEmail = nil
+			verifyErr = fmt.Errorf("%w: нет адреса, ожидающего подтверждения", domain.ErrInvalidInput)
+			return nil
+		}
+
+		hash := emailCodeHash(pending.Address, code)
+		if subtle.ConstantTimeCompare([]byte(hash), []byte(pending.CodeHash)) != 1 {
+			pending.Attempts++
+			if pending.Attempts >= emailCodeMaxAttempts {
+				preferences.PendingEmail = nil
+			}
+			// Неудачная попытка сохраняется, чтобы счётчик нельзя было обойти
+			verifyErr = fmt.Errorf("%w: неверный код подтверждения", domain.ErrInvalidInput)
+			return nil
+		}
+
+		preferences.Email = pending.Address
+		preferences.PendingEmail = nil
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if verifyErr != nil {
+		return nil, verifyErr
+	}
+
+	s.logger.WithField("userID", userID).Info("Адрес электронной почты подтверждён")
+	return preferences, nil
+}
+
+func (s *PreferenceService) update(ctx context.Context, userID string, change func(*domain.Preferences) error) (*domain.Preferences, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	preferences, err := s.store.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := change(preferences); err != nil {
+		return nil, err
+	}
+
+	if err := s.save(ctx, preferences); err != nil {
+		return nil, err
+	}
+
+	return preferences.Public(), nil
+}
+
+func (s *PreferenceService) save(ctx context.Context, preferences *domain.Preferences) error {
+	preferences.UpdatedAt = time.Now()
+
+	if err := s.store.Save(ctx, preferences); err != nil {
+		s.logger.WithError(err).WithField("userID", preferences.UserID).Error("Ошибка сохранения настроек пользователя")
+		return err
+	}
+
+	s.logger.WithField("userID", preferences.UserID).Info("Настройки уведомлений пользователя обновлены")
+	return nil
+}
+
+// emailCode возвращает шестизначный код подтверждения.
+func emailCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+func emailCodeHash(address string, code string) string {
+	sum := sha256.Sum256([]byte(address + ":" + code))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,322 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+	"github.com/anatoly_dev/go-ws-notifications/internal/infrastructure/repository"
+	"github.com/anatoly_dev/go-ws-notifications/pkg/logger"
+)
+
+// fakeEmailSender запоминает отправленные письма.
+type fakeEmailSender struct {
+	err      error
+	mutex    sync.Mutex
+	messages []*domain.EmailMessage
+}
+
+func (s *fakeEmailSender) Send(ctx context.Context, message *domain.EmailMessage) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.messages = append(s.messages, message)
+	return s.err
+}
+
+var emailCodePattern = regexp.MustCompile(`\b\d{6}\b`)
+
+// lastCode возвращает код из последнего письма.
+func (s *fakeEmailSender) lastCode(t *testing.T) string {
+	t.Helper()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if len(s.messages) == 0 {
+		t.Fatal("письмо с кодом не отправлено")
+	}
+	code := emailCodePattern.FindString(s.messages[len(s.messages)-1].Body)
+	if code == "" {
+		t.Fatalf("в письме нет кода: %q", s.messages[len(s.messages)-1].Body)
+	}
+
+	return code
+}
+
+func newTestPreferenceService(t *testing.T, sender domain.EmailSender) (*PreferenceService, *repository.MemoryPreferenceStore) {
+	t.Helper()
+
+	log, err := logger.NewLogger("error", false)
+	if err != nil {
+		t.Fatalf("logger.NewLogger: %v", err)
+	}
+
+	store := repository.NewMemoryPreferenceStore()
+	return NewPreferenceService(store, sender, log), store
+}
+
+// wrongCode возвращает код, заведомо отличный от code.
+func wrongCode(code string) string {
+	if code == "000000" {
+		return "111111"
+	}
+	return "000000"
+}
+
+func TestPreferenceServiceVerifyEmail(t *testing.T) {
+	ctx := context.Background()
+	const userID = "user-1"
+	const address = "user@example.com"
+
+	tests := []struct {
+		name      string
+		attempts  func(code string) []string
+		wantEmail string
+		wantErr   bool
+	}{
+		{
+			name:      "correct code",
+			attempts:  func(code string) []string { return []string{code} },
+			wantEmail: address,
+		},
+		{
+			name:      "wrong then correct code",
+			attempts:  func(code string) []string { return []string{wrongCode(code), code} },
+			wantEmail: address,
+		},
+		{
+			name:     "wrong code",
+			attempts: func(code string) []string { return []string{wrongCode(code)} },
+			wantErr:  true,
+		},
+		{
+			name: "attempts exhausted",
+			attempts: func(code string) []string {
+				attempts := make([]string, 0, emailCodeMaxAttempts+1)
+				for i := 0; i < emailCodeMaxAttempts; i++ {
+					attempts = append(attempts, wrongCode(code))
+				}
+				return append(attempts, code)
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := &fakeEmailSender{}
+			service, store := newTestPreferenceService(t, sender)
+
+			preferences, err := service.SetEmail(ctx, userID, address)
+			if err != nil {
+				t.Fatalf("SetEmail: %v", err)
+			}
+			if preferences.Email != "" {
+				t.Errorf("Email = %q до подтверждения", preferences.Email)
+			}
+			if preferences.PendingEmail == nil || preferences.PendingEmail.Address != address {
+				t.Fatalf("PendingEmail = %+v, want %s", preferences.PendingEmail, address)
+			}
+			if sender.messages[0].To != address {
+				t.Errorf("письмо отправлено на %q, want %q", sender.messages[0].To, address)
+			}
+
+			code := sender.lastCode(t)
+			attempts := tt.attempts(code)
+			for i, attempt := range attempts {
+				preferences, err = service.VerifyEmail(ctx, userID, attempt)
+				if i < len(attempts)-1 && err == nil {
+					t.Fatalf("попытка %d с кодом %q принята", i, attempt)
+				}
+			}
+
+			if tt.wantErr {
+				if !errors.Is(err, domain.ErrInvalidInput) {
+					t.Fatalf("VerifyEmail = %v, want ErrInvalidInput", err)
+				}
+			} else if err != nil {
+				t.Fatalf("VerifyEmail: %v", err)
+			}
+
+			stored, err := store.Get(ctx, userID)
+			if err != nil {
+				t.Fatalf("store.Get: %v", err)
+			}
+			if stored.Email != tt.wantEmail {
+				t.Errorf("Email = %q, want %q", stored.Email, tt.wantEmail)
+			}
+		})
+	}
+}
+
+func TestPreferenceServiceSetEmail(t *testing.T) {
+	ctx := context.Background()
+	const userID = "user-1"
+
+	t.Run("email disabled", func(t *testing.T) {
+		service, _ := newTestPreferenceService(t, nil)
+
+		if _, err := service.SetEmail(ctx, userID, "user@example.com"); !errors.Is(err, domain.ErrInvalidInput) {
+			t.Fatalf("SetEmail = %v, want ErrInvalidInput", err)
+		}
+	})
+
+	t.Run("invalid address", func(t *testing.T) {
+		sender := &fakeEmailSender{}
+		service, _ := newTestPreferenceService(t, sender)
+
+		if _, err := service.SetEmail(ctx, userID, "not an address"); !errors.Is(err, domain.ErrInvalidInput) {
+			t.Fatalf("SetEmail = %v, want ErrInvalidInput", err)
+		}
+		if len(sender.messages) != 0 {
+			t.Errorf("отправлено %d писем", len(sender.messages))
+		}
+	})
+
+	t.Run("cooldown", func(t *testing.T) {
+		sender := &fakeEmailSender{}
+		service, _ := newTestPreferenceService(t, sender)
+
+		if _, err := service.SetEmail(ctx, userID, "user@example.com"); err != nil {
+			t.Fatalf("SetEmail: %v", err)
+		}
+		if _, err := service.SetEmail(ctx, userID, "other@example.com"); !errors.Is(err, domain.ErrInvalidInput) {
+			t.Fatalf("повторный SetEmail = %v, want ErrInvalidInput", err)
+		}
+		if len(sender.messages) != 1 {
+			t.Errorf("отправлено %d писем, want 1", len(sender.messages))
+		}
+	})
+
+	t.Run("send failure", func(t *testing.T) {
+		sender := &fakeEmailSender{err: errors.New("smtp down")}
+		service, store := newTestPreferenceService(t, sender)
+
+		if _, err := service.SetEmail(ctx, userID, "user@example.com"); err == nil {
+			t.Fatal("SetEmail без ошибки при сбое отправки")
+		}
+
+		stored, err := store.Get(ctx, userID)
+		if err != nil {
+			t.Fatalf("store.Get: %v", err)
+		}
+		if stored.PendingEmail != nil {
+			t.Errorf("PendingEmail = %+v после сбоя отправки", stored.PendingEmail)
+		}
+	})
+
+	t.Run("expired code", func(t *testing.T) {
+		sender := &fakeEmailSender{}
+		service, store := newTestPreferenceService(t, sender)
+
+		if _, err := service.SetEmail(ctx, userID, "user@example.com"); err != nil {
+			t.Fatalf("SetEmail: %v", err)
+		}
+
+		stored, err := store.Get(ctx, userID)
+		if err != nil {
+			t.Fatalf("store.Get: %v", err)
+		}
+		stored.PendingEmail.ExpiresAt = time.Now().Add(-time.Second)
+		if err := store.Save(ctx, stored); err != nil {
+			t.Fatalf("store.Save: %v", err)
+		}
+
+		if _, err := service.VerifyEmail(ctx, userID, sender.lastCode(t)); !errors.Is(err, domain.ErrInvalidInput) {
+			t.Fatalf("VerifyEmail = %v, want ErrInvalidInput", err)
+		}
+	})
+
+	t.Run("clear", func(t *testing.T) {
+		sender := &fakeEmailSender{}
+		service, _ := newTestPreferenceService(t, sender)
+
+		if _, err := service.SetEmail(ctx, userID, "user@example.com"); err != nil {
+			t.Fatalf("SetEmail: %v", err)
+		}
+		if _, err := service.VerifyEmail(ctx, userID, sender.lastCode(t)); err != nil {
+			t.Fatalf("VerifyEmail: %v", err)
+		}
+
+		preferences, err := service.SetEmail(ctx, userID, "")
+		if err != nil {
+			t.Fatalf("SetEmail(\"\"): %v", err)
+		}
+		if preferences.Email != "" || preferences.PendingEmail != nil {
+			t.Errorf("адрес не очищен: %+v", preferences)
+		}
+	})
+}
+
+func TestPreferenceServiceReplaceKeepsEmail(t *testing.T) {
+	ctx := context.Background()
+	const userID = "user-1"
+
+	sender := &fakeEmailSender{}
+	service, store := newTestPreferenceService(t, sender)
+
+	if _, err := service.SetEmail(ctx, userID, "user@example.com"); err != nil {
+		t.Fatalf("SetEmail: %v", err)
+	}
+	if _, err := service.VerifyEmail(ctx, userID, sender.lastCode(t)); err != nil {
+		t.Fatalf("VerifyEmail: %v", err)
+	}
+
+	preferences := &domain.Preferences{
+		UserID: userID,
+		Email:  "victim@example.com",
+		PendingEmail: &domain.EmailVerification{
+			Address:   "victim@example.com",
+			ExpiresAt: time.Now().Add(time.Hour),
+		},
+	}
+	if err := service.Replace(ctx, preferences); err != nil {
+		t.Fatalf("Replace: %v", err)
+	}
+
+	stored, err := store.Get(ctx, userID)
+	if err != nil {
+		t.Fatalf("store.Get: %v", err)
+	}
+	if stored.Email != "user@example.com" {
+		t.Errorf("Email = %q, want подтверждённый адрес", stored.Email)
+	}
+	if stored.PendingEmail != nil {
+		t.Errorf("PendingEmail = %+v, want nil", stored.PendingEmail)
+	}
+}
+
+func TestPreferenceServicePublicHidesCode(t *testing.T) {
+	ctx := context.Background()
+	const userID = "user-1"
+
+	sender := &fakeEmailSender{}
+	service, _ := newTestPreferenceService(t, sender)
+
+	if _, err := service.SetEmail(ctx, userID, "user@example.com"); err != nil {
+		t.Fatalf("SetEmail: %v", err)
+	}
+
+	preferences, err := service.Get(ctx, userID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+
+	data, err := json.Marshal(preferences)
+	if err != nil {
+		t.Fatalf("json.Marshal: %v", err)
+	}
+	if strings.Contains(string(data), "code_hash") || strings.Contains(string(data), "attempts") {
+		t.Errorf("ответ раскрывает код: %s", data)
+	}
+	if preferences.PendingEmail == nil || preferences.PendingEmail.Address != "user@example.com" {
+		t.Errorf("PendingEmail = %+v", preferences.PendingEmail)
+	}
+}
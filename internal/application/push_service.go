@@ -0,0 +1,248 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"time"
+	"unicode/utf8"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+	"github.com/anatoly_dev/go-ws-notifications/pkg/logger"
+)
+
+const (
+	// Ограничения полей, чтобы сообщение поместилось в одну запись Web Push
+	maxPushTitleBytes   = 256
+	maxPushContentBytes = 2048
+)
+
+// Topic push-сервиса - до 32 символов base64url (RFC 8030)
+var pushTopicPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+// pushPayload - содержимое push-сообщения, которое получает service worker.
+type pushPayload struct {
+	ID        string                  `json:"id"`
+	Type      domain.NotificationType `json:"type"`
+	Category  string                  `json:"category,omitempty"`
+	Title     string                  `json:"title"`
+	Content   string                  `json:"content"`
+	Priority  int                     `json:"priority"`
+	CreatedAt time.Time               `json:"created_at"`
+}
+
+// PushService хранит подписки браузеров на Web Push и доставляет через них
+// важные уведомления как канал webpush.
+type PushService struct {
+	store       domain.PushSubscriptionStore
+	sender      domain.PushSender
+	publicKey   string
+	minPriority int
+	ttl         time.Duration
+	tracker     domain.DeliveryTracker
+	metrics     domain.Metrics
+	logger      *logger.Logger
+}
+
+// NewPushService создаёт сервис; при sender == nil Web Push выключен
+// и подписки не принимаются.
+func NewPushService(
+	store domain.PushSubscriptionStore,
+	sender domain.PushSender,
+	publicKey string,
+	minPriority int,
+	ttl time.Duration,
+	tracker domain.DeliveryTracker,
+	metrics domain.Metrics,
+	logger *logger.Logger,
+) *PushService {
+	return &PushService{
+		store:       store,
+		sender:      sender,
+		publicKey:   publicKey,
+		minPriority: minPriority,
+		ttl:         ttl,
+		tracker:     tracker,
+		metrics:     metrics,
+		logger:      logger.WithField("source", "push_service"),
+	}
+}
+
+func (s *PushService) Subscribe(ctx context.Context, subscription *domain.PushSubscription) error {
+	if s.sender == nil {
+		return fmt.Errorf("%w: Web Push выключен", domain.ErrInvalidInput)
+	}
+
+	if err := subscription.Validate(); err != nil {
+		return err
+	}
+
+	subscription.CreatedAt = time.Now()
+
+	if err := s.store.Save(ctx, subscription); err != nil {
+		return err
+	}
+
+	s.logger.WithFields(map[string]interface{}{
+		"userID":      subscription.UserID,
+		"pushService": endpointHost(subscription.Endpoint),
+	}).Info("Добавлена push-подписка")
+
+	return nil
+}
+
+func (s *PushService) Unsubscribe(ctx context.Context, userID string, endpoint string) error {
+	return s.store.Delete(ctx, userID, endpoint)
+}
+
+func (s *PushService) List(ctx context.Context, userID string) ([]*domain.PushSubscription, error) {
+	return s.store.FindByUserID(ctx, userID)
+}
+
+func (s *PushService) PublicKey() string {
+	return s.publicKey
+}
+
+func (s *PushService) Name() string {
+	return domain.ChannelWebPush
+}
+
+// Deliver отправляет уведомление на все подписки пользователя, если его
+// приоритет не ниже порога. Подписки, отклонённые push-сервисом как
+// несуществующие, удаляются. Уведомление доставлено, если его принял
+// хотя бы один push-сервис.
+func (s *PushService) Deliver(ctx context.Context, notification *domain.Notification, frame []byte) error {
+	if s.sender == nil || notification.Priority < s.minPriority {
+		return domain.ErrChannelUnavailable
+	}
+
+	log := s.logger.WithFields(map[string]interface{}{
+		"notificationID": notification.ID,
+		"userID":         notification.UserID,
+	})
+
+	subscriptions, err := s.store.FindByUserID(ctx, notification.UserID)
+	if err != nil {
+		log.WithError(err).Error("Ошибка чтения push-подписок")
+		return err
+	}
+
+	if len(subscriptions) == 0 {
+		return domain.ErrChannelUnavailable
+	}
+
+	message, err := s.message(notification)
+	if err != nil {
+		log.WithError(err).Error("Ошибка сериализации push-сообщения")
+		return err
+	}
+
+	sent := 0
+	var sendErr error
+	for _, subscription := range subscriptions {
+		host := endpointHost(subscription.Endpoint)
+
+		err := s.sender.Send(ctx, subscription, message)
+		switch {
+		case errors.Is(err, domain.ErrSubscriptionGone):
+			s.metrics.PushMessage("gone")
+			log.WithField("pushService", host).Info("Push-подписка больше не действует и удалена")
+			if err := s.store.Delete(ctx, subscription.UserID, subscription.Endpoint); err != nil && !errors.Is(err, domain.ErrNotFound) {
+				log.WithError(err).Error("Ошибка удаления push-подписки")
+			}
+		case err != nil:
+			s.metrics.PushMessage("failed")
+			log.WithError(err).WithField("pushService", host).Error("Ошибка отправки Web Push")
+			s.tracker.Track(notification.ID, notification.UserID, domain.StageFailed, "webpush: "+host)
+			sendErr = err
+		default:
+			s.metrics.PushMessage("sent")
+			s.tracker.Track(notification.ID, notification.UserID, domain.StagePushed, host)
+			sent++
+		}
+	}
+
+	switch {
+	case sent > 0:
+		return nil
+	case sendErr != nil:
+		return fmt.Errorf("ошибка отправки Web Push: %w", sendErr)
+	default:
+		return domain.ErrChannelUnavailable
+	}
+}
+
+func (s *PushService) message(notification *domain.Notification) (*domain.PushMessage, error) {
+	payload, err := json.Marshal(pushPayload{
+		ID:        notification.ID,
+		Type:      notification.Type,
+		Category:  notification.Category,
+		Title:     truncateUTF8(notification.Title, maxPushTitleBytes),
+		Content:   truncateUTF8(notification.Content, maxPushContentBytes),
+		Priority:  notification.Priority,
+		CreatedAt: notification.CreatedAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Push-сервис не должен хранить сообщение дольше, чем живёт уведомление
+	ttl := s.ttl
+	if notification.ExpiresAt != nil {
+		if remaining := time.Until(*notification.ExpiresAt); remaining < ttl {
+			ttl = remaining
+		}
+	}
+	if ttl < 0 {
+		ttl = 0
+	}
+
+	message := &domain.PushMessage{
+		Payload: payload,
+		TTL:     ttl,
+		Urgency: pushUrgency(notification.Priority),
+	}
+
+	if pushTopicPattern.MatchString(notification.CollapseKey) {
+		message.Topic = notification.CollapseKey
+	}
+
+	return message, nil
+}
+
+func pushUrgency(priority int) string {
+	switch {
+	case priority >= 4:
+		return "high"
+	case priority >= 2:
+		return "normal"
+	}
+
+	return "low"
+}
+
+func truncateUTF8(value string, limit int) string {
+	if len(value) <= limit {
+		return value
+	}
+
+	value = value[:limit]
+	for !utf8.ValidString(value) {
+		value = value[:len(value)-1]
+	}
+
+	return value
+}
+
+// endpointHost скрывает токен подписки из endpoint в логах.
+func endpointHost(endpoint string) string {
+	parsed, err := url.Parse(endpoint)
+	if err != nil {
+		return ""
+	}
+
+	return parsed.Host
+}
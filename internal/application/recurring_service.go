@@ -0,0 +1,318 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+	"github.com/anatoly_dev/go-ws-notifications/pkg/logger"
+	"github.com/google/uuid"
+	"github.com/robfig/cron/v3"
+)
+
+// RecurringService ведёт периодические объявления и рассылает их по cron-расписанию:
+// всем подключённым пользователям через BroadcastMessage или адресно через Send.
+type RecurringService struct {
+	store           domain.RecurringJobStore
+	notificationSvc domain.NotificationService
+	wsService       domain.WebSocketService
+	interval        time.Duration
+	logger          *logger.Logger
+	mutex           sync.Mutex
+	stop            chan struct{}
+	wg              sync.WaitGroup
+}
+
+func NewRecurringService(
+	store domain.RecurringJobStore,
+	notificationSvc domain.NotificationService,
+	wsService domain.WebSocketService,
+	interval time.Duration,
+	logger *logger.Logger,
+) *RecurringService {
+	return &RecurringService{
+		store:           store,
+		notificationSvc: notificationSvc,
+		wsService:       wsService,
+		interval:        interval,
+		logger:          logger.WithField("source", "recurring_service"),
+		stop:            make(chan struct{}),
+	}
+}
+
+func (s *RecurringService) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				s.RunDue(context.Background(), time.Now())
+			}
+		}
+	}()
+}
+
+func (s *RecurringService) Stop() {
+	close(s.stop)
+	s.wg.Wait()
+}
+
+func (s *RecurringService) Create(ctx context.Context, job *domain.RecurringJob) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := job.Validate(); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	job.ID = uuid.New().String()
+	job.CreatedAt = now
+	job.LastRunAt = nil
+
+	if err := scheduleNextRun(job, now); err != nil {
+		return err
+	}
+
+	if err := s.store.Save(ctx, job); err != nil {
+		return err
+	}
+
+	s.logger.WithFields(map[string]interface{}{
+		"jobID": job.ID,
+		"name":  job.Name,
+		"spec":  job.Spec,
+	}).Info("Создано периодическое объявление")
+
+	return nil
+}
+
+func (s *RecurringService) Update(ctx context.Context, job *domain.RecurringJob) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	existing, err := s.store.FindByID(ctx, job.ID)
+	if err != nil {
+		return err
+	}
+
+	if err := job.Validate(); err != nil {
+		return err
+	}
+
+	job.CreatedAt = existing.CreatedAt
+	job.LastRunAt = existing.LastRunAt
+
+	if err := scheduleNextRun(job, time.Now()); err != nil {
+		return err
+	}
+
+	if err := s.store.Save(ctx, job); err != nil {
+		return err
+	}
+
+	s.logger.WithField("jobID", job.ID).Info("Периодическое объявление обновлено")
+
+	return nil
+}
+
+func (s *RecurringService) Get(ctx context.Context, id string) (*domain.RecurringJob, error) {
+	return s.store.FindByID(ctx, id)
+}
+
+func (s *RecurringService) List(ctx context.Context) ([]*domain.RecurringJob, error) {
+	return s.store.FindAll(ctx)
+}
+
+func (s *RecurringService) Delete(ctx context.Context, id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := s.store.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	s.logger.WithField("jobID", id).Info("Периодическое объявление удалено")
+
+	return nil
+}
+
+// SetPaused приостанавливает или возобновляет задание. После возобновления
+// пропущенные запуски не выполняются, расписание продолжается от текущего момента.
+func (s *RecurringService) SetPaused(ctx context.Context, id string, paused bool) (*domain.RecurringJob, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	job, err := s.store.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	job.Paused = paused
+	if !paused {
+		if err := scheduleNextRun(job, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.store.Save(ctx, job); err != nil {
+		return nil, err
+	}
+
+	s.logger.WithFields(map[string]interface{}{
+		"jobID":  id,
+		"paused": paused,
+	}).Info("Изменено состояние периодического объявления")
+
+	return job, nil
+}
+
+// RunDue запускает задания, время которых наступило. Если сервис был остановлен
+// дольше одного периода, пропущенные запуски объединяются в один.
+// Перед отправкой запуск захватывается в хранилище, поэтому при общей базе
+// задание выполняет только один узел.
+func (s *RecurringService) RunDue(ctx context.Context, now time.Time) {
+	for _, job := range s.claimDue(ctx, now) {
+		s.run(ctx, job, now)
+	}
+}
+
+// claimDue переносит наступившие задания на следующий запуск и возвращает
+// те, которые удалось захватить. Отправка идёт уже без блокировки сервиса.
+func (s *RecurringService) claimDue(ctx context.Context, now time.Time) []*domain.RecurringJob {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	jobs, err := s.store.FindAll(ctx)
+	if err != nil {
+		s.logger.WithError(err).Error("Ошибка загрузки периодических объявлений")
+		return nil
+	}
+
+	claimed := []*domain.RecurringJob{}
+	for _, job := range jobs {
+		if job.Paused || job.NextRunAt == nil || job.NextRunAt.After(now) {
+			continue
+		}
+
+		log := s.logger.WithField("jobID", job.ID)
+
+		next := *job
+		next.LastRunAt = &now
+		if err := scheduleNextRun(&next, now); err != nil {
+			log.WithError(err).Error("Ошибка расчёта следующего запуска")
+			next.NextRunAt = nil
+		}
+
+		advanced, err := s.store.Advance(ctx, &next, job.NextRunAt)
+		if err != nil {
+			log.WithError(err).Error("Ошибка сохранения периодического объявления")
+			continue
+		}
+		if !advanced {
+			log.Debug("Запуск периодического объявления выполнен другим узлом")
+			continue
+		}
+
+		claimed = append(claimed, &next)
+	}
+
+	return claimed
+}
+
+func (s *RecurringService) run(ctx context.Context, job *domain.RecurringJob, now time.Time) {
+	log := s.logger.WithFields(map[string]interface{}{
+		"jobID": job.ID,
+		"name":  job.Name,
+	})
+
+	if len(job.UserIDs) == 0 {
+		notification := newRecurringNotification(job, "", now)
+
+		message, err := json.Marshal(notification)
+		if err != nil {
+			log.WithError(err).Error("Ошибка сериализации периодического объявления")
+			return
+		}
+
+		if err := s.wsService.BroadcastMessage(message); err != nil {
+			log.WithError(err).Error("Ошибка рассылки периодического объявления")
+			return
+		}
+
+		log.Info("Периодическое объявление разослано всем пользователям")
+		return
+	}
+
+	delivered := 0
+	for _, userID := range job.UserIDs {
+		err := s.notificationSvc.Send(ctx, newRecurringNotification(job, userID, now))
+		if err != nil && !isFinalSendError(err) {
+			log.WithError(err).WithField("userID", userID).Error("Ошибка отправки периодического объявления")
+			continue
+		}
+		delivered++
+	}
+
+	log.WithFields(map[string]interface{}{
+		"recipients": len(job.UserIDs),
+		"sent":       delivered,
+	}).Info("Периодическое объявление отправлено")
+}
+
+func newRecurringNotification(job *domain.RecurringJob, userID string, now time.Time) *domain.Notification {
+	notification := &domain.Notification{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		Type:      job.Type,
+		Title:     job.Title,
+		Content:   job.Content,
+		CreatedAt: now,
+		Priority:  job.Priority,
+		TTL:       job.TTL,
+	}
+	notification.ApplyExpiry(now, 0)
+
+	return notification
+}
+
+// scheduleNextRun вычисляет ближайший запуск после after с учётом часового пояса
+// и окна start_at/end_at. NextRunAt = nil означает, что запусков больше не будет.
+func scheduleNextRun(job *domain.RecurringJob, after time.Time) error {
+	schedule, err := cron.ParseStandard(job.Spec)
+	if err != nil {
+		return fmt.Errorf("%w: некорректное cron-выражение %q: %v", domain.ErrInvalidInput, job.Spec, err)
+	}
+
+	location := time.UTC
+	if job.TimeZone != "" {
+		location, err = time.LoadLocation(job.TimeZone)
+		if err != nil {
+			return fmt.Errorf("%w: неизвестный часовой пояс %s", domain.ErrInvalidInput, job.TimeZone)
+		}
+	}
+
+	// Next возвращает время строго после аргумента, поэтому сам start_at тоже допустим
+	if job.StartAt != nil && job.StartAt.After(after) {
+		after = job.StartAt.Add(-time.Second)
+	}
+
+	next := schedule.Next(after.In(location))
+	if next.IsZero() || (job.EndAt != nil && next.After(*job.EndAt)) {
+		job.NextRunAt = nil
+		return nil
+	}
+
+	job.NextRunAt = &next
+	return nil
+}
@@ -0,0 +1,82 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+	"github.com/anatoly_dev/go-ws-notifications/pkg/logger"
+)
+
+// ResumeService восстанавливает поток уведомлений по Last-Event-ID:
+// досылает непрочитанные уведомления, созданные после последнего полученного.
+type ResumeService struct {
+	repository domain.NotificationRepository
+	wsService  domain.WebSocketService
+	logger     *logger.Logger
+}
+
+func NewResumeService(
+	repository domain.NotificationRepository,
+	wsService domain.WebSocketService,
+	logger *logger.Logger,
+) *ResumeService {
+	return &ResumeService{
+		repository: repository,
+		wsService:  wsService,
+		logger:     logger.WithField("source", "resume_service"),
+	}
+}
+
+// Resume возвращает количество досланных уведомлений. Неизвестный или чужой
+// lastEventID считается ошибкой ErrNotFound.
+func (s *ResumeService) Resume(ctx context.Context, userID string, lastEventID string) (int, error) {
+	last, err := s.repository.FindByID(ctx, lastEventID)
+	if err != nil {
+		return 0, err
+	}
+
+	if last.UserID != userID {
+		return 0, domain.ErrNotFound
+	}
+
+	notifications, err := s.repository.FindByUserID(ctx, userID)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка получения уведомлений пользователя: %w", err)
+	}
+
+	sort.SliceStable(notifications, func(i, j int) bool {
+		return notifications[i].CreatedAt.Before(notifications[j].CreatedAt)
+	})
+
+	now := time.Now()
+	sent := 0
+	for _, notification := range notifications {
+		if !notification.CreatedAt.After(last.CreatedAt) || notification.IsRead || notification.IsExpired(now) {
+			continue
+		}
+
+		message, err := json.Marshal(notification)
+		if err != nil {
+			return sent, fmt.Errorf("ошибка сериализации уведомления: %w", err)
+		}
+
+		if err := s.wsService.SendNotification(ctx, notification, message); err != nil {
+			return sent, err
+		}
+		sent++
+	}
+
+	if sent > 0 {
+		s.logger.WithFields(map[string]interface{}{
+			"userID":      userID,
+			"lastEventID": lastEventID,
+			"count":       sent,
+		}).Info("Досланы уведомления после переподключения")
+	}
+
+	return sent, nil
+}
@@ -0,0 +1,125 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+	"github.com/anatoly_dev/go-ws-notifications/pkg/logger"
+)
+
+const (
+	schedulerBatchSize = 500
+	// schedulerLease - время, на которое узел захватывает пачку; за него
+	// пачка должна быть отправлена, иначе её повторно заберёт другой узел
+	schedulerLease = 10 * time.Minute
+)
+
+// Scheduler периодически забирает из хранилища наступившие отложенные
+// уведомления и отправляет их через NotificationService.Send, а резервные
+// шаги цепочек доставки выполняет через DeliverFallback.
+type Scheduler struct {
+	store    domain.ScheduleStore
+	service  domain.NotificationService
+	interval time.Duration
+	logger   *logger.Logger
+	stop     chan struct{}
+	wg       sync.WaitGroup
+}
+
+func NewScheduler(
+	store domain.ScheduleStore,
+	service domain.NotificationService,
+	interval time.Duration,
+	logger *logger.Logger,
+) *Scheduler {
+	return &Scheduler{
+		store:    store,
+		service:  service,
+		interval: interval,
+		logger:   logger.WithField("source", "scheduler"),
+		stop:     make(chan struct{}),
+	}
+}
+
+func (s *Scheduler) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				s.DispatchDue(context.Background(), time.Now())
+			}
+		}
+	}()
+}
+
+func (s *Scheduler) Stop() {
+	close(s.stop)
+	s.wg.Wait()
+}
+
+func (s *Scheduler) DispatchDue(ctx context.Context, now time.Time) {
+	for {
+		due, err := s.store.Claim(ctx, now, schedulerLease, schedulerBatchSize)
+		if err != nil {
+			s.logger.WithError(err).Error("Ошибка поиска отложенных уведомлений")
+			return
+		}
+
+		// Ошибка одного элемента не задерживает остальные: он останется
+		// захваченным и будет отправлен повторно после истечения захвата
+		for _, item := range due {
+			s.dispatch(ctx, item)
+		}
+
+		if len(due) < schedulerBatchSize {
+			return
+		}
+	}
+}
+
+// dispatch отправляет уведомление и удаляет его из расписания. При ошибке
+// хранилища элемент остаётся в расписании и будет отправлен повторно
+// после истечения захвата.
+func (s *Scheduler) dispatch(ctx context.Context, item *domain.ScheduledNotification) {
+	log := s.logger.WithFields(map[string]interface{}{
+		"notificationID": item.ID,
+		"userID":         item.UserID,
+	})
+
+	var err error
+	if item.FallbackStep > 0 {
+		err = s.service.DeliverFallback(ctx, item)
+	} else {
+		// TTL отсчитывается от фактической отправки
+		err = s.service.SendScheduled(ctx, item.Notification, time.Now())
+	}
+	if err != nil && !isFinalSendError(err) {
+		log.WithError(err).Error("Ошибка отправки отложенного уведомления")
+		return
+	}
+
+	if err := s.store.Remove(ctx, item.ID); err != nil && !errors.Is(err, domain.ErrNotFound) {
+		log.WithError(err).Error("Ошибка удаления отправленного отложенного уведомления")
+		return
+	}
+
+	log.Debug("Отложенное уведомление отправлено")
+}
+
+// isFinalSendError сообщает, что повторная отправка не изменит результат:
+// уведомление сохранено, но пользователь не подключён и других каналов
+// нет, оно истекло или заглушено.
+func isFinalSendError(err error) bool {
+	return isUndeliverable(err) || errors.Is(err, domain.ErrExpired) || errors.Is(err, domain.ErrMuted)
+}
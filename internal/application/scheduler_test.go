@@ -0,0 +1,136 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+	"github.com/anatoly_dev/go-ws-notifications/internal/infrastructure/repository"
+	"github.com/anatoly_dev/go-ws-notifications/pkg/logger"
+)
+
+// fakeNotificationService отвечает заданной ошибкой для отдельных уведомлений
+// и запоминает отправленные.
+type fakeNotificationService struct {
+	domain.NotificationService
+	errs  map[string]error
+	mutex sync.Mutex
+	sent  []string
+}
+
+func (s *fakeNotificationService) SendScheduled(ctx context.Context, notification *domain.Notification, sentAt time.Time) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.sent = append(s.sent, notification.ID)
+	return s.errs[notification.ID]
+}
+
+func TestSchedulerDispatchDue(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name string
+		errs map[string]error
+		// wantLeft - элементы, оставшиеся в расписании
+		wantLeft []string
+	}{
+		{
+			name: "all sent",
+		},
+		{
+			name:     "error does not stop batch",
+			errs:     map[string]error{"n2": errors.New("storage unavailable")},
+			wantLeft: []string{"n2"},
+		},
+		{
+			name: "final error removes item",
+			errs: map[string]error{"n2": domain.ErrUserNotConnected, "n3": domain.ErrExpired},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log, err := logger.NewLogger("error", false)
+			if err != nil {
+				t.Fatalf("logger.NewLogger: %v", err)
+			}
+
+			store := repository.NewMemoryScheduleStore()
+			for i, id := range []string{"n1", "n2", "n3"} {
+				err := store.Add(context.Background(), &domain.ScheduledNotification{
+					ID:           id,
+					UserID:       "user1",
+					SendAt:       now.Add(time.Duration(i-3) * time.Minute),
+					Notification: &domain.Notification{ID: id, UserID: "user1", Type: domain.TypeMessage},
+					CreatedAt:    now.Add(-time.Hour),
+				})
+				if err != nil {
+					t.Fatalf("Add: %v", err)
+				}
+			}
+
+			service := &fakeNotificationService{errs: tt.errs}
+			NewScheduler(store, service, time.Minute, log).DispatchDue(context.Background(), now)
+
+			if len(service.sent) != 3 {
+				t.Errorf("отправлены %v, want [n1 n2 n3]", service.sent)
+			}
+
+			left := map[string]bool{}
+			for _, id := range tt.wantLeft {
+				left[id] = true
+			}
+			for _, id := range []string{"n1", "n2", "n3"} {
+				_, err := store.FindByID(context.Background(), id)
+				if found := err == nil; found != left[id] {
+					t.Errorf("%s в расписании = %v, want %v", id, found, left[id])
+				}
+			}
+		})
+	}
+}
+
+func TestNotificationServiceSendScheduled(t *testing.T) {
+	realtime := &fakeChannel{name: domain.ChannelRealtime}
+	fixture := newChainFixture(t, []domain.DeliveryStep{{Channel: domain.ChannelRealtime}}, realtime)
+
+	createdAt := time.Now().Add(-2 * time.Hour).Truncate(time.Second)
+	sentAt := time.Now().Truncate(time.Second)
+	sendAt := sentAt
+	notification := &domain.Notification{
+		ID:        "n1",
+		UserID:    "user1",
+		Type:      domain.TypeMessage,
+		Title:     "title",
+		Content:   "content",
+		CreatedAt: createdAt,
+		TTL:       3600,
+		SendAt:    &sendAt,
+	}
+
+	// TTL, отсчитанный от создания, истёк бы час назад
+	if err := fixture.service.SendScheduled(context.Background(), notification, sentAt); err != nil {
+		t.Fatalf("SendScheduled: %v", err)
+	}
+
+	saved, err := fixture.repository.FindByID(context.Background(), notification.ID)
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	if !saved.CreatedAt.Equal(createdAt) {
+		t.Errorf("CreatedAt = %v, want %v", saved.CreatedAt, createdAt)
+	}
+	if saved.ExpiresAt == nil || !saved.ExpiresAt.Equal(sentAt.Add(time.Hour)) {
+		t.Errorf("ExpiresAt = %v, want %v", saved.ExpiresAt, sentAt.Add(time.Hour))
+	}
+	if saved.SendAt != nil {
+		t.Errorf("SendAt = %v, want nil", saved.SendAt)
+	}
+	if got := realtime.Calls(); got != 1 {
+		t.Errorf("realtime вызван %d раз, want 1", got)
+	}
+}
@@ -0,0 +1,57 @@
+package application
+
+import "time"
+
+// circuitBreaker размыкается после threshold неудачных попыток подряд и
+// через cooldown пропускает одну пробную попытку: успех замыкает его,
+// неудача размыкает снова. Пока проба не завершилась, остальные попытки
+// откладываются на retry.
+type circuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	retry     time.Duration
+	failures  int
+	openUntil time.Time
+	// probeUntil - пропущенная проба; если её результат не пришёл к этому
+	// времени, пропускается новая
+	probeUntil time.Time
+}
+
+// allow возвращает false и время, до которого попытки откладываются, если
+// цепь разомкнута или пробная попытка уже выполняется.
+func (b *circuitBreaker) allow(now time.Time) (bool, time.Time) {
+	if b.failures < b.threshold {
+		return true, time.Time{}
+	}
+
+	if now.Before(b.openUntil) {
+		return false, b.openUntil
+	}
+
+	if now.Before(b.probeUntil) {
+		return false, now.Add(b.retry)
+	}
+
+	b.probeUntil = now.Add(b.cooldown)
+	return true, time.Time{}
+}
+
+func (b *circuitBreaker) success() {
+	b.failures = 0
+	b.openUntil = time.Time{}
+	b.probeUntil = time.Time{}
+}
+
+// failure возвращает true, только если неудача разомкнула замкнутую цепь:
+// неудачная проба и попытки, завершившиеся уже после размыкания, только
+// продлевают cooldown.
+func (b *circuitBreaker) failure(now time.Time) bool {
+	b.failures++
+	b.probeUntil = time.Time{}
+	if b.failures < b.threshold {
+		return false
+	}
+
+	b.openUntil = now.Add(b.cooldown)
+	return b.failures == b.threshold
+}
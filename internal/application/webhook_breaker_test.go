@@ -0,0 +1,187 @@
+package application
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	cooldown := time.Minute
+	retry := 5 * time.Second
+
+	type step struct {
+		at      time.Duration // от start
+		action  string        // allow, success, failure
+		allowed bool
+		retryAt time.Duration // для запрещённого allow
+		opened  bool          // для failure
+	}
+
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "closed below threshold",
+			steps: []step{
+				{action: "failure"},
+				{action: "failure"},
+				{action: "allow", allowed: true},
+			},
+		},
+		{
+			name: "opens at threshold",
+			steps: []step{
+				{action: "failure"},
+				{action: "failure"},
+				{action: "failure", opened: true},
+				{at: 30 * time.Second, action: "allow", retryAt: cooldown},
+			},
+		},
+		{
+			name: "failures after opening do not reopen",
+			steps: []step{
+				{action: "failure"},
+				{action: "failure"},
+				{action: "failure", opened: true},
+				// Попытки, начатые до размыкания, завершаются позже
+				{at: time.Second, action: "failure"},
+				{at: 2 * time.Second, action: "failure"},
+				{at: 3 * time.Second, action: "allow", retryAt: 2*time.Second + cooldown},
+			},
+		},
+		{
+			name: "opens again after closing",
+			steps: []step{
+				{action: "failure"},
+				{action: "failure"},
+				{action: "failure", opened: true},
+				{at: cooldown, action: "allow", allowed: true},
+				{at: cooldown, action: "success"},
+				{at: cooldown, action: "failure"},
+				{at: cooldown, action: "failure"},
+				{at: cooldown, action: "failure", opened: true},
+			},
+		},
+		{
+			name: "success resets failures",
+			steps: []step{
+				{action: "failure"},
+				{action: "failure"},
+				{action: "success"},
+				{action: "failure"},
+				{action: "allow", allowed: true},
+			},
+		},
+		{
+			name: "single probe after cooldown",
+			steps: []step{
+				{action: "failure"},
+				{action: "failure"},
+				{action: "failure", opened: true},
+				{at: cooldown, action: "allow", allowed: true},
+				{at: cooldown, action: "allow", retryAt: cooldown + retry},
+				{at: cooldown + time.Second, action: "allow", retryAt: cooldown + time.Second + retry},
+			},
+		},
+		{
+			name: "successful probe closes",
+			steps: []step{
+				{action: "failure"},
+				{action: "failure"},
+				{action: "failure", opened: true},
+				{at: cooldown, action: "allow", allowed: true},
+				{at: cooldown, action: "success"},
+				{at: cooldown, action: "allow", allowed: true},
+				{at: cooldown, action: "allow", allowed: true},
+			},
+		},
+		{
+			name: "failed probe reopens",
+			steps: []step{
+				{action: "failure"},
+				{action: "failure"},
+				{action: "failure", opened: true},
+				{at: cooldown, action: "allow", allowed: true},
+				{at: cooldown + time.Second, action: "failure"},
+				{at: cooldown + 2*time.Second, action: "allow", retryAt: 2*cooldown + time.Second},
+				{at: 2*cooldown + time.Second, action: "allow", allowed: true},
+			},
+		},
+		{
+			name: "lost probe is replaced after cooldown",
+			steps: []step{
+				{action: "failure"},
+				{action: "failure"},
+				{action: "failure", opened: true},
+				{at: cooldown, action: "allow", allowed: true},
+				{at: 2 * cooldown, action: "allow", allowed: true},
+				{at: 2 * cooldown, action: "allow", retryAt: 2*cooldown + retry},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			breaker := &circuitBreaker{threshold: 3, cooldown: cooldown, retry: retry}
+
+			for i, s := range tt.steps {
+				now := start.Add(s.at)
+
+				switch s.action {
+				case "allow":
+					allowed, retryAt := breaker.allow(now)
+					if allowed != s.allowed {
+						t.Fatalf("шаг %d: allow = %v, want %v", i, allowed, s.allowed)
+					}
+					if !allowed && !retryAt.Equal(start.Add(s.retryAt)) {
+						t.Fatalf("шаг %d: retryAt = %v, want %v", i, retryAt, start.Add(s.retryAt))
+					}
+				case "success":
+					breaker.success()
+				case "failure":
+					if opened := breaker.failure(now); opened != s.opened {
+						t.Fatalf("шаг %d: failure = %v, want %v", i, opened, s.opened)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	service := &WebhookService{config: &WebhookConfig{
+		BackoffMin: time.Second,
+		BackoffMax: 30 * time.Second,
+	}}
+
+	tests := []struct {
+		attempts int
+		delay    time.Duration
+	}{
+		{attempts: 1, delay: time.Second},
+		{attempts: 2, delay: 2 * time.Second},
+		{attempts: 3, delay: 4 * time.Second},
+		{attempts: 5, delay: 16 * time.Second},
+		{attempts: 6, delay: 30 * time.Second},
+		{attempts: 20, delay: 30 * time.Second},
+	}
+
+	for _, tt := range tests {
+		for i := 0; i < 100; i++ {
+			got := service.backoff(tt.attempts)
+			if got < tt.delay/2 || got >= tt.delay {
+				t.Fatalf("backoff(%d) = %v, want [%v, %v)", tt.attempts, got, tt.delay/2, tt.delay)
+			}
+		}
+	}
+}
+
+func TestWebhookBackoffWithoutJitter(t *testing.T) {
+	service := &WebhookService{config: &WebhookConfig{BackoffMin: time.Nanosecond, BackoffMax: time.Nanosecond}}
+
+	if got := service.backoff(3); got != time.Nanosecond {
+		t.Fatalf("backoff(3) = %v, want 1ns", got)
+	}
+}
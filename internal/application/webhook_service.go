@@ -0,0 +1,535 @@
+package application
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	mathrand "math/rand"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+	"github.com/anatoly_dev/go-ws-notifications/pkg/logger"
+	"github.com/google/uuid"
+)
+
+const (
+	webhookBatchSize       = 100
+	webhookCleanupInterval = time.Hour
+	// Если узел не сохранил результат попытки за это время, доставку заберёт другой
+	webhookLease = 10 * time.Minute
+)
+
+// WebhookConfig задаёт повторы, размыкатель и параллельность доставки.
+type WebhookConfig struct {
+	MaxAttempts int
+	// Задержка перед n-м повтором - BackoffMin * 2^(n-1), но не больше BackoffMax
+	BackoffMin       time.Duration
+	BackoffMax       time.Duration
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+	// Сколько подписок обслуживается параллельно; доставки одной подписки идут по порядку
+	Concurrency  int
+	Interval     time.Duration
+	LogRetention time.Duration
+}
+
+// WebhookService ведёт подписки внешних сервисов и доставляет им
+// уведомления подписанными запросами. Доставки хранятся в журнале:
+// воркер забирает из него наступившие попытки, а неудачные переносит
+// с экспоненциальной задержкой.
+type WebhookService struct {
+	store      domain.WebhookStore
+	deliveries domain.WebhookDeliveryStore
+	sender     domain.WebhookSender
+	config     *WebhookConfig
+	tracker    domain.DeliveryTracker
+	metrics    domain.Metrics
+	logger     *logger.Logger
+
+	breakers     map[string]*circuitBreaker // по URL подписки
+	breakersLock sync.Mutex
+
+	wake chan struct{}
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+func NewWebhookService(
+	store domain.WebhookStore,
+	deliveries domain.WebhookDeliveryStore,
+	sender domain.WebhookSender,
+	config *WebhookConfig,
+	tracker domain.DeliveryTracker,
+	metrics domain.Metrics,
+	logger *logger.Logger,
+) *WebhookService {
+	return &WebhookService{
+		store:      store,
+		deliveries: deliveries,
+		sender:     sender,
+		config:     config,
+		tracker:    tracker,
+		metrics:    metrics,
+		logger:     logger.WithField("source", "webhook_service"),
+		breakers:   make(map[string]*circuitBreaker),
+		wake:       make(chan struct{}, 1),
+		stop:       make(chan struct{}),
+	}
+}
+
+// Create регистрирует подписку. Если секрет не задан, он генерируется;
+// подписчик получает его только в ответе на создание.
+func (s *WebhookService) Create(ctx context.Context, webhook *domain.Webhook) error {
+	if err := webhook.Validate(); err != nil {
+		return err
+	}
+
+	if webhook.Secret == "" {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return fmt.Errorf("ошибка генерации секрета вебхука: %w", err)
+		}
+		webhook.Secret = hex.EncodeToString(secret)
+	}
+
+	webhook.ID = uuid.New().String()
+	webhook.CreatedAt = time.Now()
+
+	if err := s.store.Save(ctx, webhook); err != nil {
+		return err
+	}
+
+	s.logger.WithFields(map[string]interface{}{
+		"webhookID": webhook.ID,
+		"host":      endpointHost(webhook.URL),
+	}).Info("Зарегистрирован вебхук")
+
+	return nil
+}
+
+func (s *WebhookService) List(ctx context.Context) ([]*domain.Webhook, error) {
+	webhooks, err := s.store.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, webhook := range webhooks {
+		webhook.Secret = ""
+	}
+
+	return webhooks, nil
+}
+
+// Delete удаляет подписку; её ожидающие доставки завершатся ошибкой при
+// следующей попытке, а журнал остаётся до истечения срока хранения.
+func (s *WebhookService) Delete(ctx context.Context, id string) error {
+	if err := s.store.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	s.logger.WithField("webhookID", id).Info("Вебхук удалён")
+	return nil
+}
+
+func (s *WebhookService) Authenticate(ctx context.Context, webhookID string, secret string) error {
+	webhook, err := s.store.FindByID(ctx, webhookID)
+	if errors.Is(err, domain.ErrNotFound) {
+		return domain.ErrUnauthorized
+	}
+	if err != nil {
+		return err
+	}
+
+	if secret == "" || !hmac.Equal([]byte(secret), []byte(webhook.Secret)) {
+		return domain.ErrUnauthorized
+	}
+
+	return nil
+}
+
+func (s *WebhookService) Deliveries(ctx context.Context, webhookID string, limit int) ([]*domain.WebhookDelivery, error) {
+	if _, err := s.store.FindByID(ctx, webhookID); err != nil {
+		return nil, err
+	}
+
+	return s.deliveries.FindByWebhookID(ctx, webhookID, limit)
+}
+
+func (s *WebhookService) Replay(ctx context.Context, webhookID string, deliveryID string) (*domain.WebhookDelivery, error) {
+	original, err := s.deliveries.FindByID(ctx, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+
+	if webhookID != "" && original.WebhookID != webhookID {
+		return nil, domain.ErrNotFound
+	}
+
+	if original.Status == domain.WebhookPending {
+		return nil, fmt.Errorf("%w: доставка ещё не завершена", domain.ErrInvalidInput)
+	}
+
+	if _, err := s.store.FindByID(ctx, original.WebhookID); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	replay := &domain.WebhookDelivery{
+		ID:             uuid.New().String(),
+		WebhookID:      original.WebhookID,
+		NotificationID: original.NotificationID,
+		UserID:         original.UserID,
+		Payload:        original.Payload,
+		Status:         domain.WebhookPending,
+		NextAttemptAt:  now,
+		ReplayOf:       original.ID,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+
+	if err := s.deliveries.Save(ctx, replay); err != nil {
+		return nil, err
+	}
+
+	s.logger.WithFields(map[string]interface{}{
+		"webhookID":  replay.WebhookID,
+		"deliveryID": replay.ID,
+		"replayOf":   original.ID,
+	}).Info("Доставка вебхука поставлена в очередь повторно")
+
+	s.notify()
+
+	return replay, nil
+}
+
+func (s *WebhookService) Name() string {
+	return domain.ChannelWebhook
+}
+
+// Deliver ставит уведомление в очередь доставки всем подходящим подпискам.
+// Сама отправка выполняется воркером, поэтому уведомление считается
+// доставленным, когда поставлена хотя бы одна доставка.
+func (s *WebhookService) Deliver(ctx context.Context, notification *domain.Notification, frame []byte) error {
+	log := s.logger.WithFields(map[string]interface{}{
+		"notificationID": notification.ID,
+		"userID":         notification.UserID,
+	})
+
+	webhooks, err := s.store.FindAll(ctx)
+	if err != nil {
+		log.WithError(err).Error("Ошибка чтения вебхуков")
+		return err
+	}
+
+	var payload []byte
+	var saveErr error
+	queued := 0
+	now := time.Now()
+
+	for _, webhook := range webhooks {
+		if !webhook.Matches(notification) {
+			continue
+		}
+
+		if payload == nil {
+			payload, err = json.Marshal(domain.WebhookEvent{
+				Event:        domain.EventNotificationCreated,
+				Notification: notification,
+			})
+			if err != nil {
+				log.WithError(err).Error("Ошибка сериализации события вебхука")
+				return err
+			}
+		}
+
+		delivery := &domain.WebhookDelivery{
+			ID:             uuid.New().String(),
+			WebhookID:      webhook.ID,
+			NotificationID: notification.ID,
+			UserID:         notification.UserID,
+			Payload:        payload,
+			Status:         domain.WebhookPending,
+			NextAttemptAt:  now,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		}
+
+		if err := s.deliveries.Save(ctx, delivery); err != nil {
+			log.WithError(err).WithField("webhookID", webhook.ID).Error("Ошибка постановки доставки вебхука")
+			saveErr = err
+			continue
+		}
+		queued++
+	}
+
+	switch {
+	case queued > 0:
+		s.notify()
+		return nil
+	case saveErr != nil:
+		return saveErr
+	default:
+		return domain.ErrChannelUnavailable
+	}
+}
+
+func (s *WebhookService) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(s.config.Interval)
+		defer ticker.Stop()
+
+		cleanup := time.NewTicker(webhookCleanupInterval)
+		defer cleanup.Stop()
+
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+			case <-s.wake:
+			case <-cleanup.C:
+				s.cleanup(context.Background())
+				continue
+			}
+
+			s.ProcessDue(context.Background(), time.Now())
+		}
+	}()
+}
+
+func (s *WebhookService) Stop() {
+	close(s.stop)
+	s.wg.Wait()
+}
+
+// ProcessDue захватывает и выполняет наступившие попытки доставки. Доставки
+// одной подписки отправляются по порядку, разные подписки - параллельно.
+func (s *WebhookService) ProcessDue(ctx context.Context, now time.Time) {
+	for {
+		due, err := s.deliveries.Claim(ctx, now, webhookLease, webhookBatchSize)
+		if err != nil {
+			s.logger.WithError(err).Error("Ошибка поиска доставок вебхуков")
+			return
+		}
+
+		groups := map[string][]*domain.WebhookDelivery{}
+		order := []string{}
+		for _, delivery := range due {
+			if _, ok := groups[delivery.WebhookID]; !ok {
+				order = append(order, delivery.WebhookID)
+			}
+			groups[delivery.WebhookID] = append(groups[delivery.WebhookID], delivery)
+		}
+
+		var wg sync.WaitGroup
+		var failed atomic.Bool
+		slots := make(chan struct{}, s.config.Concurrency)
+
+		for _, webhookID := range order {
+			slots <- struct{}{}
+			wg.Add(1)
+
+			go func(webhookID string, deliveries []*domain.WebhookDelivery) {
+				defer func() {
+					<-slots
+					wg.Done()
+				}()
+
+				if !s.processWebhook(ctx, webhookID, deliveries) {
+					failed.Store(true)
+				}
+			}(webhookID, groups[webhookID])
+		}
+
+		wg.Wait()
+
+		// При ошибке хранилища несохранённые доставки вернутся после истечения захвата
+		if failed.Load() || len(due) < webhookBatchSize {
+			return
+		}
+	}
+}
+
+// processWebhook возвращает false при ошибке хранилища.
+func (s *WebhookService) processWebhook(ctx context.Context, webhookID string, deliveries []*domain.WebhookDelivery) bool {
+	webhook, err := s.store.FindByID(ctx, webhookID)
+	if err != nil && !errors.Is(err, domain.ErrNotFound) {
+		s.logger.WithError(err).WithField("webhookID", webhookID).Error("Ошибка чтения вебхука")
+		return false
+	}
+
+	for _, delivery := range deliveries {
+		if webhook == nil {
+			s.finish(delivery, domain.WebhookFailed, "вебхук удалён")
+		} else {
+			s.attempt(ctx, webhook, delivery)
+		}
+
+		if err := s.deliveries.Save(ctx, delivery); err != nil {
+			s.logger.WithError(err).WithField("deliveryID", delivery.ID).Error("Ошибка сохранения доставки вебхука")
+			return false
+		}
+	}
+
+	return true
+}
+
+func (s *WebhookService) attempt(ctx context.Context, webhook *domain.Webhook, delivery *domain.WebhookDelivery) {
+	host := endpointHost(webhook.URL)
+	log := s.logger.WithFields(map[string]interface{}{
+		"webhookID":      webhook.ID,
+		"deliveryID":     delivery.ID,
+		"notificationID": delivery.NotificationID,
+		"host":           host,
+	})
+
+	if allowed, retryAt := s.allow(webhook.URL, time.Now()); !allowed {
+		// Отложенная из-за разомкнутой цепи доставка не тратит попытку
+		delivery.NextAttemptAt = retryAt
+		delivery.UpdatedAt = time.Now()
+		s.metrics.WebhookAttempt("deferred")
+		return
+	}
+
+	status, err := s.sender.Send(ctx, webhook, delivery)
+	now := time.Now()
+
+	delivery.Attempts++
+	delivery.LastStatusCode = status
+	delivery.LastError = ""
+	delivery.UpdatedAt = now
+
+	if err == nil {
+		s.recordSuccess(webhook.URL)
+		s.finish(delivery, domain.WebhookSucceeded, "")
+		s.metrics.WebhookAttempt("succeeded")
+		s.tracker.Track(delivery.NotificationID, delivery.UserID, domain.StageWebhookSent, host)
+		log.Debug("Вебхук доставлен")
+		return
+	}
+
+	delivery.LastError = err.Error()
+
+	// Ответ 4xx означает, что адрес доступен, но повтор не поможет
+	retryable := isRetryableWebhookStatus(status)
+	if !retryable {
+		s.recordSuccess(webhook.URL)
+	} else if s.recordFailure(webhook.URL, now) {
+		s.metrics.WebhookCircuitOpened()
+		log.WithField("cooldown", s.config.BreakerCooldown).Warn("Адрес вебхука недоступен, доставки приостановлены")
+	}
+
+	if retryable && delivery.Attempts < s.config.MaxAttempts {
+		delivery.NextAttemptAt = now.Add(s.backoff(delivery.Attempts))
+		s.metrics.WebhookAttempt("retried")
+		log.WithError(err).WithFields(map[string]interface{}{
+			"attempt":       delivery.Attempts,
+			"nextAttemptAt": delivery.NextAttemptAt,
+		}).Warn("Ошибка доставки вебхука, попытка будет повторена")
+		return
+	}
+
+	s.finish(delivery, domain.WebhookFailed, delivery.LastError)
+	s.metrics.WebhookAttempt("failed")
+	s.tracker.Track(delivery.NotificationID, delivery.UserID, domain.StageFailed, "webhook: "+host+": "+delivery.LastError)
+	log.WithError(err).WithField("attempts", delivery.Attempts).Error("Доставка вебхука не удалась")
+}
+
+func (s *WebhookService) finish(delivery *domain.WebhookDelivery, status domain.WebhookDeliveryStatus, reason string) {
+	delivery.Status = status
+	if reason != "" {
+		delivery.LastError = reason
+	}
+	delivery.UpdatedAt = time.Now()
+}
+
+// backoff возвращает задержку перед следующей попыткой со случайным
+// разбросом в её вторую половину, чтобы повторы к одному адресу не шли пачкой.
+func (s *WebhookService) backoff(attempts int) time.Duration {
+	delay := s.config.BackoffMin
+	for i := 1; i < attempts && delay < s.config.BackoffMax; i++ {
+		delay *= 2
+	}
+	if delay > s.config.BackoffMax {
+		delay = s.config.BackoffMax
+	}
+
+	half := delay / 2
+	if half <= 0 {
+		return delay
+	}
+
+	return half + time.Duration(mathrand.Int63n(int64(half)))
+}
+
+func (s *WebhookService) cleanup(ctx context.Context) {
+	deleted, err := s.deliveries.DeleteFinishedBefore(ctx, time.Now().Add(-s.config.LogRetention))
+	if err != nil {
+		s.logger.WithError(err).Error("Ошибка очистки журнала доставок вебхуков")
+		return
+	}
+
+	if deleted > 0 {
+		s.logger.WithField("count", deleted).Debug("Удалены старые записи журнала доставок вебхуков")
+	}
+}
+
+func (s *WebhookService) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *WebhookService) allow(url string, now time.Time) (bool, time.Time) {
+	s.breakersLock.Lock()
+	defer s.breakersLock.Unlock()
+
+	return s.breaker(url).allow(now)
+}
+
+func (s *WebhookService) recordSuccess(url string) {
+	s.breakersLock.Lock()
+	defer s.breakersLock.Unlock()
+
+	s.breaker(url).success()
+}
+
+func (s *WebhookService) recordFailure(url string, now time.Time) bool {
+	s.breakersLock.Lock()
+	defer s.breakersLock.Unlock()
+
+	return s.breaker(url).failure(now)
+}
+
+func (s *WebhookService) breaker(url string) *circuitBreaker {
+	breaker, ok := s.breakers[url]
+	if !ok {
+		breaker = &circuitBreaker{
+			threshold: s.config.BreakerThreshold,
+			cooldown:  s.config.BreakerCooldown,
+			retry:     s.config.Interval,
+		}
+		s.breakers[url] = breaker
+	}
+
+	return breaker
+}
+
+// isRetryableWebhookStatus: сетевая ошибка (0), таймаут, лимит запросов и 5xx.
+func isRetryableWebhookStatus(status int) bool {
+	return status == 0 ||
+		status == http.StatusRequestTimeout ||
+		status == http.StatusTooManyRequests ||
+		status >= 500
+}
@@ -0,0 +1,45 @@
+package domain
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// ValidatePublicURL проверяет адрес, на который сервис сам отправляет
+// запросы по указанию пользователя: только https и не внутренний хост.
+// Имена, которые резолвятся во внутренние адреса, отсекаются при
+// подключении на уровне инфраструктуры.
+func ValidatePublicURL(raw string) error {
+	target, err := url.Parse(raw)
+	if err != nil || target.Host == "" {
+		return fmt.Errorf("%w: некорректный адрес %q", ErrInvalidInput, raw)
+	}
+
+	if target.Scheme != "https" {
+		return fmt.Errorf("%w: адрес должен начинаться с https://", ErrInvalidInput)
+	}
+
+	host := strings.ToLower(strings.TrimSuffix(target.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("%w: адрес указывает на внутренний хост", ErrInvalidInput)
+	}
+
+	if ip := net.ParseIP(host); ip != nil && !IsPublicIP(ip) {
+		return fmt.Errorf("%w: адрес указывает на внутренний хост", ErrInvalidInput)
+	}
+
+	return nil
+}
+
+// sharedAddressSpace - диапазон carrier-grade NAT (RFC 6598): в облаках
+// в нём бывают внутренние сервисы, а net.IP.IsPrivate его не учитывает.
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// IsPublicIP сообщает, что адрес не относится к loopback, частным,
+// carrier-grade NAT, link-local и другим внутренним диапазонам.
+func IsPublicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || sharedAddressSpace.Contains(ip) || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsUnspecified() || ip.IsMulticast())
+}
@@ -0,0 +1,47 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidatePublicURL(t *testing.T) {
+	tests := []struct {
+		name  string
+		raw   string
+		valid bool
+	}{
+		{name: "public https", raw: "https://fcm.googleapis.com/fcm/send/abc", valid: true},
+		{name: "public ip", raw: "https://93.184.216.34/push", valid: true},
+		{name: "http", raw: "http://fcm.googleapis.com/fcm/send/abc"},
+		{name: "no host", raw: "https:///path"},
+		{name: "localhost", raw: "https://localhost/push"},
+		{name: "localhost subdomain", raw: "https://api.localhost./push"},
+		{name: "loopback", raw: "https://127.0.0.1:8080/push"},
+		{name: "loopback ipv6", raw: "https://[::1]/push"},
+		{name: "private", raw: "https://10.1.2.3/push"},
+		{name: "private 192", raw: "https://192.168.0.10/push"},
+		{name: "carrier-grade nat", raw: "https://100.64.0.1/push"},
+		{name: "carrier-grade nat end", raw: "https://100.127.255.254/push"},
+		{name: "carrier-grade nat mapped ipv6", raw: "https://[::ffff:100.100.100.200]/push"},
+		{name: "after carrier-grade nat", raw: "https://100.128.0.1/push", valid: true},
+		{name: "link-local metadata", raw: "https://169.254.169.254/latest/meta-data"},
+		{name: "link-local ipv6", raw: "https://[fe80::1]/push"},
+		{name: "unspecified", raw: "https://0.0.0.0/push"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePublicURL(tt.raw)
+			if tt.valid {
+				if err != nil {
+					t.Fatalf("ValidatePublicURL(%q) = %v, want nil", tt.raw, err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidInput) {
+				t.Fatalf("ValidatePublicURL(%q) = %v, want ErrInvalidInput", tt.raw, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+const (
+	EventBroadcast = "broadcast"
+	// KindBroadcast отличает объявление от уведомления в общем топике Kafka
+	KindBroadcast = "broadcast"
+)
+
+// ClientAttributes - свойства соединения, которые клиент передаёт при подключении.
+type ClientAttributes struct {
+	AppVersion string `json:"app_version,omitempty"`
+	Platform   string `json:"platform,omitempty"`
+	Locale     string `json:"locale,omitempty"`
+}
+
+// Audience ограничивает получателей широковещательного объявления.
+// Пустые поля не ограничивают; локаль "ru" подходит и для "ru-RU".
+type Audience struct {
+	Platforms     []string `json:"platforms,omitempty"`
+	Locales       []string `json:"locales,omitempty"`
+	MinAppVersion string   `json:"min_app_version,omitempty"`
+	MaxAppVersion string   `json:"max_app_version,omitempty"`
+}
+
+func (a *Audience) Matches(attributes ClientAttributes) bool {
+	if a == nil {
+		return true
+	}
+
+	if len(a.Platforms) > 0 && !containsFold(a.Platforms, attributes.Platform) {
+		return false
+	}
+
+	if len(a.Locales) > 0 && !matchesLocale(a.Locales, attributes.Locale) {
+		return false
+	}
+
+	// Клиент без версии не подходит под ограничение по версии
+	if a.MinAppVersion != "" && (attributes.AppVersion == "" || CompareVersions(attributes.AppVersion, a.MinAppVersion) < 0) {
+		return false
+	}
+
+	if a.MaxAppVersion != "" && (attributes.AppVersion == "" || CompareVersions(attributes.AppVersion, a.MaxAppVersion) > 0) {
+		return false
+	}
+
+	return true
+}
+
+// Broadcast - объявление для всех подключённых клиентов, подходящих под
+// Audience. Хранится до ExpiresAt и доставляется клиентам, подключившимся позже.
+type Broadcast struct {
+	ID        string           `json:"id"`
+	Type      NotificationType `json:"type" validate:"required,oneof=system alert message"`
+	Title     string           `json:"title" validate:"required"`
+	Content   string           `json:"content" validate:"required"`
+	Priority  int              `json:"priority" validate:"min=0,max=5"`
+	Audience  *Audience        `json:"audience,omitempty"`
+	CreatedAt time.Time        `json:"created_at"`
+	ExpiresAt *time.Time       `json:"expires_at,omitempty"`
+	TTL       int              `json:"ttl,omitempty" validate:"min=0"` // секунды
+}
+
+func (b *Broadcast) Validate() error {
+	validate := validator.New()
+	if err := validate.Struct(b); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+
+	return nil
+}
+
+func (b *Broadcast) IsExpired(now time.Time) bool {
+	return b.ExpiresAt != nil && !now.Before(*b.ExpiresAt)
+}
+
+// BroadcastEvent - фрейм, которым объявление доставляется клиенту.
+type BroadcastEvent struct {
+	Event     string     `json:"event"`
+	Broadcast *Broadcast `json:"broadcast"`
+}
+
+type BroadcastStore interface {
+	Save(ctx context.Context, broadcast *Broadcast) error
+	FindByID(ctx context.Context, id string) (*Broadcast, error)
+	// FindActive возвращает неистёкшие объявления в порядке создания
+	FindActive(ctx context.Context, now time.Time) ([]*Broadcast, error)
+	Delete(ctx context.Context, id string) error
+	DeleteExpired(ctx context.Context, now time.Time) (int, error)
+}
+
+// BroadcastBroker рассылает объявления подключённым клиентам.
+type BroadcastBroker interface {
+	// BroadcastToAudience возвращает количество клиентов, которым поставлено сообщение
+	BroadcastToAudience(audience *Audience, message []byte) int
+	SendToUser(userID string, message []byte) error
+}
+
+type BroadcastService interface {
+	Send(ctx context.Context, broadcast *Broadcast) (int, error)
+	List(ctx context.Context) ([]*Broadcast, error)
+	Delete(ctx context.Context, id string) error
+}
+
+// CompareVersions сравнивает версии вида "1.10.2" по числовым компонентам;
+// префикс "v" и суффикс после "-" или "+" игнорируются.
+func CompareVersions(a, b string) int {
+	left, right := versionParts(a), versionParts(b)
+
+	for i := 0; i < len(left) || i < len(right); i++ {
+		var x, y int
+		if i < len(left) {
+			x = left[i]
+		}
+		if i < len(right) {
+			y = right[i]
+		}
+
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+	}
+
+	return 0
+}
+
+func versionParts(version string) []int {
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if i := strings.IndexAny(version, "-+"); i >= 0 {
+		version = version[:i]
+	}
+
+	parts := []int{}
+	for _, part := range strings.Split(version, ".") {
+		number, _ := strconv.Atoi(part)
+		parts = append(parts, number)
+	}
+
+	return parts
+}
+
+func containsFold(values []string, value string) bool {
+	for _, candidate := range values {
+		if strings.EqualFold(candidate, value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func matchesLocale(locales []string, locale string) bool {
+	locale = strings.ToLower(strings.ReplaceAll(locale, "_", "-"))
+
+	for _, candidate := range locales {
+		candidate = strings.ToLower(strings.ReplaceAll(candidate, "_", "-"))
+		if locale == candidate || strings.HasPrefix(locale, candidate+"-") {
+			return true
+		}
+	}
+
+	return false
+}
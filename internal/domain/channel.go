@@ -0,0 +1,64 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+const EventChannelMessage = "channel.message"
+
+var channelNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.:/-]{1,200}$`)
+
+// ValidateChannelName проверяет имя канала, например "orders:42" или "incidents/live".
+func ValidateChannelName(channel string) error {
+	if !channelNamePattern.MatchString(channel) {
+		return fmt.Errorf("%w: некорректное имя канала %q", ErrInvalidInput, channel)
+	}
+
+	return nil
+}
+
+// ChannelMessage - сообщение для всех сокетов, подписанных на канал.
+// В отличие от уведомления не адресовано пользователю и не сохраняется.
+type ChannelMessage struct {
+	ID        string           `json:"id"`
+	Channel   string           `json:"channel"`
+	Type      NotificationType `json:"type,omitempty"`
+	Title     string           `json:"title,omitempty"`
+	Content   string           `json:"content,omitempty"`
+	Data      json.RawMessage  `json:"data,omitempty"`
+	CreatedAt time.Time        `json:"created_at"`
+}
+
+// ChannelEvent - фрейм, которым сообщение канала доставляется клиенту.
+type ChannelEvent struct {
+	Event   string          `json:"event"`
+	Channel string          `json:"channel"`
+	Message *ChannelMessage `json:"message"`
+}
+
+// ChannelPolicy решает, может ли пользователь подписаться на канал.
+// Отказ возвращается как ErrUnauthorized.
+type ChannelPolicy interface {
+	Authorize(ctx context.Context, userID string, channel string) error
+}
+
+// ChannelBroker хранит подписки сокетов на каналы и рассылает им сообщения.
+// connectionID - сокет, приславший команду.
+type ChannelBroker interface {
+	Subscribe(userID string, connectionID string, channel string) error
+	Unsubscribe(userID string, connectionID string, channel string) error
+	Subscriptions(userID string) []string
+	// PublishToChannel возвращает количество сокетов, которым поставлено сообщение
+	PublishToChannel(channel string, message []byte) int
+}
+
+type ChannelService interface {
+	Subscribe(ctx context.Context, userID string, connectionID string, channel string) error
+	Unsubscribe(ctx context.Context, userID string, connectionID string, channel string) error
+	Subscriptions(ctx context.Context, userID string) []string
+	Publish(ctx context.Context, message *ChannelMessage) (int, error)
+}
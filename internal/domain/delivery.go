@@ -0,0 +1,47 @@
+package domain
+
+import "time"
+
+type DeliveryStage string
+
+const (
+	StageConsumed     DeliveryStage = "consumed"
+	StageValidated    DeliveryStage = "validated"
+	StageSaved        DeliveryStage = "saved"
+	StageScheduled    DeliveryStage = "scheduled"
+	StageCancelled    DeliveryStage = "cancelled"
+	StageMuted        DeliveryStage = "muted"
+	StageReplaced     DeliveryStage = "replaced"
+	StageDigested     DeliveryStage = "digested"
+	StageFannedOut    DeliveryStage = "fanned_out"
+	StageSpilled      DeliveryStage = "spilled"
+	StageClientFound  DeliveryStage = "client_found"
+	StageNotConnected DeliveryStage = "not_connected"
+	StagePushed       DeliveryStage = "pushed"
+	StageWebhookSent  DeliveryStage = "webhook_sent"
+	StageEmailed      DeliveryStage = "emailed"
+	StageDelivered    DeliveryStage = "delivered"
+	StageQueued       DeliveryStage = "queued"
+	StageWritten      DeliveryStage = "written"
+	StageAcked        DeliveryStage = "acked"
+	StageRead         DeliveryStage = "read"
+	StageFailed       DeliveryStage = "failed"
+)
+
+type DeliveryEvent struct {
+	Stage     DeliveryStage `json:"stage"`
+	Timestamp time.Time     `json:"timestamp"`
+	Details   string        `json:"details,omitempty"`
+}
+
+type DeliveryTimeline struct {
+	NotificationID string          `json:"notification_id"`
+	UserID         string          `json:"user_id"`
+	Events         []DeliveryEvent `json:"events"`
+}
+
+type DeliveryTracker interface {
+	Track(notificationID string, userID string, stage DeliveryStage, details string)
+	Timeline(notificationID string) (*DeliveryTimeline, error)
+	UserTimelines(userID string, limit int) ([]*DeliveryTimeline, error)
+}
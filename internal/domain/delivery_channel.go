@@ -0,0 +1,58 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Имена каналов доставки, из которых составляются цепочки.
+const (
+	// ChannelRealtime - любое активное соединение: WebSocket, SSE или long-polling
+	ChannelRealtime  = "realtime"
+	ChannelWebSocket = "websocket"
+	ChannelSSE       = "sse"
+	ChannelLongPoll  = "long_poll"
+	ChannelWebPush   = "webpush"
+	ChannelWebhook   = "webhook"
+	ChannelEmail     = "email"
+)
+
+// DeliveryChannel доставляет сохранённое уведомление пользователю. Возвращает
+// ErrChannelUnavailable, если канал не может доставить его этому получателю:
+// нет подписки, адреса или канал выключен.
+// frame - фрейм для клиентских соединений; остальные каналы формируют
+// сообщение сами.
+type DeliveryChannel interface {
+	Name() string
+	Deliver(ctx context.Context, notification *Notification, frame []byte) error
+}
+
+// IsConnectionChannel сообщает, что канал доставляет через соединение
+// клиента. Такой канал только ставит фрейм в буфер; уведомление отмечается
+// доставленным после записи фрейма.
+func IsConnectionChannel(name string) bool {
+	switch name {
+	case ChannelRealtime, ChannelWebSocket, ChannelSSE, ChannelLongPoll:
+		return true
+	}
+	return false
+}
+
+// DeliveryStep - шаг цепочки доставки. Шаг с After > 0 выполняется через
+// After после отправки, если уведомление не доставлено предыдущими шагами
+// и всё ещё не прочитано.
+type DeliveryStep struct {
+	Channel string
+	After   time.Duration
+}
+
+// EmailMessage - письмо, отправляемое каналом email.
+type EmailMessage struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type EmailSender interface {
+	Send(ctx context.Context, message *EmailMessage) error
+}
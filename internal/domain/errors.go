@@ -3,9 +3,17 @@ package domain
 import "errors"
 
 var (
-	ErrInternal         = errors.New("internal server error")
-	ErrNotFound         = errors.New("resource not found")
-	ErrUserNotConnected = errors.New("user not connected")
-	ErrInvalidInput     = errors.New("invalid input")
-	ErrUnauthorized     = errors.New("unauthorized")
+	ErrInternal           = errors.New("internal server error")
+	ErrNotFound           = errors.New("resource not found")
+	ErrUserNotConnected   = errors.New("user not connected")
+	ErrInvalidInput       = errors.New("invalid input")
+	ErrUnauthorized       = errors.New("unauthorized")
+	ErrExpired            = errors.New("notification expired")
+	ErrMuted              = errors.New("notification muted by user preferences")
+	ErrSubscriptionGone   = errors.New("push subscription expired")
+	ErrChannelUnavailable = errors.New("delivery channel unavailable for recipient")
+	// ErrMessageDropped - сообщение не попало в буфер соединения из-за политики переполнения
+	ErrMessageDropped = errors.New("message dropped by client backpressure policy")
+	// ErrMessageSpilled - уведомление перенесено в офлайн-очередь и будет отправлено позже
+	ErrMessageSpilled = errors.New("notification moved to the offline queue")
 )
@@ -0,0 +1,15 @@
+package domain
+
+const (
+	EventNotificationRemoved  = "notification.removed"
+	EventNotificationReplaced = "notification.replaced"
+)
+
+// NotificationEvent - служебный фрейм, сообщающий клиенту об изменении
+// ранее доставленного уведомления.
+type NotificationEvent struct {
+	Event        string        `json:"event"`
+	ID           string        `json:"id,omitempty"`
+	Reason       string        `json:"reason,omitempty"`
+	Notification *Notification `json:"notification,omitempty"`
+}
@@ -0,0 +1,19 @@
+package domain
+
+import "context"
+
+// GroupAll - имя группы, объединяющей всех пользователей.
+const GroupAll = "all"
+
+// Recipients задаёт адресатов уведомления для рассылки на стороне сервиса:
+// каждый получатель получает собственную копию со своим статусом прочтения.
+type Recipients struct {
+	UserIDs []string `json:"user_ids,omitempty"`
+	Groups  []string `json:"groups,omitempty"`
+	All     bool     `json:"all,omitempty"`
+}
+
+type GroupDirectory interface {
+	// Members возвращает пользователей группы или ErrNotFound для неизвестной группы
+	Members(ctx context.Context, group string) ([]string, error)
+}
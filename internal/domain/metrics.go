@@ -0,0 +1,18 @@
+package domain
+
+// Metrics - счётчики, которые ведут сервисы приложения. Реализация живёт
+// в инфраструктуре, чтобы приложение не зависело от Prometheus.
+type Metrics interface {
+	NotificationDelivered(notificationType NotificationType, channel string)
+	NotificationFailed(notificationType NotificationType, reason string)
+	NotificationDigested(rule string)
+	DigestEmitted(rule string, reason string)
+	PushMessage(result string)
+	EmailMessage(result string)
+	WebhookAttempt(result string)
+	WebhookCircuitOpened()
+	ChannelSubscribeDenied()
+	ChannelMessagePublished()
+	BroadcastSent()
+	BroadcastDeliveries(kind string, count int)
+}
@@ -1,6 +1,8 @@
 package domain
 
 import (
+	"context"
+	"fmt"
 	"time"
 
 	"github.com/go-playground/validator/v10"
@@ -15,14 +17,25 @@ const (
 )
 
 type Notification struct {
-	ID        string           `json:"id" validate:"required"`
-	UserID    string           `json:"user_id" validate:"required"`
-	Type      NotificationType `json:"type" validate:"required,oneof=system alert message"`
-	Title     string           `json:"title" validate:"required"`
-	Content   string           `json:"content" validate:"required"`
-	IsRead    bool             `json:"is_read"`
-	CreatedAt time.Time        `json:"created_at"`
-	Priority  int              `json:"priority" validate:"min=0,max=5"`
+	ID          string           `json:"id" validate:"required"`
+	UserID      string           `json:"user_id" validate:"required"`
+	Recipients  *Recipients      `json:"recipients,omitempty"`
+	Type        NotificationType `json:"type" validate:"required,oneof=system alert message"`
+	Category    string           `json:"category,omitempty"`
+	CollapseKey string           `json:"collapse_key,omitempty"`
+	GroupedIDs  []string         `json:"grouped_ids,omitempty"` // уведомления, объединённые в дайджест
+	Title       string           `json:"title" validate:"required"`
+	Content     string           `json:"content" validate:"required"`
+	IsRead      bool             `json:"is_read"`
+	CreatedAt   time.Time        `json:"created_at"`
+	Priority    int              `json:"priority" validate:"min=0,max=5"`
+	ExpiresAt   *time.Time       `json:"expires_at,omitempty"`
+	TTL         int              `json:"ttl,omitempty" validate:"min=0"` // секунды
+	SendAt      *time.Time       `json:"send_at,omitempty"`
+	Delay       int              `json:"delay,omitempty" validate:"min=0"` // секунды
+	SendAtLocal string           `json:"send_at_local,omitempty"`          // "15:04" или "2006-01-02T15:04:05" в TimeZone
+	TimeZone    string           `json:"timezone,omitempty"`
+	DeliveredAt *time.Time       `json:"delivered_at,omitempty"` // первая успешная доставка на любом узле
 }
 
 func (n *Notification) Validate() error {
@@ -30,24 +43,116 @@ func (n *Notification) Validate() error {
 	return validate.Struct(n)
 }
 
+// ApplyExpiry заполняет ExpiresAt из TTL уведомления или, если TTL не задан,
+// из значения по умолчанию для его типа. TTL отсчитывается от sentAt - момента
+// фактической отправки. Нулевой defaultTTL означает бессрочное уведомление.
+func (n *Notification) ApplyExpiry(sentAt time.Time, defaultTTL time.Duration) {
+	if n.ExpiresAt != nil {
+		return
+	}
+
+	ttl := time.Duration(n.TTL) * time.Second
+	if ttl == 0 {
+		ttl = defaultTTL
+	}
+	if ttl <= 0 {
+		return
+	}
+
+	expiresAt := sentAt.Add(ttl)
+	n.ExpiresAt = &expiresAt
+}
+
+func (n *Notification) IsExpired(now time.Time) bool {
+	return n.ExpiresAt != nil && !now.Before(*n.ExpiresAt)
+}
+
+// ResolveSendAt вычисляет момент отложенной доставки из send_at, delay или
+// send_at_local + timezone. Время "15:04" означает ближайшее такое время
+// в указанном часовом поясе. Возвращает нулевое время для немедленной доставки.
+func (n *Notification) ResolveSendAt(now time.Time) (time.Time, error) {
+	switch {
+	case n.SendAt != nil:
+		return *n.SendAt, nil
+	case n.Delay > 0:
+		return now.Add(time.Duration(n.Delay) * time.Second), nil
+	case n.SendAtLocal != "":
+		location := time.UTC
+		if n.TimeZone != "" {
+			var err error
+			location, err = time.LoadLocation(n.TimeZone)
+			if err != nil {
+				return time.Time{}, fmt.Errorf("%w: неизвестный часовой пояс %s", ErrInvalidInput, n.TimeZone)
+			}
+		}
+
+		if clock, err := time.ParseInLocation("15:04", n.SendAtLocal, location); err == nil {
+			local := now.In(location)
+			sendAt := time.Date(local.Year(), local.Month(), local.Day(), clock.Hour(), clock.Minute(), 0, 0, location)
+			if !sendAt.After(now) {
+				sendAt = sendAt.AddDate(0, 0, 1)
+			}
+			return sendAt, nil
+		}
+
+		sendAt, err := time.ParseInLocation("2006-01-02T15:04:05", n.SendAtLocal, location)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%w: некорректное время send_at_local %s", ErrInvalidInput, n.SendAtLocal)
+		}
+		return sendAt, nil
+	}
+
+	return time.Time{}, nil
+}
+
+// ClearSchedule сбрасывает параметры отложенной доставки перед фактической отправкой.
+func (n *Notification) ClearSchedule() {
+	n.SendAt = nil
+	n.Delay = 0
+	n.SendAtLocal = ""
+}
+
 type NotificationService interface {
-	Send(notification *Notification) error
-	MarkAsRead(id string, userID string) error
+	Send(ctx context.Context, notification *Notification) error
+	// SendScheduled отправляет наступившее отложенное уведомление
+	SendScheduled(ctx context.Context, notification *Notification, sentAt time.Time) error
+	MarkAsRead(ctx context.Context, id string, userID string) error
+	Ack(ctx context.Context, id string, userID string) error
+	History(ctx context.Context, userID string) ([]*Notification, error)
+	ListScheduled(ctx context.Context, userID string) ([]*ScheduledNotification, error)
+	CancelScheduled(ctx context.Context, id string, userID string) error
+	// DeliverFallback выполняет наступивший отложенный шаг цепочки доставки
+	DeliverFallback(ctx context.Context, item *ScheduledNotification) error
 }
 
 type NotificationRepository interface {
-	Save(notification *Notification) error
-	FindByID(id string) (*Notification, error)
-	FindByUserID(userID string) ([]*Notification, error)
-	Update(notification *Notification) error
+	Save(ctx context.Context, notification *Notification) error
+	FindByID(ctx context.Context, id string) (*Notification, error)
+	FindByUserID(ctx context.Context, userID string) ([]*Notification, error)
+	Update(ctx context.Context, notification *Notification) error
+	FindExpired(ctx context.Context, now time.Time, limit int) ([]*Notification, error)
+	// FindUnreadByCollapseKey возвращает последнее непрочитанное уведомление пользователя с этим ключом
+	FindUnreadByCollapseKey(ctx context.Context, userID string, collapseKey string) (*Notification, error)
+	// MarkDelivered отмечает первую доставку уведомления; повторные вызовы
+	// и отсутствующее уведомление ничего не меняют
+	MarkDelivered(ctx context.Context, id string, at time.Time) error
+	Delete(ctx context.Context, id string) error
 }
 
 type WebSocketService interface {
 	SendToUser(userID string, message []byte) error
+	SendNotification(ctx context.Context, notification *Notification, message []byte) error
 	BroadcastMessage(message []byte) error
+	IsConnected(userID string) bool
+}
+
+// ResumeService досылает уведомления, созданные после lastEventID, клиенту,
+// переподключившемуся после разрыва потока.
+type ResumeService interface {
+	Resume(ctx context.Context, userID string, lastEventID string) (int, error)
 }
 
 type KafkaConsumer interface {
-	Subscribe(topic string, handler func(message []byte) error) error
+	Subscribe(topic string, handler func(ctx context.Context, message []byte) error) error
 	Close() error
 }
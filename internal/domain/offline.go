@@ -0,0 +1,11 @@
+package domain
+
+import "context"
+
+// OfflineQueue хранит уведомления, которые не удалось поставить в сокет
+// пользователя, до его следующего подключения.
+type OfflineQueue interface {
+	Push(ctx context.Context, notification *Notification) error
+	// PopAll возвращает уведомления пользователя в порядке постановки и удаляет их из очереди
+	PopAll(ctx context.Context, userID string) ([]*Notification, error)
+}
@@ -0,0 +1,180 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+	"time"
+)
+
+const (
+	// MutedSilent - заглушённые уведомления сохраняются, но не отправляются клиенту
+	MutedSilent = "silent"
+	// MutedDrop - заглушённые уведомления не сохраняются
+	MutedDrop = "drop"
+)
+
+// Preferences - настройки уведомлений пользователя. Типы и категории,
+// отсутствующие в картах, включены.
+type Preferences struct {
+	UserID     string                    `json:"user_id"`
+	Types      map[NotificationType]bool `json:"types,omitempty"`
+	Categories map[string]bool           `json:"categories,omitempty"`
+	QuietHours *QuietHours               `json:"quiet_hours,omitempty"`
+	// Email - подтверждённый адрес канала email
+	Email        string             `json:"email,omitempty"`
+	PendingEmail *EmailVerification `json:"pending_email,omitempty"`
+	UpdatedAt    time.Time          `json:"updated_at"`
+}
+
+// EmailVerification - адрес, ожидающий подтверждения кодом из письма. Код
+// хранится только хэшем; Public убирает хэш и счётчик попыток из ответов.
+type EmailVerification struct {
+	Address   string    `json:"address"`
+	CodeHash  string    `json:"code_hash,omitempty"`
+	Attempts  int       `json:"attempts,omitempty"`
+	SentAt    time.Time `json:"sent_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// QuietHours - ежедневное окно "не беспокоить" в часовом поясе пользователя.
+// Окно может переходить через полночь, например 22:00-07:00.
+type QuietHours struct {
+	Start    string `json:"start"`
+	End      string `json:"end"`
+	TimeZone string `json:"timezone,omitempty"`
+}
+
+func (q *QuietHours) Validate() error {
+	if _, err := time.Parse("15:04", q.Start); err != nil {
+		return fmt.Errorf("%w: некорректное начало тихих часов %s", ErrInvalidInput, q.Start)
+	}
+
+	if _, err := time.Parse("15:04", q.End); err != nil {
+		return fmt.Errorf("%w: некорректное окончание тихих часов %s", ErrInvalidInput, q.End)
+	}
+
+	if _, err := time.LoadLocation(q.TimeZone); err != nil {
+		return fmt.Errorf("%w: неизвестный часовой пояс %s", ErrInvalidInput, q.TimeZone)
+	}
+
+	return nil
+}
+
+// Until возвращает момент окончания окна, если now попадает в тихие часы.
+func (q *QuietHours) Until(now time.Time) (time.Time, bool) {
+	location, err := time.LoadLocation(q.TimeZone)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	startClock, err := time.Parse("15:04", q.Start)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	endClock, err := time.Parse("15:04", q.End)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	local := now.In(location)
+	start := time.Date(local.Year(), local.Month(), local.Day(), startClock.Hour(), startClock.Minute(), 0, 0, location)
+	end := time.Date(local.Year(), local.Month(), local.Day(), endClock.Hour(), endClock.Minute(), 0, 0, location)
+
+	switch {
+	case start.Equal(end):
+		return time.Time{}, false
+	case start.Before(end):
+		if !local.Before(start) && local.Before(end) {
+			return end, true
+		}
+	default:
+		if !local.Before(start) {
+			return end.AddDate(0, 0, 1), true
+		}
+		if local.Before(end) {
+			return end, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+func NewPreferences(userID string) *Preferences {
+	return &Preferences{
+		UserID:     userID,
+		Types:      make(map[NotificationType]bool),
+		Categories: make(map[string]bool),
+	}
+}
+
+// Public возвращает копию настроек для ответа клиенту.
+func (p *Preferences) Public() *Preferences {
+	public := *p
+	if p.PendingEmail != nil {
+		public.PendingEmail = &EmailVerification{
+			Address:   p.PendingEmail.Address,
+			SentAt:    p.PendingEmail.SentAt,
+			ExpiresAt: p.PendingEmail.ExpiresAt,
+		}
+	}
+
+	return &public
+}
+
+// Allows сообщает, разрешил ли пользователь уведомления такого типа и категории.
+func (p *Preferences) Allows(notification *Notification) bool {
+	if enabled, ok := p.Types[notification.Type]; ok && !enabled {
+		return false
+	}
+
+	if notification.Category != "" {
+		if enabled, ok := p.Categories[notification.Category]; ok && !enabled {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ValidateEmail проверяет адрес для канала email; пустой адрес отключает доставку по почте.
+func ValidateEmail(address string) error {
+	if address == "" {
+		return nil
+	}
+
+	parsed, err := mail.ParseAddress(address)
+	if err != nil || parsed.Address != address {
+		return fmt.Errorf("%w: некорректный адрес электронной почты %s", ErrInvalidInput, address)
+	}
+
+	return nil
+}
+
+func IsKnownType(notificationType NotificationType) bool {
+	switch notificationType {
+	case TypeMessage, TypeSystem, TypeAlert:
+		return true
+	}
+	return false
+}
+
+type PreferenceStore interface {
+	// Get возвращает настройки по умолчанию, если пользователь их не менял
+	Get(ctx context.Context, userID string) (*Preferences, error)
+	Save(ctx context.Context, preferences *Preferences) error
+}
+
+type PreferenceService interface {
+	Get(ctx context.Context, userID string) (*Preferences, error)
+	Replace(ctx context.Context, preferences *Preferences) error
+	SetType(ctx context.Context, userID string, notificationType NotificationType, enabled bool) (*Preferences, error)
+	SetCategory(ctx context.Context, userID string, category string, enabled bool) (*Preferences, error)
+	// SetQuietHours включает тихие часы, nil отключает их
+	SetQuietHours(ctx context.Context, userID string, quietHours *QuietHours) (*Preferences, error)
+	// SetEmail отправляет на адрес код подтверждения; канал email начинает
+	// использовать адрес после VerifyEmail. Пустая строка удаляет адрес.
+	SetEmail(ctx context.Context, userID string, email string) (*Preferences, error)
+	VerifyEmail(ctx context.Context, userID string, code string) (*Preferences, error)
+}
@@ -0,0 +1,110 @@
+package domain
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// PushSubscription - подписка браузера на Web Push, объект PushSubscription
+// из Push API. Endpoint однозначно определяет подписку.
+type PushSubscription struct {
+	UserID    string    `json:"user_id"`
+	Endpoint  string    `json:"endpoint" validate:"required,url"`
+	Keys      PushKeys  `json:"keys"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// PushSubscriptionView - то, что о подписке видит клиент: ключи
+// шифрования не покидают сервер.
+type PushSubscriptionView struct {
+	ID        string    `json:"id"`
+	Host      string    `json:"host"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ID - устойчивый идентификатор подписки, производный от endpoint.
+func (s *PushSubscription) ID() string {
+	sum := sha256.Sum256([]byte(s.Endpoint))
+	return hex.EncodeToString(sum[:8])
+}
+
+func (s *PushSubscription) View() *PushSubscriptionView {
+	view := &PushSubscriptionView{ID: s.ID(), CreatedAt: s.CreatedAt}
+	if endpoint, err := url.Parse(s.Endpoint); err == nil {
+		view.Host = endpoint.Hostname()
+	}
+	return view
+}
+
+// PushKeys - ключи шифрования подписки в base64url: p256dh - открытый ключ
+// P-256 браузера, auth - 16-байтовый секрет аутентификации.
+type PushKeys struct {
+	P256dh string `json:"p256dh" validate:"required"`
+	Auth   string `json:"auth" validate:"required"`
+}
+
+func (s *PushSubscription) Validate() error {
+	validate := validator.New()
+	if err := validate.Struct(s); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+
+	if err := ValidatePublicURL(s.Endpoint); err != nil {
+		return fmt.Errorf("endpoint: %w", err)
+	}
+
+	if key, err := DecodePushKey(s.Keys.P256dh); err != nil || len(key) != 65 || key[0] != 4 {
+		return fmt.Errorf("%w: некорректный ключ p256dh", ErrInvalidInput)
+	}
+
+	if secret, err := DecodePushKey(s.Keys.Auth); err != nil || len(secret) != 16 {
+		return fmt.Errorf("%w: некорректный секрет auth", ErrInvalidInput)
+	}
+
+	return nil
+}
+
+// DecodePushKey декодирует ключ в base64url; браузеры отдают его без
+// дополнения, но некоторые клиенты добавляют "=".
+func DecodePushKey(value string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+}
+
+// PushMessage - зашифровываемое содержимое и заголовки доставки Web Push.
+type PushMessage struct {
+	Payload []byte
+	TTL     time.Duration
+	// Urgency - very-low, low, normal или high (RFC 8030)
+	Urgency string
+	// Topic заменяет в push-сервисе ещё не доставленное сообщение с тем же топиком
+	Topic string
+}
+
+type PushSubscriptionStore interface {
+	// Save добавляет подписку или обновляет существующую с тем же endpoint
+	Save(ctx context.Context, subscription *PushSubscription) error
+	FindByUserID(ctx context.Context, userID string) ([]*PushSubscription, error)
+	Delete(ctx context.Context, userID string, endpoint string) error
+}
+
+// PushSender отправляет сообщение в push-сервис браузера. Возвращает
+// ErrSubscriptionGone, если подписка больше не действует.
+type PushSender interface {
+	Send(ctx context.Context, subscription *PushSubscription, message *PushMessage) error
+}
+
+type PushService interface {
+	Subscribe(ctx context.Context, subscription *PushSubscription) error
+	Unsubscribe(ctx context.Context, userID string, endpoint string) error
+	List(ctx context.Context, userID string) ([]*PushSubscription, error)
+	// PublicKey возвращает VAPID-ключ для PushManager.subscribe
+	PublicKey() string
+}
@@ -0,0 +1,69 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// RecurringJob - повторяющееся объявление по cron-расписанию. Без UserIDs
+// объявление рассылается всем подключённым пользователям.
+type RecurringJob struct {
+	ID        string           `json:"id"`
+	Name      string           `json:"name" validate:"required"`
+	Spec      string           `json:"spec" validate:"required"` // "0 9 * * 1" - по понедельникам в 09:00
+	TimeZone  string           `json:"timezone,omitempty"`
+	StartAt   *time.Time       `json:"start_at,omitempty"`
+	EndAt     *time.Time       `json:"end_at,omitempty"`
+	Paused    bool             `json:"paused"`
+	UserIDs   []string         `json:"user_ids,omitempty"`
+	Type      NotificationType `json:"type" validate:"required,oneof=system alert message"`
+	Title     string           `json:"title" validate:"required"`
+	Content   string           `json:"content" validate:"required"`
+	Priority  int              `json:"priority" validate:"min=0,max=5"`
+	TTL       int              `json:"ttl,omitempty" validate:"min=0"` // секунды
+	NextRunAt *time.Time       `json:"next_run_at,omitempty"`
+	LastRunAt *time.Time       `json:"last_run_at,omitempty"`
+	CreatedAt time.Time        `json:"created_at"`
+}
+
+// RunKey - строковое представление запуска для сравнения в хранилищах.
+func RunKey(runAt *time.Time) string {
+	if runAt == nil {
+		return ""
+	}
+	return runAt.UTC().Format(time.RFC3339Nano)
+}
+
+func (j *RecurringJob) Validate() error {
+	if err := validator.New().Struct(j); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+
+	if j.StartAt != nil && j.EndAt != nil && !j.EndAt.After(*j.StartAt) {
+		return fmt.Errorf("%w: end_at должен быть позже start_at", ErrInvalidInput)
+	}
+
+	return nil
+}
+
+type RecurringJobStore interface {
+	Save(ctx context.Context, job *RecurringJob) error
+	FindByID(ctx context.Context, id string) (*RecurringJob, error)
+	FindAll(ctx context.Context) ([]*RecurringJob, error)
+	// Advance сохраняет задание, только если следующий запуск в хранилище
+	// всё ещё previous. false означает, что запуск уже забрал другой узел.
+	Advance(ctx context.Context, job *RecurringJob, previous *time.Time) (bool, error)
+	Delete(ctx context.Context, id string) error
+}
+
+type RecurringJobService interface {
+	Create(ctx context.Context, job *RecurringJob) error
+	Update(ctx context.Context, job *RecurringJob) error
+	Get(ctx context.Context, id string) (*RecurringJob, error)
+	List(ctx context.Context) ([]*RecurringJob, error)
+	Delete(ctx context.Context, id string) error
+	SetPaused(ctx context.Context, id string, paused bool) (*RecurringJob, error)
+}
@@ -0,0 +1,28 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// ScheduledNotification - уведомление, ожидающее отложенной доставки.
+type ScheduledNotification struct {
+	ID           string        `json:"id"`
+	UserID       string        `json:"user_id"`
+	SendAt       time.Time     `json:"send_at"`
+	Notification *Notification `json:"notification"`
+	CreatedAt    time.Time     `json:"created_at"`
+	// FallbackStep - номер (с 1) отложенного шага цепочки доставки; 0 - обычная отложенная отправка
+	FallbackStep int `json:"fallback_step,omitempty"`
+}
+
+type ScheduleStore interface {
+	Add(ctx context.Context, item *ScheduledNotification) error
+	// Claim возвращает элементы с SendAt <= now в порядке времени доставки
+	// и захватывает их на lease, чтобы другие узлы их не отправили.
+	// Элемент, не удалённый до истечения захвата, возвращается снова.
+	Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*ScheduledNotification, error)
+	FindByID(ctx context.Context, id string) (*ScheduledNotification, error)
+	FindByUserID(ctx context.Context, userID string) ([]*ScheduledNotification, error)
+	Remove(ctx context.Context, id string) error
+}
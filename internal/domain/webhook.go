@@ -0,0 +1,137 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+const EventNotificationCreated = "notification.created"
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookPending   WebhookDeliveryStatus = "pending"
+	WebhookSucceeded WebhookDeliveryStatus = "succeeded"
+	WebhookFailed    WebhookDeliveryStatus = "failed"
+)
+
+// Webhook - подписка внешнего сервиса на уведомления. Пустые Types и
+// UserIDs не ограничивают выборку.
+type Webhook struct {
+	ID          string             `json:"id"`
+	URL         string             `json:"url" validate:"required,url"`
+	Secret      string             `json:"secret,omitempty"`
+	Types       []NotificationType `json:"types,omitempty" validate:"dive,oneof=system alert message"`
+	UserIDs     []string           `json:"user_ids,omitempty"`
+	Description string             `json:"description,omitempty"`
+	CreatedAt   time.Time          `json:"created_at"`
+}
+
+func (w *Webhook) Validate() error {
+	validate := validator.New()
+	if err := validate.Struct(w); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+
+	if err := ValidatePublicURL(w.URL); err != nil {
+		return fmt.Errorf("url: %w", err)
+	}
+
+	return nil
+}
+
+func (w *Webhook) Matches(notification *Notification) bool {
+	if len(w.Types) > 0 {
+		matched := false
+		for _, notificationType := range w.Types {
+			if notificationType == notification.Type {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	if len(w.UserIDs) > 0 {
+		for _, userID := range w.UserIDs {
+			if userID == notification.UserID {
+				return true
+			}
+		}
+		return false
+	}
+
+	return true
+}
+
+// WebhookEvent - тело запроса к подписчику.
+type WebhookEvent struct {
+	Event        string        `json:"event"`
+	Notification *Notification `json:"notification"`
+}
+
+// WebhookDelivery - запись журнала доставки одного события подписчику.
+// Payload хранится как отправлен, чтобы повтор и replay были побайтно одинаковыми.
+type WebhookDelivery struct {
+	ID             string                `json:"id"`
+	WebhookID      string                `json:"webhook_id"`
+	NotificationID string                `json:"notification_id"`
+	UserID         string                `json:"user_id"`
+	Payload        json.RawMessage       `json:"payload"`
+	Status         WebhookDeliveryStatus `json:"status"`
+	Attempts       int                   `json:"attempts"`
+	LastStatusCode int                   `json:"last_status_code,omitempty"`
+	LastError      string                `json:"last_error,omitempty"`
+	NextAttemptAt  time.Time             `json:"next_attempt_at"`
+	ReplayOf       string                `json:"replay_of,omitempty"` // исходная доставка для повторной отправки вручную
+	CreatedAt      time.Time             `json:"created_at"`
+	UpdatedAt      time.Time             `json:"updated_at"`
+}
+
+type WebhookStore interface {
+	Save(ctx context.Context, webhook *Webhook) error
+	FindByID(ctx context.Context, id string) (*Webhook, error)
+	FindAll(ctx context.Context) ([]*Webhook, error)
+	Delete(ctx context.Context, id string) error
+}
+
+type WebhookDeliveryStore interface {
+	// Save добавляет доставку или обновляет существующую с тем же ID
+	Save(ctx context.Context, delivery *WebhookDelivery) error
+	FindByID(ctx context.Context, id string) (*WebhookDelivery, error)
+	// FindByWebhookID возвращает последние доставки подписки, начиная с новых
+	FindByWebhookID(ctx context.Context, webhookID string, limit int) ([]*WebhookDelivery, error)
+	// Claim захватывает на lease ожидающие доставки с NextAttemptAt <= now и
+	// возвращает их в порядке времени попытки. Захваченные доставки не
+	// выдаются повторно, пока не истечёт захват или не будет вызван Save,
+	// поэтому при общем хранилище каждую доставку отправляет один узел.
+	Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*WebhookDelivery, error)
+	// DeleteFinishedBefore удаляет завершённые доставки, созданные раньше before
+	DeleteFinishedBefore(ctx context.Context, before time.Time) (int, error)
+}
+
+// WebhookSender отправляет доставку подписчику и возвращает HTTP статус
+// ответа; 0 - ответ не получен.
+type WebhookSender interface {
+	Send(ctx context.Context, webhook *Webhook, delivery *WebhookDelivery) (int, error)
+}
+
+type WebhookService interface {
+	// Create регистрирует подписку и возвращает её вместе с секретом
+	Create(ctx context.Context, webhook *Webhook) error
+	List(ctx context.Context) ([]*Webhook, error)
+	Delete(ctx context.Context, id string) error
+	// Authenticate проверяет секрет подписки; для неизвестной подписки и
+	// неверного секрета возвращает ErrUnauthorized
+	Authenticate(ctx context.Context, webhookID string, secret string) error
+	Deliveries(ctx context.Context, webhookID string, limit int) ([]*WebhookDelivery, error)
+	// Replay ставит завершённую доставку в очередь повторно как новую запись
+	// журнала. Непустой webhookID ограничивает выбор доставками этой подписки.
+	Replay(ctx context.Context, webhookID string, deliveryID string) (*WebhookDelivery, error)
+}
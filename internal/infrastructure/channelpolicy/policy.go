@@ -0,0 +1,103 @@
+package channelpolicy
+
+import (
+	"context"
+	"errors"
+	"path"
+	"strings"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+)
+
+const (
+	DriverAllowAll = "allow_all"
+	DriverRules    = "rules"
+)
+
+// userPlaceholder в шаблоне канала заменяется идентификатором подписчика,
+// например "user:{userId}:*" разрешает только собственные каналы.
+const userPlaceholder = "{userId}"
+
+// AllowAllPolicy разрешает любому пользователю подписку на любой канал.
+type AllowAllPolicy struct{}
+
+func NewAllowAllPolicy() *AllowAllPolicy {
+	return &AllowAllPolicy{}
+}
+
+func (p *AllowAllPolicy) Authorize(ctx context.Context, userID string, channel string) error {
+	return nil
+}
+
+// Rule разрешает подписку на каналы, совпадающие с Pattern (синтаксис
+// path.Match), перечисленным пользователям и участникам групп. Правило
+// без Users и Groups открыто для всех.
+type Rule struct {
+	Pattern string
+	Users   []string
+	Groups  []string
+}
+
+// RulePolicy разрешает подписку, если её допускает хотя бы одно правило.
+// Каналы, не попавшие ни под одно правило, закрыты.
+type RulePolicy struct {
+	rules     []Rule
+	directory domain.GroupDirectory
+}
+
+func NewRulePolicy(rules []Rule, directory domain.GroupDirectory) *RulePolicy {
+	return &RulePolicy{
+		rules:     rules,
+		directory: directory,
+	}
+}
+
+func (p *RulePolicy) Authorize(ctx context.Context, userID string, channel string) error {
+	for _, rule := range p.rules {
+		pattern := strings.ReplaceAll(rule.Pattern, userPlaceholder, userID)
+		matched, err := path.Match(pattern, channel)
+		if err != nil || !matched {
+			continue
+		}
+
+		allowed, err := p.allows(ctx, rule, userID)
+		if err != nil {
+			return err
+		}
+		if allowed {
+			return nil
+		}
+	}
+
+	return domain.ErrUnauthorized
+}
+
+func (p *RulePolicy) allows(ctx context.Context, rule Rule, userID string) (bool, error) {
+	if len(rule.Users) == 0 && len(rule.Groups) == 0 {
+		return true, nil
+	}
+
+	for _, allowed := range rule.Users {
+		if allowed == userID {
+			return true, nil
+		}
+	}
+
+	for _, group := range rule.Groups {
+		members, err := p.directory.Members(ctx, group)
+		if errors.Is(err, domain.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+
+		for _, member := range members {
+			if member == userID {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
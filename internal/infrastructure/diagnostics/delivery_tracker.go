@@ -0,0 +1,154 @@
+package diagnostics
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+)
+
+type TrackerConfig struct {
+	MaxNotifications int
+	MaxEvents        int
+	UserHistory      int
+}
+
+type timelineEntry struct {
+	timeline *domain.DeliveryTimeline
+	element  *list.Element
+}
+
+// DeliveryTracker хранит ограниченную историю этапов доставки в памяти.
+// При превышении MaxNotifications вытесняются самые старые хронологии.
+type DeliveryTracker struct {
+	timelines map[string]*timelineEntry
+	order     *list.List
+	userIndex map[string][]string
+	config    *TrackerConfig
+	mutex     sync.RWMutex
+}
+
+func NewDeliveryTracker(config *TrackerConfig) *DeliveryTracker {
+	return &DeliveryTracker{
+		timelines: make(map[string]*timelineEntry),
+		order:     list.New(),
+		userIndex: make(map[string][]string),
+		config:    config,
+	}
+}
+
+func (t *DeliveryTracker) Track(notificationID string, userID string, stage domain.DeliveryStage, details string) {
+	if notificationID == "" {
+		return
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	entry, ok := t.timelines[notificationID]
+	if !ok {
+		entry = &timelineEntry{
+			timeline: &domain.DeliveryTimeline{
+				NotificationID: notificationID,
+				UserID:         userID,
+			},
+		}
+		entry.element = t.order.PushBack(notificationID)
+		t.timelines[notificationID] = entry
+
+		if userID != "" {
+			t.indexUser(userID, notificationID)
+		}
+
+		t.evict()
+	} else if entry.timeline.UserID == "" && userID != "" {
+		entry.timeline.UserID = userID
+		t.indexUser(userID, notificationID)
+	}
+
+	if len(entry.timeline.Events) >= t.config.MaxEvents {
+		return
+	}
+
+	entry.timeline.Events = append(entry.timeline.Events, domain.DeliveryEvent{
+		Stage:     stage,
+		Timestamp: time.Now(),
+		Details:   details,
+	})
+}
+
+func (t *DeliveryTracker) Timeline(notificationID string) (*domain.DeliveryTimeline, error) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	entry, ok := t.timelines[notificationID]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+
+	return copyTimeline(entry.timeline), nil
+}
+
+func (t *DeliveryTracker) UserTimelines(userID string, limit int) ([]*domain.DeliveryTimeline, error) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	ids := t.userIndex[userID]
+	if limit <= 0 || limit > len(ids) {
+		limit = len(ids)
+	}
+
+	result := make([]*domain.DeliveryTimeline, 0, limit)
+	for i := len(ids) - 1; i >= 0 && len(result) < limit; i-- {
+		if entry, ok := t.timelines[ids[i]]; ok {
+			result = append(result, copyTimeline(entry.timeline))
+		}
+	}
+
+	return result, nil
+}
+
+func (t *DeliveryTracker) indexUser(userID string, notificationID string) {
+	ids := append(t.userIndex[userID], notificationID)
+	if len(ids) > t.config.UserHistory {
+		ids = append([]string(nil), ids[len(ids)-t.config.UserHistory:]...)
+	}
+	t.userIndex[userID] = ids
+}
+
+func (t *DeliveryTracker) evict() {
+	for t.order.Len() > t.config.MaxNotifications {
+		oldest := t.order.Front()
+		id := oldest.Value.(string)
+		t.order.Remove(oldest)
+
+		entry := t.timelines[id]
+		delete(t.timelines, id)
+
+		userID := entry.timeline.UserID
+		ids := t.userIndex[userID]
+		for i, existing := range ids {
+			if existing == id {
+				ids = append(ids[:i], ids[i+1:]...)
+				break
+			}
+		}
+		if len(ids) == 0 {
+			delete(t.userIndex, userID)
+		} else {
+			t.userIndex[userID] = ids
+		}
+	}
+}
+
+func copyTimeline(timeline *domain.DeliveryTimeline) *domain.DeliveryTimeline {
+	events := make([]domain.DeliveryEvent, len(timeline.Events))
+	copy(events, timeline.Events)
+
+	return &domain.DeliveryTimeline{
+		NotificationID: timeline.NotificationID,
+		UserID:         timeline.UserID,
+		Events:         events,
+	}
+}
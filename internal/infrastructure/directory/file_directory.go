@@ -0,0 +1,85 @@
+package directory
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/anatoly_dev/go-ws-notifications/pkg/logger"
+	"gopkg.in/yaml.v3"
+)
+
+// FileDirectory читает состав групп из YAML-файла вида
+// "group: [user1, user2]" и перечитывает его при изменении.
+type FileDirectory struct {
+	path      string
+	logger    *logger.Logger
+	mutex     sync.Mutex
+	modTime   time.Time
+	directory *StaticDirectory
+}
+
+func NewFileDirectory(path string, logger *logger.Logger) (*FileDirectory, error) {
+	d := &FileDirectory{
+		path:   path,
+		logger: logger,
+	}
+
+	if _, err := d.current(); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+func (d *FileDirectory) Members(ctx context.Context, group string) ([]string, error) {
+	directory, err := d.current()
+	if err != nil {
+		return nil, err
+	}
+
+	return directory.Members(ctx, group)
+}
+
+// current возвращает актуальный состав групп. Если файл повреждён после
+// изменения, продолжает использоваться последняя успешно прочитанная версия.
+func (d *FileDirectory) current() (*StaticDirectory, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	info, err := os.Stat(d.path)
+	if err != nil {
+		if d.directory != nil {
+			d.logger.WithError(err).Warn("Файл групп недоступен, используется прежний состав")
+			return d.directory, nil
+		}
+		return nil, fmt.Errorf("ошибка чтения файла групп: %w", err)
+	}
+
+	if d.directory != nil && info.ModTime().Equal(d.modTime) {
+		return d.directory, nil
+	}
+
+	data, err := os.ReadFile(d.path)
+	if err == nil {
+		var groups map[string][]string
+		if err = yaml.Unmarshal(data, &groups); err == nil {
+			d.directory = NewStaticDirectory(groups)
+			d.modTime = info.ModTime()
+			d.logger.WithFields(map[string]interface{}{
+				"path":   d.path,
+				"groups": len(groups),
+			}).Info("Загружен состав групп")
+			return d.directory, nil
+		}
+	}
+
+	if d.directory != nil {
+		d.logger.WithError(err).Warn("Ошибка чтения файла групп, используется прежний состав")
+		return d.directory, nil
+	}
+
+	return nil, fmt.Errorf("ошибка чтения файла групп: %w", err)
+}
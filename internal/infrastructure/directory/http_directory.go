@@ -0,0 +1,92 @@
+package directory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+)
+
+type groupEntry struct {
+	members   []string
+	expiresAt time.Time
+}
+
+// HTTPDirectory получает состав группы у внешнего сервиса:
+// GET <url>?group=<name> -> {"members": ["user1", "user2"]}, 404 - неизвестная группа.
+// Ответы кэшируются на cacheTTL.
+type HTTPDirectory struct {
+	url      string
+	client   *http.Client
+	cacheTTL time.Duration
+	cache    map[string]groupEntry
+	mutex    sync.Mutex
+}
+
+func NewHTTPDirectory(endpoint string, timeout time.Duration, cacheTTL time.Duration) *HTTPDirectory {
+	return &HTTPDirectory{
+		url:      endpoint,
+		client:   &http.Client{Timeout: timeout},
+		cacheTTL: cacheTTL,
+		cache:    make(map[string]groupEntry),
+	}
+}
+
+func (d *HTTPDirectory) Members(ctx context.Context, group string) ([]string, error) {
+	now := time.Now()
+
+	d.mutex.Lock()
+	entry, ok := d.cache[group]
+	d.mutex.Unlock()
+	if ok && now.Before(entry.expiresAt) {
+		return entry.members, nil
+	}
+
+	members, err := d.fetch(ctx, group)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.cacheTTL > 0 {
+		d.mutex.Lock()
+		d.cache[group] = groupEntry{members: members, expiresAt: now.Add(d.cacheTTL)}
+		d.mutex.Unlock()
+	}
+
+	return members, nil
+}
+
+func (d *HTTPDirectory) fetch(ctx context.Context, group string) ([]string, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, d.url+"?group="+url.QueryEscape(group), nil)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка запроса состава группы: %w", err)
+	}
+	request.Header.Set("Accept", "application/json")
+
+	response, err := d.client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка запроса состава группы: %w", err)
+	}
+	defer response.Body.Close()
+
+	switch {
+	case response.StatusCode == http.StatusNotFound:
+		return nil, domain.ErrNotFound
+	case response.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("сервис групп вернул статус %d", response.StatusCode)
+	}
+
+	var body struct {
+		Members []string `json:"members"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("ошибка чтения состава группы: %w", err)
+	}
+
+	return body.Members, nil
+}
@@ -0,0 +1,60 @@
+package directory
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+)
+
+const (
+	DriverStatic = "static"
+	DriverFile   = "file"
+	DriverHTTP   = "http"
+)
+
+// StaticDirectory хранит состав групп, заданный в конфигурации. Имена групп
+// регистронезависимы. Если группа all не задана явно, в неё входят
+// участники всех групп.
+type StaticDirectory struct {
+	groups map[string][]string
+}
+
+func NewStaticDirectory(groups map[string][]string) *StaticDirectory {
+	normalized := make(map[string][]string, len(groups))
+	for name, members := range groups {
+		normalized[strings.ToLower(name)] = members
+	}
+
+	if _, ok := normalized[domain.GroupAll]; !ok {
+		normalized[domain.GroupAll] = unionMembers(normalized)
+	}
+
+	return &StaticDirectory{groups: normalized}
+}
+
+func (d *StaticDirectory) Members(ctx context.Context, group string) ([]string, error) {
+	members, ok := d.groups[strings.ToLower(group)]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+
+	return members, nil
+}
+
+func unionMembers(groups map[string][]string) []string {
+	seen := map[string]bool{}
+	members := []string{}
+	for _, groupMembers := range groups {
+		for _, userID := range groupMembers {
+			if !seen[userID] {
+				seen[userID] = true
+				members = append(members, userID)
+			}
+		}
+	}
+
+	sort.Strings(members)
+	return members
+}
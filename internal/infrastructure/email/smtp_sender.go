@@ -0,0 +1,145 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+)
+
+// Режимы шифрования соединения с SMTP-сервером.
+const (
+	TLSNone     = "none"
+	TLSStartTLS = "starttls"
+	// TLSImplicit - соединение шифруется сразу, обычно порт 465
+	TLSImplicit = "tls"
+)
+
+type Config struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	TLS      string
+	Timeout  time.Duration
+}
+
+// SMTPSender отправляет письма через SMTP-сервер, открывая соединение на
+// каждое письмо.
+type SMTPSender struct {
+	config *Config
+}
+
+func NewSMTPSender(config *Config) *SMTPSender {
+	return &SMTPSender{config: config}
+}
+
+func (s *SMTPSender) Send(ctx context.Context, message *domain.EmailMessage) error {
+	address := net.JoinHostPort(s.config.Host, strconv.Itoa(s.config.Port))
+
+	dialer := &net.Dialer{Timeout: s.config.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return fmt.Errorf("ошибка подключения к SMTP-серверу: %w", err)
+	}
+
+	// Таймаут ограничивает весь диалог, а не только подключение
+	deadline := time.Now().Add(s.config.Timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	_ = conn.SetDeadline(deadline)
+
+	if s.config.TLS == TLSImplicit {
+		conn = tls.Client(conn, &tls.Config{ServerName: s.config.Host})
+	}
+
+	client, err := smtp.NewClient(conn, s.config.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("ошибка подключения к SMTP-серверу: %w", err)
+	}
+	defer client.Close()
+
+	if s.config.TLS == TLSStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("SMTP-сервер не поддерживает STARTTLS")
+		}
+		if err := client.StartTLS(&tls.Config{ServerName: s.config.Host}); err != nil {
+			return fmt.Errorf("ошибка STARTTLS: %w", err)
+		}
+	}
+
+	if s.config.Username != "" {
+		// PlainAuth отказывается передавать пароль без TLS, кроме localhost
+		auth := smtp.PlainAuth("", s.config.Username, s.config.Password, s.config.Host)
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("ошибка аутентификации на SMTP-сервере: %w", err)
+		}
+	}
+
+	data, err := s.compose(message)
+	if err != nil {
+		return fmt.Errorf("ошибка формирования письма: %w", err)
+	}
+
+	if err := client.Mail(s.config.From); err != nil {
+		return fmt.Errorf("SMTP-сервер отклонил отправителя: %w", err)
+	}
+
+	if err := client.Rcpt(message.To); err != nil {
+		return fmt.Errorf("SMTP-сервер отклонил получателя: %w", err)
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("ошибка отправки письма: %w", err)
+	}
+
+	if _, err := writer.Write(data); err != nil {
+		writer.Close()
+		return fmt.Errorf("ошибка отправки письма: %w", err)
+	}
+
+	// Close дожидается ответа сервера о приёме письма
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("SMTP-сервер не принял письмо: %w", err)
+	}
+
+	return client.Quit()
+}
+
+func (s *SMTPSender) compose(message *domain.EmailMessage) ([]byte, error) {
+	// Перевод строки в теме позволил бы дописать произвольные заголовки
+	subject := strings.NewReplacer("\r", " ", "\n", " ").Replace(message.Subject)
+
+	var buffer bytes.Buffer
+	buffer.WriteString("From: " + s.config.From + "\r\n")
+	buffer.WriteString("To: " + message.To + "\r\n")
+	buffer.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", subject) + "\r\n")
+	buffer.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	buffer.WriteString("MIME-Version: 1.0\r\n")
+	buffer.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buffer.WriteString("Content-Transfer-Encoding: quoted-printable\r\n")
+	buffer.WriteString("\r\n")
+
+	body := quotedprintable.NewWriter(&buffer)
+	if _, err := body.Write([]byte(message.Body)); err != nil {
+		return nil, err
+	}
+	if err := body.Close(); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
@@ -0,0 +1,249 @@
+package email
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+)
+
+// sinkSession - то, что SMTP-приёмник получил за одно соединение.
+type sinkSession struct {
+	mailFrom string
+	rcptTo   []string
+	data     []byte
+}
+
+// newSMTPSink запускает минимальный SMTP-сервер на 127.0.0.1, который
+// принимает одно письмо. rcptCode - ответ на RCPT TO.
+func newSMTPSink(t *testing.T, rcptCode int) (string, int, <-chan sinkSession) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	sessions := make(chan sinkSession, 1)
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+		text := textproto.NewConn(conn)
+		session := sinkSession{}
+		defer func() { sessions <- session }()
+
+		reply := func(code int, message string) {
+			_ = text.PrintfLine("%d %s", code, message)
+		}
+
+		reply(220, "sink ready")
+		for {
+			line, err := text.ReadLine()
+			if err != nil {
+				return
+			}
+
+			verb, argument, _ := strings.Cut(line, " ")
+			switch strings.ToUpper(verb) {
+			case "EHLO", "HELO":
+				reply(250, "sink")
+			case "MAIL":
+				session.mailFrom = argument
+				reply(250, "ok")
+			case "RCPT":
+				session.rcptTo = append(session.rcptTo, argument)
+				if rcptCode != 250 {
+					reply(rcptCode, "no such user")
+					continue
+				}
+				reply(250, "ok")
+			case "DATA":
+				reply(354, "go ahead")
+				data, err := io.ReadAll(text.DotReader())
+				if err != nil {
+					return
+				}
+				session.data = data
+				reply(250, "queued")
+			case "RSET", "NOOP":
+				reply(250, "ok")
+			case "QUIT":
+				reply(221, "bye")
+				return
+			default:
+				reply(502, "not implemented")
+			}
+		}
+	}()
+
+	address := listener.Addr().(*net.TCPAddr)
+	return address.IP.String(), address.Port, sessions
+}
+
+func TestSMTPSenderSend(t *testing.T) {
+	tests := []struct {
+		name        string
+		message     *domain.EmailMessage
+		wantSubject string
+		wantBody    string
+		// Q-кодирование применяется, только если в теме есть не-ASCII символы
+		qEncoded bool
+	}{
+		{
+			name:        "ascii",
+			message:     &domain.EmailMessage{To: "user@example.com", Subject: "Build finished", Body: "All green."},
+			wantSubject: "Build finished",
+			wantBody:    "All green.",
+		},
+		{
+			name: "utf-8 and long lines",
+			message: &domain.EmailMessage{
+				To:      "пользователь@example.com",
+				Subject: "Новое сообщение от службы поддержки",
+				Body:    "Здравствуйте! " + strings.Repeat("Длинная строка без переносов. ", 10) + "\nИтого = 100%",
+			},
+			wantSubject: "Новое сообщение от службы поддержки",
+			wantBody:    "Здравствуйте! " + strings.Repeat("Длинная строка без переносов. ", 10) + "\nИтого = 100%",
+			qEncoded:    true,
+		},
+		{
+			name:        "header injection in subject",
+			message:     &domain.EmailMessage{To: "user@example.com", Subject: "Hi\r\nBcc: victim@example.com", Body: "."},
+			wantSubject: "Hi  Bcc: victim@example.com",
+			wantBody:    ".",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			host, port, sessions := newSMTPSink(t, 250)
+			sender := NewSMTPSender(&Config{
+				Host:    host,
+				Port:    port,
+				From:    "noreply@example.com",
+				TLS:     TLSNone,
+				Timeout: 5 * time.Second,
+			})
+
+			if err := sender.Send(context.Background(), tt.message); err != nil {
+				t.Fatalf("Send: %v", err)
+			}
+
+			session := <-sessions
+			if !strings.HasPrefix(session.mailFrom, "FROM:<noreply@example.com>") {
+				t.Errorf("MAIL %q, want FROM:<noreply@example.com>", session.mailFrom)
+			}
+			if len(session.rcptTo) != 1 || session.rcptTo[0] != "TO:<"+tt.message.To+">" {
+				t.Errorf("RCPT %q, want TO:<%s>", session.rcptTo, tt.message.To)
+			}
+
+			message, err := mail.ReadMessage(bytes.NewReader(session.data))
+			if err != nil {
+				t.Fatalf("mail.ReadMessage: %v", err)
+			}
+
+			header := message.Header
+			if got := header.Get("From"); got != "noreply@example.com" {
+				t.Errorf("From = %q", got)
+			}
+			if got := header.Get("To"); got != tt.message.To {
+				t.Errorf("To = %q, want %q", got, tt.message.To)
+			}
+			if got := header.Get("Bcc"); got != "" {
+				t.Errorf("Bcc = %q, want none", got)
+			}
+			if got := header.Get("Content-Type"); got != "text/plain; charset=utf-8" {
+				t.Errorf("Content-Type = %q", got)
+			}
+			if got := header.Get("Content-Transfer-Encoding"); got != "quoted-printable" {
+				t.Errorf("Content-Transfer-Encoding = %q", got)
+			}
+			if _, err := mail.ParseDate(header.Get("Date")); err != nil {
+				t.Errorf("Date %q: %v", header.Get("Date"), err)
+			}
+
+			rawSubject := header.Get("Subject")
+			if strings.HasPrefix(rawSubject, "=?utf-8?q?") != tt.qEncoded {
+				t.Errorf("Subject %q, Q-encoded = %v", rawSubject, tt.qEncoded)
+			}
+			subject, err := new(mime.WordDecoder).DecodeHeader(rawSubject)
+			if err != nil {
+				t.Fatalf("DecodeHeader(%q): %v", rawSubject, err)
+			}
+			if subject != tt.wantSubject {
+				t.Errorf("Subject = %q, want %q", subject, tt.wantSubject)
+			}
+
+			encoded, err := io.ReadAll(message.Body)
+			if err != nil {
+				t.Fatalf("чтение тела: %v", err)
+			}
+			scanner := bufio.NewScanner(bytes.NewReader(encoded))
+			for scanner.Scan() {
+				if len(scanner.Text()) > 76 {
+					t.Errorf("строка тела длиннее 76 символов: %q", scanner.Text())
+				}
+			}
+
+			body, err := io.ReadAll(quotedprintable.NewReader(bytes.NewReader(encoded)))
+			if err != nil {
+				t.Fatalf("декодирование тела: %v", err)
+			}
+			// Последний перевод строки дописывает SMTP-клиент при завершении DATA
+			if got := strings.TrimSuffix(string(body), "\n"); got != tt.wantBody {
+				t.Errorf("тело = %q, want %q", got, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestSMTPSenderRejectedRecipient(t *testing.T) {
+	host, port, sessions := newSMTPSink(t, 550)
+	sender := NewSMTPSender(&Config{
+		Host:    host,
+		Port:    port,
+		From:    "noreply@example.com",
+		TLS:     TLSNone,
+		Timeout: 5 * time.Second,
+	})
+
+	err := sender.Send(context.Background(), &domain.EmailMessage{To: "nobody@example.com", Subject: "s", Body: "b"})
+	if err == nil || !strings.Contains(err.Error(), "получателя") {
+		t.Fatalf("Send = %v, want recipient rejection", err)
+	}
+
+	<-sessions
+}
+
+func TestSMTPSenderStartTLSRequired(t *testing.T) {
+	host, port, _ := newSMTPSink(t, 250)
+	sender := NewSMTPSender(&Config{
+		Host:    host,
+		Port:    port,
+		From:    "noreply@example.com",
+		TLS:     TLSStartTLS,
+		Timeout: 5 * time.Second,
+	})
+
+	err := sender.Send(context.Background(), &domain.EmailMessage{To: "user@example.com", Subject: "s", Body: "b"})
+	if err == nil || !strings.Contains(err.Error(), "STARTTLS") {
+		t.Fatalf("Send = %v, want STARTTLS error", err)
+	}
+}
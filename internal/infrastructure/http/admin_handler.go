@@ -0,0 +1,495 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+	"github.com/anatoly_dev/go-ws-notifications/pkg/logger"
+)
+
+const defaultDeliveriesLimit = 20
+
+// BackupProvider реализуется хранилищами, поддерживающими онлайн-резервное копирование.
+type BackupProvider interface {
+	Backup(w io.Writer) (int64, error)
+}
+
+type AdminHandler struct {
+	tracker   domain.DeliveryTracker
+	recurring domain.RecurringJobService
+	broadcast domain.BroadcastService
+	channels  domain.ChannelService
+	webhooks  domain.WebhookService
+	backup    BackupProvider
+	logger    *logger.Logger
+}
+
+func NewAdminHandler(
+	tracker domain.DeliveryTracker,
+	recurring domain.RecurringJobService,
+	broadcast domain.BroadcastService,
+	channels domain.ChannelService,
+	webhooks domain.WebhookService,
+	backup BackupProvider,
+	logger *logger.Logger,
+) *AdminHandler {
+	return &AdminHandler{
+		tracker:   tracker,
+		recurring: recurring,
+		broadcast: broadcast,
+		channels:  channels,
+		webhooks:  webhooks,
+		backup:    backup,
+		logger:    logger,
+	}
+}
+
+func (h *AdminHandler) Register(router *http.ServeMux) {
+	// GET возвращает текущий уровень, PUT {"level":"debug"} меняет его
+	router.Handle("/admin/log/level", h.logger.AtomicLevel())
+	router.HandleFunc("/admin/log/debug-users", h.HandleDebugUsers)
+	router.HandleFunc("/admin/deliveries", h.HandleDeliveries)
+	router.HandleFunc("/admin/storage/backup", h.HandleBackup)
+	router.HandleFunc("/admin/recurring", h.HandleRecurring)
+	router.HandleFunc("/admin/recurring/pause", h.HandleRecurringPause)
+	router.HandleFunc("/admin/recurring/resume", h.HandleRecurringPause)
+	router.HandleFunc("/admin/broadcasts", h.HandleBroadcasts)
+	router.HandleFunc("/admin/channels/publish", h.HandleChannelPublish)
+	router.HandleFunc("/admin/webhooks", h.HandleWebhooks)
+	router.HandleFunc("/admin/webhooks/deliveries", h.HandleWebhookDeliveries)
+	router.HandleFunc("/admin/webhooks/replay", h.HandleWebhookReplay)
+}
+
+// HandleDebugUsers управляет списком пользователей, для которых
+// отладочные логи пишутся независимо от глобального уровня.
+func (h *AdminHandler) HandleDebugUsers(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost, http.MethodDelete:
+		userID := r.URL.Query().Get("userId")
+		if userID == "" {
+			http.Error(w, "User ID required", http.StatusBadRequest)
+			return
+		}
+
+		enabled := r.Method == http.MethodPost
+		h.logger.SetUserDebug(userID, enabled)
+		h.logger.WithFields(map[string]interface{}{
+			"debugUserID": userID,
+			"enabled":     enabled,
+		}).Info("Изменено отладочное логирование пользователя")
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"debug_users": h.logger.DebugUsers(),
+	})
+}
+
+// HandleDeliveries возвращает хронологию доставки уведомления (?notificationId=)
+// или последних уведомлений пользователя (?userId=&limit=).
+func (h *AdminHandler) HandleDeliveries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+
+	if notificationID := query.Get("notificationId"); notificationID != "" {
+		timeline, err := h.tracker.Timeline(notificationID)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, timeline)
+		return
+	}
+
+	userID := query.Get("userId")
+	if userID == "" {
+		http.Error(w, "notificationId or userId required", http.StatusBadRequest)
+		return
+	}
+
+	limit := defaultDeliveriesLimit
+	if value := query.Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	timelines, err := h.tracker.UserTimelines(userID, limit)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"user_id":       userID,
+		"notifications": timelines,
+	})
+}
+
+// HandleBackup отдаёт снимок встроенного хранилища, не останавливая сервис.
+func (h *AdminHandler) HandleBackup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if h.backup == nil {
+		http.Error(w, "Backup is not supported by the storage driver", http.StatusNotImplemented)
+		return
+	}
+
+	// Снимок сначала пишется во временный файл: ошибка посреди записи
+	// должна вернуть 500, а не обрезанный файл со статусом 200
+	file, err := os.CreateTemp("", "notifications-backup-*.db")
+	if err != nil {
+		h.logger.WithError(err).Error("Ошибка создания временного файла резервной копии")
+		writeError(w, err)
+		return
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	written, err := h.backup.Backup(file)
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		h.logger.WithError(err).Error("Ошибка создания резервной копии хранилища")
+		writeError(w, err)
+		return
+	}
+
+	filename := fmt.Sprintf("notifications-%s.db", time.Now().UTC().Format("20060102T150405Z"))
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	w.Header().Set("Content-Length", strconv.FormatInt(written, 10))
+
+	if _, err := io.Copy(w, file); err != nil {
+		h.logger.WithError(err).Error("Ошибка передачи резервной копии хранилища")
+		return
+	}
+
+	h.logger.WithField("bytes", written).Info("Создана резервная копия хранилища")
+}
+
+// HandleRecurring управляет периодическими объявлениями: GET возвращает
+// список или одно задание (?id=), POST создаёт, PUT ?id= заменяет, DELETE ?id= удаляет.
+func (h *AdminHandler) HandleRecurring(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+
+	switch r.Method {
+	case http.MethodGet:
+		if id != "" {
+			job, err := h.recurring.Get(r.Context(), id)
+			if err != nil {
+				writeError(w, err)
+				return
+			}
+			writeJSON(w, http.StatusOK, job)
+			return
+		}
+
+		jobs, err := h.recurring.List(r.Context())
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"jobs": jobs,
+		})
+	case http.MethodPost, http.MethodPut:
+		var job domain.RecurringJob
+		if err := json.NewDecoder(r.Body).Decode(&job); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		var err error
+		status := http.StatusOK
+		if r.Method == http.MethodPost {
+			err = h.recurring.Create(r.Context(), &job)
+			status = http.StatusCreated
+		} else {
+			if id == "" {
+				http.Error(w, "ID required", http.StatusBadRequest)
+				return
+			}
+			job.ID = id
+			err = h.recurring.Update(r.Context(), &job)
+		}
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		writeJSON(w, status, job)
+	case http.MethodDelete:
+		if id == "" {
+			http.Error(w, "ID required", http.StatusBadRequest)
+			return
+		}
+
+		if err := h.recurring.Delete(r.Context(), id); err != nil {
+			writeError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleRecurringPause приостанавливает (/pause) или возобновляет (/resume) задание ?id=.
+func (h *AdminHandler) HandleRecurringPause(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		http.Error(w, "ID required", http.StatusBadRequest)
+		return
+	}
+
+	paused := r.URL.Path == "/admin/recurring/pause"
+
+	job, err := h.recurring.SetPaused(r.Context(), id, paused)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, job)
+}
+
+// HandleBroadcasts управляет объявлениями: GET возвращает действующие,
+// POST публикует новое, DELETE ?id= отзывает.
+func (h *AdminHandler) HandleBroadcasts(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		broadcasts, err := h.broadcast.List(r.Context())
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"broadcasts": broadcasts,
+		})
+	case http.MethodPost:
+		var broadcast domain.Broadcast
+		if err := json.NewDecoder(r.Body).Decode(&broadcast); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		delivered, err := h.broadcast.Send(r.Context(), &broadcast)
+		if errors.Is(err, domain.ErrExpired) {
+			http.Error(w, "Broadcast already expired", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		writeJSON(w, http.StatusCreated, map[string]interface{}{
+			"broadcast": broadcast,
+			"clients":   delivered,
+		})
+	case http.MethodDelete:
+		id := r.URL.Query().Get("id")
+		if id == "" {
+			http.Error(w, "ID required", http.StatusBadRequest)
+			return
+		}
+
+		if err := h.broadcast.Delete(r.Context(), id); err != nil {
+			writeError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleChannelPublish публикует сообщение всем сокетам, подписанным на канал.
+// Публикация доступна только на административном порту, как и объявления.
+func (h *AdminHandler) HandleChannelPublish(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var message domain.ChannelMessage
+	if err := json.NewDecoder(r.Body).Decode(&message); err != nil {
+		writeError(w, domain.ErrInvalidInput)
+		return
+	}
+
+	delivered, err := h.channels.Publish(r.Context(), &message)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, map[string]interface{}{
+		"id":          message.ID,
+		"channel":     message.Channel,
+		"subscribers": delivered,
+	})
+}
+
+// HandleWebhooks управляет подписками внешних сервисов: GET возвращает
+// список без секретов, POST регистрирует и возвращает секрет, DELETE ?id= удаляет.
+func (h *AdminHandler) HandleWebhooks(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		webhooks, err := h.webhooks.List(r.Context())
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"webhooks": webhooks,
+		})
+	case http.MethodPost:
+		var webhook domain.Webhook
+		if err := json.NewDecoder(r.Body).Decode(&webhook); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		if err := h.webhooks.Create(r.Context(), &webhook); err != nil {
+			writeError(w, err)
+			return
+		}
+
+		writeJSON(w, http.StatusCreated, webhook)
+	case http.MethodDelete:
+		id := r.URL.Query().Get("id")
+		if id == "" {
+			http.Error(w, "ID required", http.StatusBadRequest)
+			return
+		}
+
+		if err := h.webhooks.Delete(r.Context(), id); err != nil {
+			writeError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleWebhookDeliveries возвращает журнал доставок подписки (?webhookId=&limit=), начиная с новых.
+func (h *AdminHandler) HandleWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+
+	webhookID := query.Get("webhookId")
+	if webhookID == "" {
+		http.Error(w, "webhookId required", http.StatusBadRequest)
+		return
+	}
+
+	limit, ok := deliveriesLimit(query.Get("limit"))
+	if !ok {
+		http.Error(w, "Invalid limit", http.StatusBadRequest)
+		return
+	}
+
+	deliveries, err := h.webhooks.Deliveries(r.Context(), webhookID, limit)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"webhook_id": webhookID,
+		"deliveries": deliveries,
+	})
+}
+
+// HandleWebhookReplay ставит завершённую доставку ?id= в очередь повторно.
+func (h *AdminHandler) HandleWebhookReplay(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		http.Error(w, "ID required", http.StatusBadRequest)
+		return
+	}
+
+	delivery, err := h.webhooks.Replay(r.Context(), "", id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, delivery)
+}
+
+// deliveriesLimit разбирает ?limit= журнала доставок; пустое значение -
+// размер по умолчанию.
+func deliveriesLimit(value string) (int, bool) {
+	if value == "" {
+		return defaultDeliveriesLimit, true
+	}
+
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit <= 0 {
+		return 0, false
+	}
+
+	return limit, true
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, domain.ErrInvalidInput):
+		status = http.StatusBadRequest
+	case errors.Is(err, domain.ErrUnauthorized):
+		status = http.StatusForbidden
+	}
+
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
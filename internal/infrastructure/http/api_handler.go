@@ -0,0 +1,373 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+	"github.com/anatoly_dev/go-ws-notifications/pkg/logger"
+)
+
+// APIHandler обслуживает клиентский REST API. Пользователь определяется
+// параметром userId, как и при подключении к /ws.
+type APIHandler struct {
+	notificationSvc domain.NotificationService
+	preferenceSvc   domain.PreferenceService
+	pushSvc         domain.PushService
+	webhookSvc      domain.WebhookService
+	logger          *logger.Logger
+}
+
+func NewAPIHandler(
+	notificationSvc domain.NotificationService,
+	preferenceSvc domain.PreferenceService,
+	pushSvc domain.PushService,
+	webhookSvc domain.WebhookService,
+	logger *logger.Logger,
+) *APIHandler {
+	return &APIHandler{
+		notificationSvc: notificationSvc,
+		preferenceSvc:   preferenceSvc,
+		pushSvc:         pushSvc,
+		webhookSvc:      webhookSvc,
+		logger:          logger,
+	}
+}
+
+func (h *APIHandler) Register(router *http.ServeMux) {
+	router.HandleFunc("/api/notifications", h.HandleHistory)
+	router.HandleFunc("/api/scheduled", h.HandleScheduled)
+	router.HandleFunc("/api/preferences", h.HandlePreferences)
+	router.HandleFunc("/api/push/key", h.HandlePushKey)
+	router.HandleFunc("/api/push/subscriptions", h.HandlePushSubscriptions)
+	router.HandleFunc("/api/webhooks/deliveries", h.HandleWebhookDeliveries)
+	router.HandleFunc("/api/webhooks/replay", h.HandleWebhookReplay)
+}
+
+// HandleHistory возвращает сохранённые уведомления пользователя.
+func (h *APIHandler) HandleHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := r.URL.Query().Get("userId")
+	if userID == "" {
+		http.Error(w, "User ID required", http.StatusBadRequest)
+		return
+	}
+
+	notifications, err := h.notificationSvc.History(r.Context(), userID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"user_id":       userID,
+		"notifications": notifications,
+	})
+}
+
+// HandleScheduled возвращает отложенные уведомления пользователя (GET)
+// или отменяет одно из них (DELETE ?id=).
+func (h *APIHandler) HandleScheduled(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	userID := query.Get("userId")
+	if userID == "" {
+		http.Error(w, "User ID required", http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		items, err := h.notificationSvc.ListScheduled(r.Context(), userID)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"user_id":   userID,
+			"scheduled": items,
+		})
+	case http.MethodDelete:
+		id := query.Get("id")
+		if id == "" {
+			http.Error(w, "ID required", http.StatusBadRequest)
+			return
+		}
+
+		if err := h.notificationSvc.CancelScheduled(r.Context(), id, userID); err != nil {
+			writeError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// HandlePreferences возвращает (GET) или заменяет (PUT) настройки уведомлений
+// пользователя. PATCH меняет одну настройку: {"type":"message","enabled":false},
+// {"category":"marketing","enabled":false} или {"quiet_hours":{...}} (null отключает).
+// {"email":"..."} отправляет на адрес код подтверждения, {"email_code":"..."}
+// подтверждает адрес; до этого канал email адрес не использует.
+func (h *APIHandler) HandlePreferences(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("userId")
+	if userID == "" {
+		http.Error(w, "User ID required", http.StatusBadRequest)
+		return
+	}
+
+	var preferences *domain.Preferences
+	var err error
+
+	switch r.Method {
+	case http.MethodGet:
+		preferences, err = h.preferenceSvc.Get(r.Context(), userID)
+	case http.MethodPut:
+		preferences = &domain.Preferences{}
+		if err := json.NewDecoder(r.Body).Decode(preferences); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		preferences.UserID = userID
+		err = h.preferenceSvc.Replace(r.Context(), preferences)
+	case http.MethodPatch:
+		var change struct {
+			Type       domain.NotificationType `json:"type"`
+			Category   string                  `json:"category"`
+			Enabled    *bool                   `json:"enabled"`
+			QuietHours json.RawMessage         `json:"quiet_hours"`
+			Email      *string                 `json:"email"`
+			EmailCode  string                  `json:"email_code"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&change); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		if change.QuietHours != nil {
+			var quietHours *domain.QuietHours
+			if err := json.Unmarshal(change.QuietHours, &quietHours); err != nil {
+				http.Error(w, "Invalid quiet_hours", http.StatusBadRequest)
+				return
+			}
+			preferences, err = h.preferenceSvc.SetQuietHours(r.Context(), userID, quietHours)
+			break
+		}
+
+		if change.Email != nil {
+			preferences, err = h.preferenceSvc.SetEmail(r.Context(), userID, *change.Email)
+			break
+		}
+
+		if change.EmailCode != "" {
+			preferences, err = h.preferenceSvc.VerifyEmail(r.Context(), userID, change.EmailCode)
+			break
+		}
+
+		if change.Enabled == nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		if change.Type != "" {
+			preferences, err = h.preferenceSvc.SetType(r.Context(), userID, change.Type, *change.Enabled)
+		} else {
+			preferences, err = h.preferenceSvc.SetCategory(r.Context(), userID, change.Category, *change.Enabled)
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, preferences)
+}
+
+// HandlePushKey возвращает открытый VAPID-ключ для PushManager.subscribe.
+func (h *APIHandler) HandlePushKey(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	key := h.pushSvc.PublicKey()
+	if key == "" {
+		writeError(w, domain.ErrNotFound)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"public_key": key})
+}
+
+// HandlePushSubscriptions возвращает push-подписки пользователя без ключей
+// (GET), добавляет подписку - результат PushSubscription.toJSON() (POST) или
+// удаляет её (DELETE ?id= или ?endpoint=).
+func (h *APIHandler) HandlePushSubscriptions(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	userID := query.Get("userId")
+	if userID == "" {
+		http.Error(w, "User ID required", http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		subscriptions, err := h.pushSvc.List(r.Context(), userID)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		views := make([]*domain.PushSubscriptionView, 0, len(subscriptions))
+		for _, subscription := range subscriptions {
+			views = append(views, subscription.View())
+		}
+
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"user_id":       userID,
+			"subscriptions": views,
+		})
+	case http.MethodPost:
+		var subscription domain.PushSubscription
+		if err := json.NewDecoder(r.Body).Decode(&subscription); err != nil {
+			writeError(w, domain.ErrInvalidInput)
+			return
+		}
+		subscription.UserID = userID
+
+		if err := h.pushSvc.Subscribe(r.Context(), &subscription); err != nil {
+			writeError(w, err)
+			return
+		}
+
+		writeJSON(w, http.StatusCreated, subscription.View())
+	case http.MethodDelete:
+		endpoint := query.Get("endpoint")
+		if id := query.Get("id"); id != "" && endpoint == "" {
+			subscriptions, err := h.pushSvc.List(r.Context(), userID)
+			if err != nil {
+				writeError(w, err)
+				return
+			}
+			for _, subscription := range subscriptions {
+				if subscription.ID() == id {
+					endpoint = subscription.Endpoint
+					break
+				}
+			}
+			if endpoint == "" {
+				writeError(w, domain.ErrNotFound)
+				return
+			}
+		}
+		if endpoint == "" {
+			http.Error(w, "Endpoint or ID required", http.StatusBadRequest)
+			return
+		}
+
+		if err := h.pushSvc.Unsubscribe(r.Context(), userID, endpoint); err != nil {
+			writeError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleWebhookDeliveries возвращает подписчику журнал доставок его подписки
+// (?webhookId=&limit=), начиная с новых. Подписчик предъявляет секрет
+// подписки в заголовке Authorization: Bearer.
+func (h *APIHandler) HandleWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+
+	webhookID, ok := h.authenticateWebhook(w, r)
+	if !ok {
+		return
+	}
+
+	limit, ok := deliveriesLimit(query.Get("limit"))
+	if !ok {
+		http.Error(w, "Invalid limit", http.StatusBadRequest)
+		return
+	}
+
+	deliveries, err := h.webhookSvc.Deliveries(r.Context(), webhookID, limit)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"webhook_id": webhookID,
+		"deliveries": deliveries,
+	})
+}
+
+// HandleWebhookReplay ставит завершённую доставку ?id= подписки ?webhookId=
+// в очередь повторно; авторизация - как у HandleWebhookDeliveries.
+func (h *APIHandler) HandleWebhookReplay(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	webhookID, ok := h.authenticateWebhook(w, r)
+	if !ok {
+		return
+	}
+
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		http.Error(w, "ID required", http.StatusBadRequest)
+		return
+	}
+
+	delivery, err := h.webhookSvc.Replay(r.Context(), webhookID, id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, delivery)
+}
+
+func (h *APIHandler) authenticateWebhook(w http.ResponseWriter, r *http.Request) (string, bool) {
+	webhookID := r.URL.Query().Get("webhookId")
+	if webhookID == "" {
+		http.Error(w, "webhookId required", http.StatusBadRequest)
+		return "", false
+	}
+
+	secret, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !found {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return "", false
+	}
+
+	if err := h.webhookSvc.Authenticate(r.Context(), webhookID, secret); err != nil {
+		writeError(w, err)
+		return "", false
+	}
+
+	return webhookID, true
+}
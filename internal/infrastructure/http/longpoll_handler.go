@@ -0,0 +1,120 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+	"github.com/anatoly_dev/go-ws-notifications/internal/infrastructure/websocket"
+	"github.com/anatoly_dev/go-ws-notifications/pkg/logger"
+)
+
+// LongPollHandler - транспорт для клиентов без WebSocket и SSE. Первый
+// запрос открывает сессию в общем реестре websocket.Service, и пока клиент
+// продолжает опрос, пользователь считается подключённым.
+type LongPollHandler struct {
+	wsService *websocket.Service
+	config    *websocket.Config
+	tracker   domain.DeliveryTracker
+	logger    *logger.Logger
+	// sessionsLock не даёт параллельным запросам открыть две сессии одного пользователя
+	sessionsLock sync.Mutex
+}
+
+func NewLongPollHandler(
+	wsService *websocket.Service,
+	config *websocket.Config,
+	tracker domain.DeliveryTracker,
+	logger *logger.Logger,
+) *LongPollHandler {
+	return &LongPollHandler{
+		wsService: wsService,
+		config:    config,
+		tracker:   tracker,
+		logger:    logger,
+	}
+}
+
+func (h *LongPollHandler) HandlePoll(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+
+	userID := query.Get("userId")
+	if userID == "" {
+		h.logger.Warn("Попытка подключения без идентификатора пользователя")
+		http.Error(w, "User ID required", http.StatusBadRequest)
+		return
+	}
+
+	timeout := h.config.LongPollTimeout
+	if value := query.Get("timeout"); value != "" {
+		seconds, err := strconv.Atoi(value)
+		if err != nil || seconds < 0 {
+			http.Error(w, "Invalid timeout", http.StatusBadRequest)
+			return
+		}
+		if requested := time.Duration(seconds) * time.Second; requested < timeout {
+			timeout = requested
+		}
+	}
+
+	session, client := h.session(r, userID)
+	if session == nil {
+		writeDraining(w, h.wsService)
+		return
+	}
+
+	// Фреймы копятся в сессии, поэтому обработчики подключения не ждут опроса
+	if client != nil {
+		h.wsService.HandleConnect(r.Context(), client)
+	}
+	endRequestSpan(r)
+
+	// WriteTimeout сервера может быть короче времени удержания запроса
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(timeout + 10*time.Second))
+
+	writeJSON(w, http.StatusOK, session.Poll(r.Context(), query.Get("cursor"), timeout))
+}
+
+// session возвращает открытую long-poll сессию пользователя или открывает
+// новую; для новой возвращается и её клиент. При дренировании новые сессии
+// не открываются, но уже открытые дочитывают оставшиеся фреймы.
+func (h *LongPollHandler) session(r *http.Request, userID string) (*websocket.PollConnection, *websocket.Client) {
+	h.sessionsLock.Lock()
+	defer h.sessionsLock.Unlock()
+
+	if conn, ok := h.wsService.Connection(userID); ok {
+		if session, ok := conn.(*websocket.PollConnection); ok && !session.Closed() {
+			return session, nil
+		}
+	}
+
+	if h.wsService.IsDraining() {
+		return nil, nil
+	}
+
+	query := r.URL.Query()
+	attributes := domain.ClientAttributes{
+		AppVersion: query.Get("appVersion"),
+		Platform:   query.Get("platform"),
+		Locale:     query.Get("locale"),
+	}
+
+	ctx := h.logger.WithField("userID", userID)
+	ctx.Info("Открывается новая long-poll сессия")
+
+	session := websocket.NewPollConnection(h.config)
+	client := websocket.NewClient(session, userID, attributes, h.config, h.tracker, ctx)
+
+	h.wsService.RegisterClient(userID, client)
+
+	client.StartListening(h.wsService.UnregisterClient, nil)
+
+	return session, client
+}
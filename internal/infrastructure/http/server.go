@@ -5,6 +5,7 @@ import (
 	"crypto/tls"
 	"fmt"
 	"net/http"
+	"sync/atomic"
 	"time"
 
 	"github.com/anatoly_dev/go-ws-notifications/config"
@@ -17,21 +18,37 @@ type Server struct {
 	metricsServer *http.Server
 	logger        *logger.Logger
 	wsHandler     *WSHandler
+	ready         *atomic.Bool
 }
 
-func NewServer(cfg *config.Config, wsHandler *WSHandler, logger *logger.Logger) *Server {
+func NewServer(cfg *config.Config, wsHandler *WSHandler, sseHandler *SSEHandler, longPollHandler *LongPollHandler, apiHandler *APIHandler, adminHandler *AdminHandler, logger *logger.Logger) *Server {
 	router := http.NewServeMux()
 
+	ready := &atomic.Bool{}
+	ready.Store(true)
+
 	router.HandleFunc("/ws", wsHandler.HandleConnection)
+	router.HandleFunc("/sse", sseHandler.HandleStream)
+	router.HandleFunc("/poll", longPollHandler.HandlePoll)
+	apiHandler.Register(router)
 
 	router.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("OK"))
 	})
 
+	router.HandleFunc("/ready", func(w http.ResponseWriter, r *http.Request) {
+		if !ready.Load() {
+			http.Error(w, "Not ready", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("OK"))
+	})
+
 	server := &http.Server{
 		Addr:         fmt.Sprintf(":%d", cfg.Server.Port),
-		Handler:      router,
+		Handler:      tracingMiddleware(router),
 		ReadTimeout:  cfg.Server.ReadTimeout,
 		WriteTimeout: cfg.Server.WriteTimeout,
 	}
@@ -44,9 +61,12 @@ func NewServer(cfg *config.Config, wsHandler *WSHandler, logger *logger.Logger)
 
 	metricsRouter := http.NewServeMux()
 	metricsRouter.Handle("/metrics", promhttp.Handler())
+	// Административные маршруты доступны только на внутреннем порту метрик
+	adminHandler.Register(metricsRouter)
 	metricsServer := &http.Server{
-		Addr:    fmt.Sprintf(":%d", cfg.Server.MetricsPort),
-		Handler: metricsRouter,
+		Addr: fmt.Sprintf(":%d", cfg.Server.MetricsPort),
+		// Опрос Prometheus не трассируем: он засорял бы трассировки
+		Handler: tracingMiddleware(metricsRouter, "/metrics"),
 	}
 
 	return &Server{
@@ -54,9 +74,15 @@ func NewServer(cfg *config.Config, wsHandler *WSHandler, logger *logger.Logger)
 		metricsServer: metricsServer,
 		logger:        logger,
 		wsHandler:     wsHandler,
+		ready:         ready,
 	}
 }
 
+// SetReady управляет ответом readiness-пробы /ready.
+func (s *Server) SetReady(ready bool) {
+	s.ready.Store(ready)
+}
+
 func (s *Server) Start() error {
 	// Запуск сервера метрик в отдельной горутине
 	go func() {
@@ -0,0 +1,118 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+	"github.com/anatoly_dev/go-ws-notifications/internal/infrastructure/websocket"
+	"github.com/anatoly_dev/go-ws-notifications/pkg/logger"
+)
+
+// SSEHandler - запасной транспорт для сетей, где прокси обрывают WebSocket.
+// SSE-клиент регистрируется в том же реестре websocket.Service и получает
+// те же фреймы, но команды отправлять не может.
+type SSEHandler struct {
+	wsService *websocket.Service
+	resumeSvc domain.ResumeService
+	config    *websocket.Config
+	tracker   domain.DeliveryTracker
+	logger    *logger.Logger
+}
+
+func NewSSEHandler(
+	wsService *websocket.Service,
+	resumeSvc domain.ResumeService,
+	config *websocket.Config,
+	tracker domain.DeliveryTracker,
+	logger *logger.Logger,
+) *SSEHandler {
+	return &SSEHandler{
+		wsService: wsService,
+		resumeSvc: resumeSvc,
+		config:    config,
+		tracker:   tracker,
+		logger:    logger,
+	}
+}
+
+func (h *SSEHandler) HandleStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if h.wsService.IsDraining() {
+		writeDraining(w, h.wsService)
+		return
+	}
+
+	query := r.URL.Query()
+
+	userID := query.Get("userId")
+	if userID == "" {
+		h.logger.Warn("Попытка подключения без идентификатора пользователя")
+		http.Error(w, "User ID required", http.StatusBadRequest)
+		return
+	}
+
+	// EventSource передаёт заголовок только при автоматическом переподключении
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = query.Get("lastEventId")
+	}
+
+	conn, err := websocket.NewSSEConnection(w, r)
+	if err != nil {
+		h.logger.WithError(err).Error("Ошибка открытия SSE потока")
+		return
+	}
+
+	ctx := h.logger.WithField("userID", userID)
+	ctx.Info("Устанавливается новое SSE соединение")
+
+	attributes := domain.ClientAttributes{
+		AppVersion: query.Get("appVersion"),
+		Platform:   query.Get("platform"),
+		Locale:     query.Get("locale"),
+	}
+
+	client := websocket.NewClient(conn, userID, attributes, h.config, h.tracker, ctx)
+
+	h.wsService.RegisterClient(userID, client)
+
+	client.StartListening(h.wsService.UnregisterClient, nil)
+
+	// Писать в ответ можно только до возврата из обработчика
+	defer func() {
+		client.Close()
+		<-client.Done()
+	}()
+
+	if lastEventID != "" {
+		h.resume(r, userID, lastEventID)
+	}
+
+	h.wsService.HandleConnect(r.Context(), client)
+	endRequestSpan(r)
+
+	select {
+	case <-r.Context().Done():
+	case <-client.Done():
+	}
+}
+
+func (h *SSEHandler) resume(r *http.Request, userID string, lastEventID string) {
+	log := h.logger.WithFields(map[string]interface{}{
+		"userID":      userID,
+		"lastEventID": lastEventID,
+	})
+
+	if _, err := h.resumeSvc.Resume(r.Context(), userID, lastEventID); err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			log.Warn("Неизвестный Last-Event-ID, поток продолжается без досылки")
+			return
+		}
+		log.WithError(err).Error("Ошибка досылки уведомлений по Last-Event-ID")
+	}
+}
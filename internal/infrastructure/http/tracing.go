@@ -0,0 +1,48 @@
+package http
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/anatoly_dev/go-ws-notifications/internal/infrastructure/http")
+
+// tracingMiddleware извлекает W3C trace context из заголовков запроса
+// и открывает серверный спан на время обработки. Пути из skip не
+// трассируются.
+func tracingMiddleware(next http.Handler, skip ...string) http.Handler {
+	skipped := make(map[string]bool, len(skip))
+	for _, path := range skip {
+		skipped[path] = true
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if skipped[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracer.Start(ctx, r.Method+" "+r.URL.Path,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", r.Method),
+				attribute.String("url.path", r.URL.Path),
+			),
+		)
+		defer span.End()
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// endRequestSpan закрывает серверный спан потокового запроса, когда
+// подключение настроено: иначе спан длился бы всё соединение. Отложенный
+// End в tracingMiddleware после этого ничего не делает.
+func endRequestSpan(r *http.Request) {
+	trace.SpanFromContext(r.Context()).End()
+}
@@ -2,7 +2,10 @@ package http
 
 import (
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
 	"github.com/anatoly_dev/go-ws-notifications/internal/infrastructure/websocket"
 	"github.com/anatoly_dev/go-ws-notifications/pkg/logger"
 	gorillaWs "github.com/gorilla/websocket"
@@ -13,9 +16,10 @@ type WSHandler struct {
 	upgrader  gorillaWs.Upgrader
 	logger    *logger.Logger
 	config    *websocket.Config
+	tracker   domain.DeliveryTracker
 }
 
-func NewWSHandler(wsService *websocket.Service, config *websocket.Config, logger *logger.Logger) *WSHandler {
+func NewWSHandler(wsService *websocket.Service, config *websocket.Config, tracker domain.DeliveryTracker, logger *logger.Logger) *WSHandler {
 	upgrader := gorillaWs.Upgrader{
 		ReadBufferSize:  config.ReadBufferSize,
 		WriteBufferSize: config.WriteBufferSize,
@@ -29,10 +33,16 @@ func NewWSHandler(wsService *websocket.Service, config *websocket.Config, logger
 		upgrader:  upgrader,
 		logger:    logger,
 		config:    config,
+		tracker:   tracker,
 	}
 }
 
 func (h *WSHandler) HandleConnection(w http.ResponseWriter, r *http.Request) {
+	if h.wsService.IsDraining() {
+		writeDraining(w, h.wsService)
+		return
+	}
+
 	userID := r.URL.Query().Get("userId")
 	if userID == "" {
 		h.logger.Warn("Попытка подключения без идентификатора пользователя")
@@ -49,9 +59,26 @@ func (h *WSHandler) HandleConnection(w http.ResponseWriter, r *http.Request) {
 	ctx := h.logger.WithField("userID", userID)
 	ctx.Info("Устанавливается новое WebSocket соединение")
 
-	client := websocket.NewClient(conn, userID, h.config, ctx)
+	query := r.URL.Query()
+	attributes := domain.ClientAttributes{
+		AppVersion: query.Get("appVersion"),
+		Platform:   query.Get("platform"),
+		Locale:     query.Get("locale"),
+	}
+
+	client := websocket.NewClient(websocket.NewWebSocketConnection(conn, h.config), userID, attributes, h.config, h.tracker, ctx)
 
 	h.wsService.RegisterClient(userID, client)
 
-	client.StartListening(h.wsService.UnregisterClient)
+	client.StartListening(h.wsService.UnregisterClient, h.wsService.HandleInbound)
+
+	h.wsService.HandleConnect(r.Context(), client)
+}
+
+// writeDraining отклоняет подключение к дренируемому узлу. Retry-After
+// берётся из того же окна, что и подсказка в close-фрейме.
+func writeDraining(w http.ResponseWriter, wsService *websocket.Service) {
+	seconds := (wsService.RetryAfter() + time.Second - 1) / time.Second
+	w.Header().Set("Retry-After", strconv.FormatInt(int64(seconds), 10))
+	http.Error(w, "Service is draining", http.StatusServiceUnavailable)
 }
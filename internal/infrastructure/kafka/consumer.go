@@ -3,17 +3,27 @@ package kafka
 import (
 	"context"
 	"errors"
+	"strconv"
 	"sync"
 	"time"
 
+	"github.com/anatoly_dev/go-ws-notifications/internal/infrastructure/metrics"
 	"github.com/anatoly_dev/go-ws-notifications/pkg/logger"
 	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var tracer = otel.Tracer("github.com/anatoly_dev/go-ws-notifications/internal/infrastructure/kafka")
+
+const commitTimeout = 5 * time.Second
+
 type Consumer struct {
 	reader  *kafka.Reader
 	logger  *logger.Logger
-	handler func(message []byte) error
+	handler func(ctx context.Context, message []byte) error
 	ctx     context.Context
 	cancel  context.CancelFunc
 	wg      sync.WaitGroup
@@ -37,7 +47,7 @@ func NewConsumer(config *ConsumerConfig, logger *logger.Logger) (*Consumer, erro
 	}, nil
 }
 
-func (c *Consumer) Subscribe(topic string, handler func(message []byte) error) error {
+func (c *Consumer) Subscribe(topic string, handler func(ctx context.Context, message []byte) error) error {
 
 	c.logger.WithFields(map[string]interface{}{
 		"brokers": c.config.Brokers,
@@ -80,7 +90,7 @@ func (c *Consumer) consumeMessages() {
 		case <-reconnectTimer.C:
 
 		default:
-			message, err := c.reader.ReadMessage(c.ctx)
+			message, err := c.reader.FetchMessage(c.ctx)
 			if err != nil {
 				if errors.Is(err, context.Canceled) {
 					return
@@ -107,13 +117,48 @@ func (c *Consumer) consumeMessages() {
 				"offset":    message.Offset,
 			}).Debug("Получено сообщение из Kafka")
 
-			if err := c.handler(message.Value); err != nil {
-				c.logger.WithError(err).Error("Ошибка обработки сообщения из Kafka")
+			metrics.KafkaConsumerLag.
+				WithLabelValues(message.Topic, strconv.Itoa(message.Partition)).
+				Set(float64(message.HighWaterMark - message.Offset - 1))
+
+			c.handle(message)
+
+			// Коммит выполняется с отдельным контекстом, чтобы сообщение,
+			// обработанное во время остановки, всё равно было зафиксировано.
+			commitCtx, cancel := context.WithTimeout(context.Background(), commitTimeout)
+			if err := c.reader.CommitMessages(commitCtx, message); err != nil {
+				c.logger.WithError(err).Error("Ошибка коммита смещения в Kafka")
 			}
+			cancel()
 		}
 	}
 }
 
+func (c *Consumer) handle(message kafka.Message) {
+	// Контекст отделён от c.ctx: начатая обработка доводится до конца даже при остановке
+	ctx := otel.GetTextMapPropagator().Extract(context.Background(), headerCarrier{headers: &message.Headers})
+	ctx, span := tracer.Start(ctx, "kafka.consume "+message.Topic,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "kafka"),
+			attribute.String("messaging.destination.name", message.Topic),
+			attribute.Int("messaging.kafka.destination.partition", message.Partition),
+			attribute.Int64("messaging.kafka.message.offset", message.Offset),
+		),
+	)
+	defer span.End()
+
+	start := time.Now()
+	result := "success"
+	if err := c.handler(ctx, message.Value); err != nil {
+		c.logger.WithError(err).Error("Ошибка обработки сообщения из Kafka")
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		result = "error"
+	}
+	metrics.KafkaHandlerDuration.WithLabelValues(message.Topic, result).Observe(time.Since(start).Seconds())
+}
+
 func min(a, b int) int {
 	if a < b {
 		return a
@@ -121,12 +166,14 @@ func min(a, b int) int {
 	return b
 }
 
+// Close прекращает чтение новых сообщений, дожидается завершения обработки
+// и коммита текущего сообщения и только после этого закрывает reader.
 func (c *Consumer) Close() error {
 	c.cancel()
+	c.wg.Wait()
 	if c.reader != nil {
 		c.reader.Close()
 	}
-	c.wg.Wait()
 	c.logger.Info("Соединение с Kafka закрыто")
 	return nil
 }
@@ -0,0 +1,195 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "notification_service"
+
+var (
+	ActiveConnections = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "websocket",
+		Name:      "active_connections",
+		Help:      "Количество открытых WebSocket соединений",
+	})
+
+	ActiveUsers = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "websocket",
+		Name:      "active_users",
+		Help:      "Количество пользователей с активным WebSocket соединением",
+	})
+
+	MessagesSent = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "websocket",
+		Name:      "messages_sent_total",
+		Help:      "Количество уведомлений, записанных в WebSocket, по типу",
+	}, []string{"type"})
+
+	MessagesDropped = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "websocket",
+		Name:      "messages_dropped_total",
+		Help:      "Количество сообщений, отброшенных без отправки клиенту, по причине",
+	}, []string{"reason"})
+
+	BufferOverflowDisconnects = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "websocket",
+		Name:      "buffer_overflow_disconnects_total",
+		Help:      "Количество отключений клиентов из-за переполнения буфера отправки",
+	})
+
+	BackpressureEvents = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "websocket",
+		Name:      "backpressure_events_total",
+		Help:      "Количество переполнений буфера отправки по сработавшей политике",
+	}, []string{"policy"})
+
+	WriteDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "websocket",
+		Name:      "write_duration_seconds",
+		Help:      "Длительность записи фрейма в WebSocket",
+		Buckets:   prometheus.ExponentialBuckets(0.0005, 2, 14),
+	})
+
+	NotificationsFailed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "notifications",
+		Name:      "failed_total",
+		Help:      "Количество уведомлений, которые не удалось доставить, по типу и причине",
+	}, []string{"type", "reason"})
+
+	NotificationsDelivered = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "notifications",
+		Name:      "delivered_total",
+		Help:      "Количество доставленных уведомлений по типу и каналу доставки",
+	}, []string{"type", "channel"})
+
+	DeliveryLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "notifications",
+		Name:      "delivery_latency_seconds",
+		Help:      "Время от создания уведомления (created_at) до записи в WebSocket",
+		Buckets:   prometheus.ExponentialBuckets(0.005, 2, 14),
+	}, []string{"type"})
+
+	KafkaHandlerDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "kafka",
+		Name:      "handler_duration_seconds",
+		Help:      "Длительность обработки сообщения из Kafka",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 2, 14),
+	}, []string{"topic", "result"})
+
+	KafkaConsumerLag = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "kafka",
+		Name:      "consumer_lag",
+		Help:      "Отставание потребителя от конца партиции в сообщениях",
+	}, []string{"topic", "partition"})
+
+	StoredNotifications = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "storage",
+		Name:      "notifications_stored",
+		Help:      "Количество уведомлений в хранилище в памяти",
+	})
+
+	EvictedNotifications = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "storage",
+		Name:      "notifications_evicted_total",
+		Help:      "Количество уведомлений, удалённых политиками хранения, по причине",
+	}, []string{"reason"})
+
+	DigestedNotifications = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "digest",
+		Name:      "notifications_total",
+		Help:      "Количество уведомлений, собранных в дайджесты, по правилу",
+	}, []string{"rule"})
+
+	DigestsEmitted = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "digest",
+		Name:      "emitted_total",
+		Help:      "Количество отправленных дайджестов по правилу и причине (count, window, shutdown)",
+	}, []string{"rule", "reason"})
+
+	ChannelSubscriptions = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "channels",
+		Name:      "subscriptions",
+		Help:      "Количество активных подписок сокетов на каналы",
+	})
+
+	ChannelSubscribeDenied = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "channels",
+		Name:      "subscribe_denied_total",
+		Help:      "Количество подписок на каналы, отклонённых политикой доступа",
+	})
+
+	ChannelMessagesPublished = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "channels",
+		Name:      "messages_published_total",
+		Help:      "Количество опубликованных в каналы сообщений",
+	})
+
+	ChannelMessagesDelivered = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "channels",
+		Name:      "messages_delivered_total",
+		Help:      "Количество сообщений каналов, поставленных в буферы подписанных сокетов",
+	})
+
+	BroadcastsSent = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "broadcasts",
+		Name:      "sent_total",
+		Help:      "Количество опубликованных широковещательных объявлений",
+	})
+
+	BroadcastDeliveries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "broadcasts",
+		Name:      "deliveries_total",
+		Help:      "Количество доставок объявлений клиентам: live - при публикации, sticky - при подключении",
+	}, []string{"mode"})
+
+	PushMessages = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "webpush",
+		Name:      "messages_total",
+		Help:      "Количество отправок Web Push по результату: sent, gone - подписка удалена, failed",
+	}, []string{"result"})
+
+	EmailMessages = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "email",
+		Name:      "messages_total",
+		Help:      "Количество отправок писем по результату: sent, failed",
+	}, []string{"result"})
+
+	WebhookAttempts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "webhooks",
+		Name:      "attempts_total",
+		Help:      "Количество попыток доставки вебхуков по результату: succeeded, retried, failed, deferred - отложена открытым circuit breaker",
+	}, []string{"result"})
+
+	WebhookCircuitOpened = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "webhooks",
+		Name:      "circuit_opened_total",
+		Help:      "Количество размыканий circuit breaker адресов вебхуков",
+	})
+)
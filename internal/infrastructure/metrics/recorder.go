@@ -0,0 +1,58 @@
+package metrics
+
+import "github.com/anatoly_dev/go-ws-notifications/internal/domain"
+
+// Recorder реализует domain.Metrics поверх счётчиков Prometheus.
+type Recorder struct{}
+
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+func (r *Recorder) NotificationDelivered(notificationType domain.NotificationType, channel string) {
+	NotificationsDelivered.WithLabelValues(string(notificationType), channel).Inc()
+}
+
+func (r *Recorder) NotificationFailed(notificationType domain.NotificationType, reason string) {
+	NotificationsFailed.WithLabelValues(string(notificationType), reason).Inc()
+}
+
+func (r *Recorder) NotificationDigested(rule string) {
+	DigestedNotifications.WithLabelValues(rule).Inc()
+}
+
+func (r *Recorder) DigestEmitted(rule string, reason string) {
+	DigestsEmitted.WithLabelValues(rule, reason).Inc()
+}
+
+func (r *Recorder) PushMessage(result string) {
+	PushMessages.WithLabelValues(result).Inc()
+}
+
+func (r *Recorder) EmailMessage(result string) {
+	EmailMessages.WithLabelValues(result).Inc()
+}
+
+func (r *Recorder) WebhookAttempt(result string) {
+	WebhookAttempts.WithLabelValues(result).Inc()
+}
+
+func (r *Recorder) WebhookCircuitOpened() {
+	WebhookCircuitOpened.Inc()
+}
+
+func (r *Recorder) ChannelSubscribeDenied() {
+	ChannelSubscribeDenied.Inc()
+}
+
+func (r *Recorder) ChannelMessagePublished() {
+	ChannelMessagesPublished.Inc()
+}
+
+func (r *Recorder) BroadcastSent() {
+	BroadcastsSent.Inc()
+}
+
+func (r *Recorder) BroadcastDeliveries(kind string, count int) {
+	BroadcastDeliveries.WithLabelValues(kind).Add(float64(count))
+}
@@ -0,0 +1,48 @@
+package netguard
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+)
+
+// Control запрещает соединения с внутренними адресами. Проверяется адрес,
+// к которому действительно идёт подключение, поэтому имя, которое
+// резолвится в loopback или частную сеть, тоже не пройдёт.
+func Control(network string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || !domain.IsPublicIP(ip) {
+		return fmt.Errorf("подключение к внутреннему адресу %s запрещено", host)
+	}
+
+	return nil
+}
+
+// NewClient создаёт HTTP-клиент для запросов по адресам, заданным
+// пользователями. Прокси не используется, иначе Control проверял бы его
+// адрес вместо адреса назначения.
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: Control,
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConnsPerHost: 4,
+			IdleConnTimeout:     90 * time.Second,
+		},
+	}
+}
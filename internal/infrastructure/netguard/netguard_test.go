@@ -0,0 +1,49 @@
+package netguard
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNewClientRefusesLoopback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	resp, err := NewClient(time.Second).Get(server.URL)
+	if err == nil {
+		resp.Body.Close()
+		t.Fatalf("запрос к %s прошёл, ожидалась ошибка", server.URL)
+	}
+}
+
+func TestControl(t *testing.T) {
+	tests := []struct {
+		address string
+		allowed bool
+	}{
+		{address: "93.184.216.34:443", allowed: true},
+		{address: "[2606:2800:220:1:248:1893:25c8:1946]:443", allowed: true},
+		{address: "127.0.0.1:443"},
+		{address: "10.0.0.5:443"},
+		{address: "172.16.3.4:443"},
+		{address: "100.100.100.200:80"},
+		{address: "100.128.0.1:443", allowed: true},
+		{address: "169.254.169.254:80"},
+		{address: "[::1]:443"},
+		{address: "[fd00::1]:443"},
+		{address: "not-an-address"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			err := Control("tcp", tt.address, nil)
+			if (err == nil) != tt.allowed {
+				t.Fatalf("Control(%q) = %v, allowed = %v", tt.address, err, tt.allowed)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+	bolt "go.etcd.io/bbolt"
+)
+
+var broadcastBucket = []byte("broadcasts")
+
+// BoltBroadcastStore просматривает все объявления при каждом запросе:
+// их немного, а истёкшие удаляются при публикации новых.
+type BoltBroadcastStore struct {
+	db *bolt.DB
+}
+
+func NewBoltBroadcastStore(database *BoltDatabase) (*BoltBroadcastStore, error) {
+	if err := database.createBuckets(broadcastBucket); err != nil {
+		return nil, err
+	}
+
+	return &BoltBroadcastStore{db: database.db}, nil
+}
+
+func (s *BoltBroadcastStore) Save(ctx context.Context, broadcast *domain.Broadcast) error {
+	// Объявление без срока никогда не удалилось бы из DeleteExpired
+	if broadcast.ExpiresAt == nil {
+		return fmt.Errorf("%w: у объявления не задан срок действия", domain.ErrInvalidInput)
+	}
+
+	data, err := json.Marshal(broadcast)
+	if err != nil {
+		return fmt.Errorf("ошибка сериализации объявления: %w", err)
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(broadcastBucket).Put([]byte(broadcast.ID), data)
+	})
+}
+
+func (s *BoltBroadcastStore) FindByID(ctx context.Context, id string) (*domain.Broadcast, error) {
+	var broadcast *domain.Broadcast
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(broadcastBucket).Get([]byte(id))
+		if data == nil {
+			return domain.ErrNotFound
+		}
+
+		var err error
+		broadcast, err = decodeBroadcast(string(data))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return broadcast, nil
+}
+
+func (s *BoltBroadcastStore) FindActive(ctx context.Context, now time.Time) ([]*domain.Broadcast, error) {
+	active := []*domain.Broadcast{}
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(broadcastBucket).ForEach(func(key, data []byte) error {
+			broadcast, err := decodeBroadcast(string(data))
+			if err != nil {
+				return err
+			}
+			if !broadcast.IsExpired(now) {
+				active = append(active, broadcast)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sortBroadcasts(active)
+	return active, nil
+}
+
+func (s *BoltBroadcastStore) Delete(ctx context.Context, id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(broadcastBucket)
+		if bucket.Get([]byte(id)) == nil {
+			return domain.ErrNotFound
+		}
+		return bucket.Delete([]byte(id))
+	})
+}
+
+func (s *BoltBroadcastStore) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	deleted := 0
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(broadcastBucket)
+
+		expired := [][]byte{}
+		err := bucket.ForEach(func(key, data []byte) error {
+			broadcast, err := decodeBroadcast(string(data))
+			if err != nil {
+				return err
+			}
+			if broadcast.IsExpired(now) {
+				expired = append(expired, append([]byte(nil), key...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, key := range expired {
+			if err := bucket.Delete(key); err != nil {
+				return err
+			}
+		}
+
+		deleted = len(expired)
+		return nil
+	})
+
+	return deleted, err
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/anatoly_dev/go-ws-notifications/pkg/logger"
+	bolt "go.etcd.io/bbolt"
+)
+
+const DriverBolt = "bolt"
+
+type BoltConfig struct {
+	Path string
+}
+
+// BoltDatabase - встроенная база bbolt, общая для всех хранилищ сервиса.
+// Каждая запись выполняется в транзакции с fsync, поэтому после сбоя
+// файл остаётся в согласованном состоянии.
+type BoltDatabase struct {
+	db *bolt.DB
+}
+
+func OpenBoltDatabase(config *BoltConfig, logger *logger.Logger) (*BoltDatabase, error) {
+	if dir := filepath.Dir(config.Path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("ошибка создания директории хранилища: %w", err)
+		}
+	}
+
+	db, err := bolt.Open(config.Path, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("ошибка открытия файла хранилища: %w", err)
+	}
+
+	logger.WithField("path", config.Path).Info("Открыто встроенное хранилище уведомлений")
+
+	return &BoltDatabase{db: db}, nil
+}
+
+func (d *BoltDatabase) createBuckets(names ...[]byte) error {
+	err := d.db.Update(func(tx *bolt.Tx) error {
+		for _, name := range names {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("ошибка инициализации хранилища: %w", err)
+	}
+
+	return nil
+}
+
+// Backup записывает согласованный снимок базы в w, не останавливая запись.
+func (d *BoltDatabase) Backup(w io.Writer) (int64, error) {
+	var written int64
+
+	err := d.db.View(func(tx *bolt.Tx) error {
+		var err error
+		written, err = tx.WriteTo(w)
+		return err
+	})
+
+	return written, err
+}
+
+func (d *BoltDatabase) Close() error {
+	return d.db.Close()
+}
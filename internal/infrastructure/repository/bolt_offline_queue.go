@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Ключ записи: <userID>\x00<время постановки><id уведомления>, поэтому
+// записи пользователя лежат подряд в порядке постановки.
+var offlineQueueBucket = []byte("offline_queue")
+
+type BoltOfflineQueue struct {
+	db *bolt.DB
+}
+
+func NewBoltOfflineQueue(database *BoltDatabase) (*BoltOfflineQueue, error) {
+	if err := database.createBuckets(offlineQueueBucket); err != nil {
+		return nil, err
+	}
+
+	return &BoltOfflineQueue{db: database.db}, nil
+}
+
+func (q *BoltOfflineQueue) Push(ctx context.Context, notification *domain.Notification) error {
+	data, err := json.Marshal(notification)
+	if err != nil {
+		return fmt.Errorf("ошибка сериализации уведомления для офлайн-очереди: %w", err)
+	}
+
+	key := appendTime(offlineQueuePrefix(notification.UserID), time.Now())
+	key = append(key, notification.ID...)
+
+	return q.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(offlineQueueBucket).Put(key, data)
+	})
+}
+
+func (q *BoltOfflineQueue) PopAll(ctx context.Context, userID string) ([]*domain.Notification, error) {
+	notifications := []*domain.Notification{}
+	prefix := offlineQueuePrefix(userID)
+
+	err := q.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(offlineQueueBucket)
+
+		keys := [][]byte{}
+		cursor := bucket.Cursor()
+		for key, data := cursor.Seek(prefix); key != nil && bytes.HasPrefix(key, prefix); key, data = cursor.Next() {
+			var notification domain.Notification
+			if err := json.Unmarshal(data, &notification); err != nil {
+				return fmt.Errorf("ошибка чтения уведомления из офлайн-очереди: %w", err)
+			}
+
+			notifications = append(notifications, &notification)
+			keys = append(keys, append([]byte(nil), key...))
+		}
+
+		for _, key := range keys {
+			if err := bucket.Delete(key); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return notifications, nil
+}
+
+func offlineQueuePrefix(userID string) []byte {
+	return append([]byte(userID), 0)
+}
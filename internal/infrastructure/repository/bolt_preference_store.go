@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+	bolt "go.etcd.io/bbolt"
+)
+
+var preferencesBucket = []byte("user_preferences")
+
+type BoltPreferenceStore struct {
+	db *bolt.DB
+}
+
+func NewBoltPreferenceStore(database *BoltDatabase) (*BoltPreferenceStore, error) {
+	if err := database.createBuckets(preferencesBucket); err != nil {
+		return nil, err
+	}
+
+	return &BoltPreferenceStore{db: database.db}, nil
+}
+
+func (s *BoltPreferenceStore) Get(ctx context.Context, userID string) (*domain.Preferences, error) {
+	preferences := domain.NewPreferences(userID)
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(preferencesBucket).Get([]byte(userID))
+		if data == nil {
+			return nil
+		}
+
+		var err error
+		preferences, err = decodePreferences(string(data))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return preferences, nil
+}
+
+func (s *BoltPreferenceStore) Save(ctx context.Context, preferences *domain.Preferences) error {
+	data, err := json.Marshal(preferences)
+	if err != nil {
+		return fmt.Errorf("ошибка сериализации настроек пользователя: %w", err)
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(preferencesBucket).Put([]byte(preferences.UserID), data)
+	})
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Подписки хранятся по endpoint; выборка по пользователю перебирает бакет,
+// так как подписок на порядки меньше, чем уведомлений.
+var pushSubscriptionsBucket = []byte("push_subscriptions")
+
+type BoltPushStore struct {
+	db *bolt.DB
+}
+
+func NewBoltPushStore(database *BoltDatabase) (*BoltPushStore, error) {
+	if err := database.createBuckets(pushSubscriptionsBucket); err != nil {
+		return nil, err
+	}
+
+	return &BoltPushStore{db: database.db}, nil
+}
+
+func (s *BoltPushStore) Save(ctx context.Context, subscription *domain.PushSubscription) error {
+	data, err := json.Marshal(subscription)
+	if err != nil {
+		return fmt.Errorf("ошибка сериализации push-подписки: %w", err)
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(pushSubscriptionsBucket).Put([]byte(subscription.Endpoint), data)
+	})
+}
+
+func (s *BoltPushStore) FindByUserID(ctx context.Context, userID string) ([]*domain.PushSubscription, error) {
+	subscriptions := []*domain.PushSubscription{}
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(pushSubscriptionsBucket).ForEach(func(key, data []byte) error {
+			var subscription domain.PushSubscription
+			if err := json.Unmarshal(data, &subscription); err != nil {
+				return fmt.Errorf("ошибка чтения push-подписки: %w", err)
+			}
+
+			if subscription.UserID == userID {
+				subscriptions = append(subscriptions, &subscription)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(subscriptions, func(i, j int) bool {
+		return subscriptions[i].CreatedAt.Before(subscriptions[j].CreatedAt)
+	})
+
+	return subscriptions, nil
+}
+
+func (s *BoltPushStore) Delete(ctx context.Context, userID string, endpoint string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(pushSubscriptionsBucket)
+
+		data := bucket.Get([]byte(endpoint))
+		if data == nil {
+			return domain.ErrNotFound
+		}
+
+		var subscription domain.PushSubscription
+		if err := json.Unmarshal(data, &subscription); err != nil {
+			return fmt.Errorf("ошибка чтения push-подписки: %w", err)
+		}
+
+		if subscription.UserID != userID {
+			return domain.ErrNotFound
+		}
+
+		return bucket.Delete([]byte(endpoint))
+	})
+}
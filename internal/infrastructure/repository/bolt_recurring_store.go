@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+	bolt "go.etcd.io/bbolt"
+)
+
+var recurringBucket = []byte("recurring_jobs")
+
+type BoltRecurringStore struct {
+	db *bolt.DB
+}
+
+func NewBoltRecurringStore(database *BoltDatabase) (*BoltRecurringStore, error) {
+	if err := database.createBuckets(recurringBucket); err != nil {
+		return nil, err
+	}
+
+	return &BoltRecurringStore{db: database.db}, nil
+}
+
+func (s *BoltRecurringStore) Save(ctx context.Context, job *domain.RecurringJob) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("ошибка сериализации периодического объявления: %w", err)
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(recurringBucket).Put([]byte(job.ID), data)
+	})
+}
+
+func (s *BoltRecurringStore) Advance(ctx context.Context, job *domain.RecurringJob, previous *time.Time) (bool, error) {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return false, fmt.Errorf("ошибка сериализации периодического объявления: %w", err)
+	}
+
+	advanced := false
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(recurringBucket)
+
+		stored := bucket.Get([]byte(job.ID))
+		if stored == nil {
+			return nil
+		}
+
+		var current domain.RecurringJob
+		if err := json.Unmarshal(stored, &current); err != nil {
+			return fmt.Errorf("ошибка чтения периодического объявления: %w", err)
+		}
+		if domain.RunKey(current.NextRunAt) != domain.RunKey(previous) {
+			return nil
+		}
+
+		advanced = true
+		return bucket.Put([]byte(job.ID), data)
+	})
+
+	return advanced, err
+}
+
+func (s *BoltRecurringStore) FindByID(ctx context.Context, id string) (*domain.RecurringJob, error) {
+	var job *domain.RecurringJob
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(recurringBucket).Get([]byte(id))
+		if data == nil {
+			return domain.ErrNotFound
+		}
+
+		var err error
+		job, err = decodeRecurringJob(string(data))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+func (s *BoltRecurringStore) FindAll(ctx context.Context) ([]*domain.RecurringJob, error) {
+	jobs := []*domain.RecurringJob{}
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(recurringBucket).ForEach(func(key, data []byte) error {
+			job, err := decodeRecurringJob(string(data))
+			if err != nil {
+				return err
+			}
+			jobs = append(jobs, job)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.Before(jobs[j].CreatedAt)
+	})
+
+	return jobs, nil
+}
+
+func (s *BoltRecurringStore) Delete(ctx context.Context, id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(recurringBucket)
+		if bucket.Get([]byte(id)) == nil {
+			return domain.ErrNotFound
+		}
+		return bucket.Delete([]byte(id))
+	})
+}
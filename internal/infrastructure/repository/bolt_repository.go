@@ -0,0 +1,300 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+	"github.com/anatoly_dev/go-ws-notifications/pkg/logger"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	notificationsBucket = []byte("notifications")
+	userIndexBucket     = []byte("notifications_by_user")
+	createdIndexBucket  = []byte("notifications_by_created_at")
+	expiryIndexBucket   = []byte("notifications_by_expires_at")
+	collapseIndexBucket = []byte("notifications_by_collapse_key")
+)
+
+type BoltRepository struct {
+	db     *bolt.DB
+	logger *logger.Logger
+}
+
+func NewBoltRepository(database *BoltDatabase, logger *logger.Logger) (*BoltRepository, error) {
+	err := database.createBuckets(notificationsBucket, userIndexBucket, createdIndexBucket, expiryIndexBucket, collapseIndexBucket)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BoltRepository{
+		db:     database.db,
+		logger: logger,
+	}, nil
+}
+
+func (r *BoltRepository) Save(ctx context.Context, notification *domain.Notification) error {
+	r.logger.WithField("notificationID", notification.ID).Debug("Сохранение уведомления")
+
+	return r.db.Update(func(tx *bolt.Tx) error {
+		return putNotification(tx, notification)
+	})
+}
+
+func (r *BoltRepository) FindByID(ctx context.Context, id string) (*domain.Notification, error) {
+	var notification *domain.Notification
+
+	err := r.db.View(func(tx *bolt.Tx) error {
+		var err error
+		notification, err = getNotification(tx, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return notification, nil
+}
+
+func (r *BoltRepository) FindByUserID(ctx context.Context, userID string) ([]*domain.Notification, error) {
+	notifications := []*domain.Notification{}
+
+	err := r.db.View(func(tx *bolt.Tx) error {
+		prefix := userIndexPrefix(userID)
+		cursor := tx.Bucket(userIndexBucket).Cursor()
+
+		for key, _ := cursor.Seek(prefix); key != nil && bytes.HasPrefix(key, prefix); key, _ = cursor.Next() {
+			id := string(key[len(prefix)+8:])
+
+			notification, err := getNotification(tx, id)
+			if err != nil {
+				return err
+			}
+			notifications = append(notifications, notification)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return notifications, nil
+}
+
+func (r *BoltRepository) Update(ctx context.Context, notification *domain.Notification) error {
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		existing, err := getNotification(tx, notification.ID)
+		if err != nil {
+			return err
+		}
+
+		// Отметку доставки, сделанную после чтения уведомления, не затираем
+		if notification.DeliveredAt == nil && existing.DeliveredAt != nil {
+			updated := *notification
+			updated.DeliveredAt = existing.DeliveredAt
+			notification = &updated
+		}
+
+		return putNotification(tx, notification)
+	})
+	if err != nil {
+		return err
+	}
+
+	r.logger.WithField("notificationID", notification.ID).Debug("Уведомление обновлено")
+
+	return nil
+}
+
+func (r *BoltRepository) MarkDelivered(ctx context.Context, id string, at time.Time) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		notification, err := getNotification(tx, id)
+		if err == domain.ErrNotFound || (err == nil && notification.DeliveredAt != nil) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		notification.DeliveredAt = &at
+		return putNotification(tx, notification)
+	})
+}
+
+func (r *BoltRepository) FindExpired(ctx context.Context, now time.Time, limit int) ([]*domain.Notification, error) {
+	expired := []*domain.Notification{}
+
+	err := r.db.View(func(tx *bolt.Tx) error {
+		bound := appendTime(nil, now)
+		cursor := tx.Bucket(expiryIndexBucket).Cursor()
+
+		for key, _ := cursor.First(); key != nil && bytes.Compare(key[:8], bound) <= 0; key, _ = cursor.Next() {
+			notification, err := getNotification(tx, string(key[8:]))
+			if err != nil {
+				return err
+			}
+			expired = append(expired, notification)
+
+			if limit > 0 && len(expired) >= limit {
+				break
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return expired, nil
+}
+
+func (r *BoltRepository) FindUnreadByCollapseKey(ctx context.Context, userID string, collapseKey string) (*domain.Notification, error) {
+	var notification *domain.Notification
+
+	err := r.db.View(func(tx *bolt.Tx) error {
+		id := tx.Bucket(collapseIndexBucket).Get(collapseIndexKey(userID, collapseKey))
+		if id == nil {
+			return domain.ErrNotFound
+		}
+
+		var err error
+		notification, err = getNotification(tx, string(id))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return notification, nil
+}
+
+func (r *BoltRepository) Delete(ctx context.Context, id string) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		notification, err := getNotification(tx, id)
+		if err != nil {
+			return err
+		}
+
+		if err := deleteIndexes(tx, notification); err != nil {
+			return err
+		}
+
+		return tx.Bucket(notificationsBucket).Delete([]byte(id))
+	})
+}
+
+func putNotification(tx *bolt.Tx, notification *domain.Notification) error {
+	if existing, err := getNotification(tx, notification.ID); err == nil {
+		if err := deleteIndexes(tx, existing); err != nil {
+			return err
+		}
+	} else if err != domain.ErrNotFound {
+		return err
+	}
+
+	data, err := json.Marshal(notification)
+	if err != nil {
+		return fmt.Errorf("ошибка сериализации уведомления: %w", err)
+	}
+
+	if err := tx.Bucket(notificationsBucket).Put([]byte(notification.ID), data); err != nil {
+		return err
+	}
+
+	if err := tx.Bucket(userIndexBucket).Put(userIndexKey(notification), nil); err != nil {
+		return err
+	}
+
+	if notification.ExpiresAt != nil {
+		if err := tx.Bucket(expiryIndexBucket).Put(expiryIndexKey(notification), nil); err != nil {
+			return err
+		}
+	}
+
+	// Индекс ключей схлопывания содержит только непрочитанные уведомления
+	if notification.CollapseKey != "" && !notification.IsRead {
+		key := collapseIndexKey(notification.UserID, notification.CollapseKey)
+		if err := tx.Bucket(collapseIndexBucket).Put(key, []byte(notification.ID)); err != nil {
+			return err
+		}
+	}
+
+	return tx.Bucket(createdIndexBucket).Put(createdIndexKey(notification), nil)
+}
+
+func getNotification(tx *bolt.Tx, id string) (*domain.Notification, error) {
+	data := tx.Bucket(notificationsBucket).Get([]byte(id))
+	if data == nil {
+		return nil, domain.ErrNotFound
+	}
+
+	var notification domain.Notification
+	if err := json.Unmarshal(data, &notification); err != nil {
+		return nil, fmt.Errorf("ошибка чтения уведомления %s: %w", id, err)
+	}
+
+	return &notification, nil
+}
+
+func deleteIndexes(tx *bolt.Tx, notification *domain.Notification) error {
+	if err := tx.Bucket(userIndexBucket).Delete(userIndexKey(notification)); err != nil {
+		return err
+	}
+
+	if notification.ExpiresAt != nil {
+		if err := tx.Bucket(expiryIndexBucket).Delete(expiryIndexKey(notification)); err != nil {
+			return err
+		}
+	}
+
+	if notification.CollapseKey != "" {
+		key := collapseIndexKey(notification.UserID, notification.CollapseKey)
+		bucket := tx.Bucket(collapseIndexBucket)
+		// Ключ мог уже перейти к более новому уведомлению
+		if bytes.Equal(bucket.Get(key), []byte(notification.ID)) {
+			if err := bucket.Delete(key); err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Bucket(createdIndexBucket).Delete(createdIndexKey(notification))
+}
+
+// Ключи индексов упорядочены по времени: <userID>\x00<created_at><id>,
+// <created_at><id> и <expires_at><id>, где время - наносекунды в big-endian.
+// Индекс схлопывания хранит <userID>\x00<collapse_key> -> <id>.
+func userIndexPrefix(userID string) []byte {
+	return append([]byte(userID), 0)
+}
+
+func userIndexKey(notification *domain.Notification) []byte {
+	key := userIndexPrefix(notification.UserID)
+	key = appendTime(key, notification.CreatedAt)
+	return append(key, notification.ID...)
+}
+
+func createdIndexKey(notification *domain.Notification) []byte {
+	key := appendTime(nil, notification.CreatedAt)
+	return append(key, notification.ID...)
+}
+
+func expiryIndexKey(notification *domain.Notification) []byte {
+	key := appendTime(nil, *notification.ExpiresAt)
+	return append(key, notification.ID...)
+}
+
+func collapseIndexKey(userID string, collapseKey string) []byte {
+	return append(userIndexPrefix(userID), collapseKey...)
+}
+
+func appendTime(key []byte, t time.Time) []byte {
+	return binary.BigEndian.AppendUint64(key, uint64(t.UnixNano()))
+}
@@ -0,0 +1,266 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+	bolt "go.etcd.io/bbolt"
+)
+
+// TestBoltRepositoryIndexes проверяет, что после каждой операции индексы
+// содержат ровно ключи сохранённых уведомлений: устаревший ключ вернул бы
+// удалённое уведомление или старую версию в FindByUserID и FindExpired.
+func TestBoltRepositoryIndexes(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+	expiresAt := now.Add(time.Hour)
+	laterExpiry := now.Add(2 * time.Hour)
+
+	collapsed := func(id string, createdAt time.Time) *domain.Notification {
+		notification := testNotification(id, createdAt)
+		notification.CollapseKey = "order-42"
+		notification.ExpiresAt = &expiresAt
+		return notification
+	}
+
+	tests := []struct {
+		name string
+		run  func(t *testing.T, repository *BoltRepository)
+		// want - уведомления, которые должны остаться в индексах
+		want []*domain.Notification
+		// wantCollapsed - ID, на который указывает ключ схлопывания order-42
+		// пользователя collapseUserID (по умолчанию user-1)
+		wantCollapsed  string
+		collapseUserID string
+	}{
+		{
+			name: "replace moves keys",
+			run: func(t *testing.T, repository *BoltRepository) {
+				saveAll(t, repository, collapsed("n1", now))
+
+				replaced := collapsed("n1", now.Add(time.Minute))
+				replaced.UserID = "user-2"
+				replaced.ExpiresAt = &laterExpiry
+				saveAll(t, repository, replaced)
+			},
+			want: func() []*domain.Notification {
+				replaced := collapsed("n1", now.Add(time.Minute))
+				replaced.UserID = "user-2"
+				replaced.ExpiresAt = &laterExpiry
+				return []*domain.Notification{replaced}
+			}(),
+			// Уведомление перешло к другому пользователю вместе с ключом
+			wantCollapsed:  "n1",
+			collapseUserID: "user-2",
+		},
+		{
+			name: "read drops collapse key",
+			run: func(t *testing.T, repository *BoltRepository) {
+				notification := collapsed("n1", now)
+				saveAll(t, repository, notification)
+
+				notification.IsRead = true
+				if err := repository.Update(ctx, notification); err != nil {
+					t.Fatalf("Update: %v", err)
+				}
+			},
+			want: func() []*domain.Notification {
+				notification := collapsed("n1", now)
+				notification.IsRead = true
+				return []*domain.Notification{notification}
+			}(),
+		},
+		{
+			name: "delete older keeps newer collapse key",
+			run: func(t *testing.T, repository *BoltRepository) {
+				saveAll(t, repository, collapsed("old", now), collapsed("new", now.Add(time.Minute)))
+
+				if err := repository.Delete(ctx, "old"); err != nil {
+					t.Fatalf("Delete: %v", err)
+				}
+			},
+			want:          []*domain.Notification{collapsed("new", now.Add(time.Minute))},
+			wantCollapsed: "new",
+		},
+		{
+			name: "delete removes all keys",
+			run: func(t *testing.T, repository *BoltRepository) {
+				saveAll(t, repository, collapsed("n1", now))
+
+				if err := repository.Delete(ctx, "n1"); err != nil {
+					t.Fatalf("Delete: %v", err)
+				}
+				if err := repository.Delete(ctx, "n1"); !errors.Is(err, domain.ErrNotFound) {
+					t.Errorf("повторный Delete = %v, want ErrNotFound", err)
+				}
+			},
+		},
+		{
+			name: "purge expired",
+			run: func(t *testing.T, repository *BoltRepository) {
+				saveAll(t, repository, collapsed("expired", now.Add(-2*time.Hour)), testNotification("forever", now))
+
+				expired, err := repository.FindExpired(ctx, expiresAt, 0)
+				if err != nil {
+					t.Fatalf("FindExpired: %v", err)
+				}
+				assertIDs(t, idsOf(expired, notificationID), "expired")
+
+				for _, notification := range expired {
+					if err := repository.Delete(ctx, notification.ID); err != nil {
+						t.Fatalf("Delete: %v", err)
+					}
+				}
+
+				expired, err = repository.FindExpired(ctx, expiresAt, 0)
+				if err != nil {
+					t.Fatalf("повторный FindExpired: %v", err)
+				}
+				assertIDs(t, idsOf(expired, notificationID))
+			},
+			want: []*domain.Notification{testNotification("forever", now)},
+		},
+		{
+			name: "expiry cleared",
+			run: func(t *testing.T, repository *BoltRepository) {
+				notification := collapsed("n1", now)
+				saveAll(t, repository, notification)
+
+				notification.ExpiresAt = nil
+				if err := repository.Update(ctx, notification); err != nil {
+					t.Fatalf("Update: %v", err)
+				}
+			},
+			want: func() []*domain.Notification {
+				notification := collapsed("n1", now)
+				notification.ExpiresAt = nil
+				return []*domain.Notification{notification}
+			}(),
+			wantCollapsed: "n1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			database := openTestBoltDatabase(t, filepath.Join(t.TempDir(), "notifications.db"))
+			repository, err := NewBoltRepository(database, newTestLogger(t))
+			mustOpen(t, err)
+
+			tt.run(t, repository)
+
+			var users, created, expiry []string
+			for _, notification := range tt.want {
+				users = append(users, string(userIndexKey(notification)))
+				created = append(created, string(createdIndexKey(notification)))
+				if notification.ExpiresAt != nil {
+					expiry = append(expiry, string(expiryIndexKey(notification)))
+				}
+			}
+
+			assertBoltKeys(t, database, userIndexBucket, users...)
+			assertBoltKeys(t, database, createdIndexBucket, created...)
+			assertBoltKeys(t, database, expiryIndexBucket, expiry...)
+
+			userID := tt.collapseUserID
+			if userID == "" {
+				userID = "user-1"
+			}
+
+			found, err := repository.FindUnreadByCollapseKey(ctx, userID, "order-42")
+			switch {
+			case tt.wantCollapsed == "" && !errors.Is(err, domain.ErrNotFound):
+				t.Errorf("FindUnreadByCollapseKey = %v, %v, want ErrNotFound", found, err)
+			case tt.wantCollapsed != "" && (err != nil || found.ID != tt.wantCollapsed):
+				t.Errorf("FindUnreadByCollapseKey = %v, %v, want %s", found, err, tt.wantCollapsed)
+			}
+		})
+	}
+}
+
+func TestBoltDatabaseBackup(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+	dir := t.TempDir()
+
+	database := openTestBoltDatabase(t, filepath.Join(dir, "notifications.db"))
+	repository, err := NewBoltRepository(database, newTestLogger(t))
+	mustOpen(t, err)
+
+	expiresAt := now.Add(-time.Minute)
+	expired := testNotification("expired", now.Add(-time.Hour))
+	expired.ExpiresAt = &expiresAt
+	saveAll(t, repository, expired, testNotification("n1", now))
+
+	var backup bytes.Buffer
+	written, err := database.Backup(&backup)
+	if err != nil {
+		t.Fatalf("Backup: %v", err)
+	}
+	if written != int64(backup.Len()) {
+		t.Errorf("Backup = %d байт, записано %d", written, backup.Len())
+	}
+
+	// Запись после снимка в него не попадает
+	saveAll(t, repository, testNotification("after", now.Add(time.Minute)))
+
+	path := filepath.Join(dir, "restored.db")
+	if err := os.WriteFile(path, backup.Bytes(), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	restored, err := NewBoltRepository(openTestBoltDatabase(t, path), newTestLogger(t))
+	mustOpen(t, err)
+
+	notifications, err := restored.FindByUserID(ctx, "user-1")
+	if err != nil {
+		t.Fatalf("FindByUserID: %v", err)
+	}
+	assertIDs(t, idsOf(notifications, notificationID), "expired", "n1")
+
+	found, err := restored.FindExpired(ctx, now, 0)
+	if err != nil {
+		t.Fatalf("FindExpired: %v", err)
+	}
+	assertIDs(t, idsOf(found, notificationID), "expired")
+}
+
+func saveAll(t *testing.T, repository domain.NotificationRepository, notifications ...*domain.Notification) {
+	t.Helper()
+
+	for _, notification := range notifications {
+		if err := repository.Save(context.Background(), notification); err != nil {
+			t.Fatalf("Save(%s): %v", notification.ID, err)
+		}
+	}
+}
+
+func assertBoltKeys(t *testing.T, database *BoltDatabase, bucket []byte, want ...string) {
+	t.Helper()
+
+	var got []string
+	err := database.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).ForEach(func(key, _ []byte) error {
+			got = append(got, string(key))
+			return nil
+		})
+	})
+	if err != nil {
+		t.Fatalf("чтение бакета %s: %v", bucket, err)
+	}
+
+	if len(got) != len(want) {
+		t.Errorf("%s: %d ключей, want %d", bucket, len(got), len(want))
+		return
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Errorf("%s: ключ %q, want %q", bucket, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	scheduledBucket      = []byte("scheduled_notifications")
+	scheduledIndexBucket = []byte("scheduled_notifications_by_send_at")
+)
+
+// BoltScheduleStore хранит отложенные уведомления во встроенной базе. Файл
+// базы открывает только один процесс, поэтому захваты ведутся в памяти.
+type BoltScheduleStore struct {
+	db         *bolt.DB
+	claims     map[string]time.Time
+	claimsLock sync.Mutex
+}
+
+func NewBoltScheduleStore(database *BoltDatabase) (*BoltScheduleStore, error) {
+	if err := database.createBuckets(scheduledBucket, scheduledIndexBucket); err != nil {
+		return nil, err
+	}
+
+	return &BoltScheduleStore{
+		db:     database.db,
+		claims: make(map[string]time.Time),
+	}, nil
+}
+
+func (s *BoltScheduleStore) Add(ctx context.Context, item *domain.ScheduledNotification) error {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return fmt.Errorf("ошибка сериализации отложенного уведомления: %w", err)
+	}
+
+	s.release(item.ID)
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if existing, err := getScheduled(tx, item.ID); err == nil {
+			if err := tx.Bucket(scheduledIndexBucket).Delete(scheduledIndexKey(existing)); err != nil {
+				return err
+			}
+		} else if err != domain.ErrNotFound {
+			return err
+		}
+
+		if err := tx.Bucket(scheduledBucket).Put([]byte(item.ID), data); err != nil {
+			return err
+		}
+
+		return tx.Bucket(scheduledIndexBucket).Put(scheduledIndexKey(item), nil)
+	})
+}
+
+func (s *BoltScheduleStore) Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*domain.ScheduledNotification, error) {
+	due := []*domain.ScheduledNotification{}
+
+	s.claimsLock.Lock()
+	defer s.claimsLock.Unlock()
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bound := appendTime(nil, now)
+		cursor := tx.Bucket(scheduledIndexBucket).Cursor()
+
+		for key, _ := cursor.First(); key != nil && bytes.Compare(key[:8], bound) <= 0; key, _ = cursor.Next() {
+			id := string(key[8:])
+			if claimedUntil, ok := s.claims[id]; ok && claimedUntil.After(now) {
+				continue
+			}
+
+			item, err := getScheduled(tx, id)
+			if err != nil {
+				return err
+			}
+			due = append(due, item)
+
+			if limit > 0 && len(due) >= limit {
+				break
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range due {
+		s.claims[item.ID] = now.Add(lease)
+	}
+
+	return due, nil
+}
+
+func (s *BoltScheduleStore) FindByID(ctx context.Context, id string) (*domain.ScheduledNotification, error) {
+	var item *domain.ScheduledNotification
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		item, err = getScheduled(tx, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
+
+func (s *BoltScheduleStore) FindByUserID(ctx context.Context, userID string) ([]*domain.ScheduledNotification, error) {
+	items := []*domain.ScheduledNotification{}
+
+	// Отложенных уведомлений немного, поэтому отдельный индекс по пользователю не ведётся
+	err := s.db.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(scheduledIndexBucket).Cursor()
+
+		for key, _ := cursor.First(); key != nil; key, _ = cursor.Next() {
+			item, err := getScheduled(tx, string(key[8:]))
+			if err != nil {
+				return err
+			}
+			if item.UserID == userID {
+				items = append(items, item)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+func (s *BoltScheduleStore) Remove(ctx context.Context, id string) error {
+	s.release(id)
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		item, err := getScheduled(tx, id)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Bucket(scheduledIndexBucket).Delete(scheduledIndexKey(item)); err != nil {
+			return err
+		}
+
+		return tx.Bucket(scheduledBucket).Delete([]byte(id))
+	})
+}
+
+func (s *BoltScheduleStore) release(id string) {
+	s.claimsLock.Lock()
+	defer s.claimsLock.Unlock()
+
+	delete(s.claims, id)
+}
+
+func getScheduled(tx *bolt.Tx, id string) (*domain.ScheduledNotification, error) {
+	data := tx.Bucket(scheduledBucket).Get([]byte(id))
+	if data == nil {
+		return nil, domain.ErrNotFound
+	}
+
+	var item domain.ScheduledNotification
+	if err := json.Unmarshal(data, &item); err != nil {
+		return nil, fmt.Errorf("ошибка чтения отложенного уведомления %s: %w", id, err)
+	}
+
+	return &item, nil
+}
+
+func scheduledIndexKey(item *domain.ScheduledNotification) []byte {
+	key := appendTime(nil, item.SendAt)
+	return append(key, item.ID...)
+}
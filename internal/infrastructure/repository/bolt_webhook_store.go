@@ -0,0 +1,325 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	webhookBucket = []byte("webhooks")
+
+	webhookDeliveryBucket = []byte("webhook_deliveries")
+	// Ключ: <время попытки><id>, только для ожидающих доставок
+	webhookDueIndexBucket = []byte("webhook_deliveries_due")
+	// Ключ: <webhookID>\x00<время создания><id>
+	webhookDeliveryIndexBucket = []byte("webhook_deliveries_by_webhook")
+)
+
+type BoltWebhookStore struct {
+	db *bolt.DB
+}
+
+func NewBoltWebhookStore(database *BoltDatabase) (*BoltWebhookStore, error) {
+	if err := database.createBuckets(webhookBucket); err != nil {
+		return nil, err
+	}
+
+	return &BoltWebhookStore{db: database.db}, nil
+}
+
+func (s *BoltWebhookStore) Save(ctx context.Context, webhook *domain.Webhook) error {
+	data, err := json.Marshal(webhook)
+	if err != nil {
+		return fmt.Errorf("ошибка сериализации вебхука: %w", err)
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(webhookBucket).Put([]byte(webhook.ID), data)
+	})
+}
+
+func (s *BoltWebhookStore) FindByID(ctx context.Context, id string) (*domain.Webhook, error) {
+	var webhook domain.Webhook
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(webhookBucket).Get([]byte(id))
+		if data == nil {
+			return domain.ErrNotFound
+		}
+
+		if err := json.Unmarshal(data, &webhook); err != nil {
+			return fmt.Errorf("ошибка чтения вебхука: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &webhook, nil
+}
+
+func (s *BoltWebhookStore) FindAll(ctx context.Context) ([]*domain.Webhook, error) {
+	webhooks := []*domain.Webhook{}
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(webhookBucket).ForEach(func(key, data []byte) error {
+			var webhook domain.Webhook
+			if err := json.Unmarshal(data, &webhook); err != nil {
+				return fmt.Errorf("ошибка чтения вебхука: %w", err)
+			}
+
+			webhooks = append(webhooks, &webhook)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(webhooks, func(i, j int) bool {
+		return webhooks[i].CreatedAt.Before(webhooks[j].CreatedAt)
+	})
+
+	return webhooks, nil
+}
+
+func (s *BoltWebhookStore) Delete(ctx context.Context, id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(webhookBucket)
+		if bucket.Get([]byte(id)) == nil {
+			return domain.ErrNotFound
+		}
+
+		return bucket.Delete([]byte(id))
+	})
+}
+
+// BoltWebhookDeliveryStore хранит журнал доставок во встроенной базе. Файл
+// базы открывает только один процесс, поэтому захваты ведутся в памяти.
+type BoltWebhookDeliveryStore struct {
+	db         *bolt.DB
+	claims     map[string]time.Time
+	claimsLock sync.Mutex
+}
+
+func NewBoltWebhookDeliveryStore(database *BoltDatabase) (*BoltWebhookDeliveryStore, error) {
+	if err := database.createBuckets(webhookDeliveryBucket, webhookDueIndexBucket, webhookDeliveryIndexBucket); err != nil {
+		return nil, err
+	}
+
+	return &BoltWebhookDeliveryStore{
+		db:     database.db,
+		claims: make(map[string]time.Time),
+	}, nil
+}
+
+func (s *BoltWebhookDeliveryStore) Save(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	data, err := json.Marshal(delivery)
+	if err != nil {
+		return fmt.Errorf("ошибка сериализации доставки вебхука: %w", err)
+	}
+
+	s.claimsLock.Lock()
+	delete(s.claims, delivery.ID)
+	s.claimsLock.Unlock()
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if existing, err := getWebhookDelivery(tx, delivery.ID); err == nil {
+			if err := deleteWebhookDeliveryIndexes(tx, existing); err != nil {
+				return err
+			}
+		} else if err != domain.ErrNotFound {
+			return err
+		}
+
+		if err := tx.Bucket(webhookDeliveryBucket).Put([]byte(delivery.ID), data); err != nil {
+			return err
+		}
+
+		if delivery.Status == domain.WebhookPending {
+			if err := tx.Bucket(webhookDueIndexBucket).Put(webhookDueKey(delivery), nil); err != nil {
+				return err
+			}
+		}
+
+		return tx.Bucket(webhookDeliveryIndexBucket).Put(webhookDeliveryKey(delivery), nil)
+	})
+}
+
+func (s *BoltWebhookDeliveryStore) FindByID(ctx context.Context, id string) (*domain.WebhookDelivery, error) {
+	var delivery *domain.WebhookDelivery
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		delivery, err = getWebhookDelivery(tx, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return delivery, nil
+}
+
+func (s *BoltWebhookDeliveryStore) FindByWebhookID(ctx context.Context, webhookID string, limit int) ([]*domain.WebhookDelivery, error) {
+	deliveries := []*domain.WebhookDelivery{}
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		prefix := webhookDeliveryPrefix(webhookID)
+		cursor := tx.Bucket(webhookDeliveryIndexBucket).Cursor()
+
+		// Обход от новых к старым: встаём за последним ключом подписки и идём назад
+		key, _ := cursor.Seek(append([]byte(webhookID), 1))
+		if key == nil {
+			key, _ = cursor.Last()
+		} else {
+			key, _ = cursor.Prev()
+		}
+
+		for ; key != nil && bytes.HasPrefix(key, prefix); key, _ = cursor.Prev() {
+			delivery, err := getWebhookDelivery(tx, string(key[len(prefix)+8:]))
+			if err != nil {
+				return err
+			}
+			deliveries = append(deliveries, delivery)
+
+			if limit > 0 && len(deliveries) >= limit {
+				break
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+func (s *BoltWebhookDeliveryStore) Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*domain.WebhookDelivery, error) {
+	due := []*domain.WebhookDelivery{}
+
+	s.claimsLock.Lock()
+	defer s.claimsLock.Unlock()
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bound := appendTime(nil, now)
+		cursor := tx.Bucket(webhookDueIndexBucket).Cursor()
+
+		for key, _ := cursor.First(); key != nil && bytes.Compare(key[:8], bound) <= 0; key, _ = cursor.Next() {
+			id := string(key[8:])
+			if claimedUntil, ok := s.claims[id]; ok && claimedUntil.After(now) {
+				continue
+			}
+
+			delivery, err := getWebhookDelivery(tx, id)
+			if err != nil {
+				return err
+			}
+			due = append(due, delivery)
+
+			if limit > 0 && len(due) >= limit {
+				break
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, delivery := range due {
+		s.claims[delivery.ID] = now.Add(lease)
+	}
+
+	return due, nil
+}
+
+// DeleteFinishedBefore просматривает весь журнал: очистка выполняется редко
+// и в одной транзакции.
+func (s *BoltWebhookDeliveryStore) DeleteFinishedBefore(ctx context.Context, before time.Time) (int, error) {
+	deleted := 0
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		expired := []*domain.WebhookDelivery{}
+
+		err := tx.Bucket(webhookDeliveryBucket).ForEach(func(key, data []byte) error {
+			var delivery domain.WebhookDelivery
+			if err := json.Unmarshal(data, &delivery); err != nil {
+				return fmt.Errorf("ошибка чтения доставки вебхука %s: %w", key, err)
+			}
+
+			if delivery.Status != domain.WebhookPending && delivery.CreatedAt.Before(before) {
+				expired = append(expired, &delivery)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, delivery := range expired {
+			if err := deleteWebhookDeliveryIndexes(tx, delivery); err != nil {
+				return err
+			}
+			if err := tx.Bucket(webhookDeliveryBucket).Delete([]byte(delivery.ID)); err != nil {
+				return err
+			}
+		}
+
+		deleted = len(expired)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return deleted, nil
+}
+
+func getWebhookDelivery(tx *bolt.Tx, id string) (*domain.WebhookDelivery, error) {
+	data := tx.Bucket(webhookDeliveryBucket).Get([]byte(id))
+	if data == nil {
+		return nil, domain.ErrNotFound
+	}
+
+	var delivery domain.WebhookDelivery
+	if err := json.Unmarshal(data, &delivery); err != nil {
+		return nil, fmt.Errorf("ошибка чтения доставки вебхука %s: %w", id, err)
+	}
+
+	return &delivery, nil
+}
+
+func deleteWebhookDeliveryIndexes(tx *bolt.Tx, delivery *domain.WebhookDelivery) error {
+	if err := tx.Bucket(webhookDueIndexBucket).Delete(webhookDueKey(delivery)); err != nil {
+		return err
+	}
+
+	return tx.Bucket(webhookDeliveryIndexBucket).Delete(webhookDeliveryKey(delivery))
+}
+
+func webhookDueKey(delivery *domain.WebhookDelivery) []byte {
+	key := appendTime(nil, delivery.NextAttemptAt)
+	return append(key, delivery.ID...)
+}
+
+func webhookDeliveryKey(delivery *domain.WebhookDelivery) []byte {
+	key := appendTime(webhookDeliveryPrefix(delivery.WebhookID), delivery.CreatedAt)
+	return append(key, delivery.ID...)
+}
+
+func webhookDeliveryPrefix(webhookID string) []byte {
+	return append([]byte(webhookID), 0)
+}
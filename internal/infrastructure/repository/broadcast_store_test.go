@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+)
+
+func testBroadcast(id string, createdAt time.Time, expiresAt time.Time) *domain.Broadcast {
+	return &domain.Broadcast{
+		ID:        id,
+		Type:      domain.NotificationType("system"),
+		Title:     "Title " + id,
+		Content:   "Content " + id,
+		Audience:  &domain.Audience{Platforms: []string{"ios"}},
+		CreatedAt: createdAt,
+		ExpiresAt: &expiresAt,
+	}
+}
+
+func TestBroadcastStore(t *testing.T) {
+	runStoreTests(t, []storeTest{
+		{
+			name: "save and find",
+			run: func(t *testing.T, backend *testBackend) {
+				ctx, now, store := backend.ctx, backend.now, backend.broadcasts(t)
+
+				broadcast := testBroadcast("b1", now, now.Add(time.Hour))
+				if err := store.Save(ctx, broadcast); err != nil {
+					t.Fatalf("Save: %v", err)
+				}
+
+				found, err := store.FindByID(ctx, "b1")
+				if err != nil {
+					t.Fatalf("FindByID: %v", err)
+				}
+				if found.Title != broadcast.Title || found.Audience == nil || len(found.Audience.Platforms) != 1 ||
+					found.ExpiresAt == nil || !found.ExpiresAt.Equal(*broadcast.ExpiresAt) {
+					t.Errorf("объявление = %+v, want %+v", found, broadcast)
+				}
+			},
+		},
+		{
+			name: "save without expiry",
+			run: func(t *testing.T, backend *testBackend) {
+				ctx, now, store := backend.ctx, backend.now, backend.broadcasts(t)
+
+				broadcast := testBroadcast("b1", now, now)
+				broadcast.ExpiresAt = nil
+				if err := store.Save(ctx, broadcast); !errors.Is(err, domain.ErrInvalidInput) {
+					t.Errorf("Save = %v, want ErrInvalidInput", err)
+				}
+			},
+		},
+		{
+			name: "find active",
+			run: func(t *testing.T, backend *testBackend) {
+				ctx, now, store := backend.ctx, backend.now, backend.broadcasts(t)
+
+				for _, broadcast := range []*domain.Broadcast{
+					testBroadcast("second", now.Add(-time.Minute), now.Add(time.Hour)),
+					testBroadcast("first", now.Add(-time.Hour), now.Add(time.Hour)),
+					testBroadcast("expired", now.Add(-time.Hour), now),
+				} {
+					if err := store.Save(ctx, broadcast); err != nil {
+						t.Fatalf("Save: %v", err)
+					}
+				}
+
+				active, err := store.FindActive(ctx, now)
+				if err != nil {
+					t.Fatalf("FindActive: %v", err)
+				}
+				assertIDs(t, idsOf(active, broadcastID), "first", "second")
+
+				// Объявление истекает в момент expires_at
+				active, err = store.FindActive(ctx, now.Add(time.Hour))
+				if err != nil {
+					t.Fatalf("FindActive: %v", err)
+				}
+				assertIDs(t, idsOf(active, broadcastID))
+			},
+		},
+		{
+			name: "delete",
+			run: func(t *testing.T, backend *testBackend) {
+				ctx, now, store := backend.ctx, backend.now, backend.broadcasts(t)
+
+				if err := store.Save(ctx, testBroadcast("b1", now, now.Add(time.Hour))); err != nil {
+					t.Fatalf("Save: %v", err)
+				}
+				if err := store.Delete(ctx, "b1"); err != nil {
+					t.Fatalf("Delete: %v", err)
+				}
+				if err := store.Delete(ctx, "b1"); !errors.Is(err, domain.ErrNotFound) {
+					t.Errorf("повторный Delete = %v, want ErrNotFound", err)
+				}
+				if _, err := store.FindByID(ctx, "b1"); !errors.Is(err, domain.ErrNotFound) {
+					t.Errorf("FindByID = %v, want ErrNotFound", err)
+				}
+			},
+		},
+		{
+			name: "delete expired",
+			run: func(t *testing.T, backend *testBackend) {
+				ctx, now, store := backend.ctx, backend.now, backend.broadcasts(t)
+
+				for _, broadcast := range []*domain.Broadcast{
+					testBroadcast("active", now, now.Add(time.Hour)),
+					testBroadcast("expired", now.Add(-time.Hour), now),
+					testBroadcast("old", now.Add(-2*time.Hour), now.Add(-time.Hour)),
+				} {
+					if err := store.Save(ctx, broadcast); err != nil {
+						t.Fatalf("Save: %v", err)
+					}
+				}
+
+				deleted, err := store.DeleteExpired(ctx, now)
+				if err != nil {
+					t.Fatalf("DeleteExpired: %v", err)
+				}
+				if deleted != 2 {
+					t.Errorf("DeleteExpired = %d, want 2", deleted)
+				}
+				active, err := store.FindActive(ctx, now.Add(-2*time.Hour))
+				if err != nil {
+					t.Fatalf("FindActive: %v", err)
+				}
+				assertIDs(t, idsOf(active, broadcastID), "active")
+			},
+		},
+	})
+}
+
+func broadcastID(broadcast *domain.Broadcast) string {
+	return broadcast.ID
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+)
+
+type MemoryBroadcastStore struct {
+	broadcasts map[string]*domain.Broadcast
+	mutex      sync.RWMutex
+}
+
+func NewMemoryBroadcastStore() *MemoryBroadcastStore {
+	return &MemoryBroadcastStore{
+		broadcasts: make(map[string]*domain.Broadcast),
+	}
+}
+
+func (s *MemoryBroadcastStore) Save(ctx context.Context, broadcast *domain.Broadcast) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.broadcasts[broadcast.ID] = broadcast
+	return nil
+}
+
+func (s *MemoryBroadcastStore) FindByID(ctx context.Context, id string) (*domain.Broadcast, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	broadcast, ok := s.broadcasts[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+
+	return broadcast, nil
+}
+
+func (s *MemoryBroadcastStore) FindActive(ctx context.Context, now time.Time) ([]*domain.Broadcast, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	active := []*domain.Broadcast{}
+	for _, broadcast := range s.broadcasts {
+		if !broadcast.IsExpired(now) {
+			active = append(active, broadcast)
+		}
+	}
+
+	sortBroadcasts(active)
+	return active, nil
+}
+
+func (s *MemoryBroadcastStore) Delete(ctx context.Context, id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.broadcasts[id]; !ok {
+		return domain.ErrNotFound
+	}
+
+	delete(s.broadcasts, id)
+	return nil
+}
+
+func (s *MemoryBroadcastStore) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	deleted := 0
+	for id, broadcast := range s.broadcasts {
+		if broadcast.IsExpired(now) {
+			delete(s.broadcasts, id)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
+
+func sortBroadcasts(broadcasts []*domain.Broadcast) {
+	sort.Slice(broadcasts, func(i, j int) bool {
+		return broadcasts[i].CreatedAt.Before(broadcasts[j].CreatedAt)
+	})
+}
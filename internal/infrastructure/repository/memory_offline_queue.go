@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"context"
+	"sync"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+)
+
+type MemoryOfflineQueue struct {
+	items map[string][]*domain.Notification
+	mutex sync.Mutex
+}
+
+func NewMemoryOfflineQueue() *MemoryOfflineQueue {
+	return &MemoryOfflineQueue{
+		items: make(map[string][]*domain.Notification),
+	}
+}
+
+func (q *MemoryOfflineQueue) Push(ctx context.Context, notification *domain.Notification) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.items[notification.UserID] = append(q.items[notification.UserID], notification)
+	return nil
+}
+
+func (q *MemoryOfflineQueue) PopAll(ctx context.Context, userID string) ([]*domain.Notification, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	items := q.items[userID]
+	delete(q.items, userID)
+
+	if items == nil {
+		items = []*domain.Notification{}
+	}
+
+	return items, nil
+}
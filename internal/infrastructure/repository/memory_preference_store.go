@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"sync"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+)
+
+type MemoryPreferenceStore struct {
+	preferences map[string]*domain.Preferences
+	mutex       sync.RWMutex
+}
+
+func NewMemoryPreferenceStore() *MemoryPreferenceStore {
+	return &MemoryPreferenceStore{
+		preferences: make(map[string]*domain.Preferences),
+	}
+}
+
+func (s *MemoryPreferenceStore) Get(ctx context.Context, userID string) (*domain.Preferences, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	preferences, ok := s.preferences[userID]
+	if !ok {
+		return domain.NewPreferences(userID), nil
+	}
+
+	return clonePreferences(preferences), nil
+}
+
+func (s *MemoryPreferenceStore) Save(ctx context.Context, preferences *domain.Preferences) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.preferences[preferences.UserID] = clonePreferences(preferences)
+	return nil
+}
+
+// clonePreferences защищает хранимые карты от изменения вызывающим кодом.
+func clonePreferences(preferences *domain.Preferences) *domain.Preferences {
+	clone := *preferences
+	clone.Types = make(map[domain.NotificationType]bool, len(preferences.Types))
+	for notificationType, enabled := range preferences.Types {
+		clone.Types[notificationType] = enabled
+	}
+	clone.Categories = make(map[string]bool, len(preferences.Categories))
+	for category, enabled := range preferences.Categories {
+		clone.Categories[category] = enabled
+	}
+	if preferences.QuietHours != nil {
+		quietHours := *preferences.QuietHours
+		clone.QuietHours = &quietHours
+	}
+
+	return &clone
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+)
+
+type MemoryPushStore struct {
+	subscriptions map[string]*domain.PushSubscription // по endpoint
+	mutex         sync.RWMutex
+}
+
+func NewMemoryPushStore() *MemoryPushStore {
+	return &MemoryPushStore{
+		subscriptions: make(map[string]*domain.PushSubscription),
+	}
+}
+
+func (s *MemoryPushStore) Save(ctx context.Context, subscription *domain.PushSubscription) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.subscriptions[subscription.Endpoint] = subscription
+	return nil
+}
+
+func (s *MemoryPushStore) FindByUserID(ctx context.Context, userID string) ([]*domain.PushSubscription, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	subscriptions := []*domain.PushSubscription{}
+	for _, subscription := range s.subscriptions {
+		if subscription.UserID == userID {
+			subscriptions = append(subscriptions, subscription)
+		}
+	}
+
+	sort.Slice(subscriptions, func(i, j int) bool {
+		return subscriptions[i].CreatedAt.Before(subscriptions[j].CreatedAt)
+	})
+
+	return subscriptions, nil
+}
+
+func (s *MemoryPushStore) Delete(ctx context.Context, userID string, endpoint string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	subscription, ok := s.subscriptions[endpoint]
+	if !ok || subscription.UserID != userID {
+		return domain.ErrNotFound
+	}
+
+	delete(s.subscriptions, endpoint)
+	return nil
+}
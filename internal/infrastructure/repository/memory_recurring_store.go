@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+)
+
+type MemoryRecurringStore struct {
+	jobs  map[string]*domain.RecurringJob
+	mutex sync.RWMutex
+}
+
+func NewMemoryRecurringStore() *MemoryRecurringStore {
+	return &MemoryRecurringStore{
+		jobs: make(map[string]*domain.RecurringJob),
+	}
+}
+
+func (s *MemoryRecurringStore) Save(ctx context.Context, job *domain.RecurringJob) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.jobs[job.ID] = job
+	return nil
+}
+
+func (s *MemoryRecurringStore) FindByID(ctx context.Context, id string) (*domain.RecurringJob, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+
+	return job, nil
+}
+
+func (s *MemoryRecurringStore) FindAll(ctx context.Context) ([]*domain.RecurringJob, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	jobs := make([]*domain.RecurringJob, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		jobs = append(jobs, job)
+	}
+
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.Before(jobs[j].CreatedAt)
+	})
+
+	return jobs, nil
+}
+
+func (s *MemoryRecurringStore) Advance(ctx context.Context, job *domain.RecurringJob, previous *time.Time) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	stored, ok := s.jobs[job.ID]
+	if !ok || domain.RunKey(stored.NextRunAt) != domain.RunKey(previous) {
+		return false, nil
+	}
+
+	s.jobs[job.ID] = job
+	return true, nil
+}
+
+func (s *MemoryRecurringStore) Delete(ctx context.Context, id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.jobs[id]; !ok {
+		return domain.ErrNotFound
+	}
+
+	delete(s.jobs, id)
+	return nil
+}
@@ -1,41 +1,102 @@
 package repository
 
 import (
+	"context"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+	"github.com/anatoly_dev/go-ws-notifications/internal/infrastructure/metrics"
 	"github.com/anatoly_dev/go-ws-notifications/pkg/logger"
 )
 
+// RetentionConfig ограничивает объём хранимых в памяти уведомлений.
+// Нулевое значение поля отключает соответствующее ограничение.
+type RetentionConfig struct {
+	MaxAge             time.Duration
+	MaxPerUser         int
+	MaxTotal           int
+	CompactionInterval time.Duration
+}
+
+// MemoryRepository хранит собственные копии уведомлений и отдаёт копии:
+// поля хранимых уведомлений меняются только под mutex.
 type MemoryRepository struct {
 	notifications map[string]*domain.Notification
 	userIndex     map[string][]*domain.Notification
 	mutex         sync.RWMutex
 	logger        *logger.Logger
+	retention     *RetentionConfig
+	stop          chan struct{}
+	wg            sync.WaitGroup
 }
 
-func NewMemoryRepository(logger *logger.Logger) *MemoryRepository {
+func NewMemoryRepository(retention *RetentionConfig, logger *logger.Logger) *MemoryRepository {
+	if retention == nil {
+		retention = &RetentionConfig{}
+	}
+
 	return &MemoryRepository{
 		notifications: make(map[string]*domain.Notification),
 		userIndex:     make(map[string][]*domain.Notification),
 		logger:        logger,
+		retention:     retention,
+		stop:          make(chan struct{}),
 	}
 }
 
-func (r *MemoryRepository) Save(notification *domain.Notification) error {
+// StartCompaction запускает фоновое применение политик хранения.
+func (r *MemoryRepository) StartCompaction() {
+	if r.retention.CompactionInterval <= 0 {
+		return
+	}
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+
+		ticker := time.NewTicker(r.retention.CompactionInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-r.stop:
+				return
+			case <-ticker.C:
+				r.Compact(time.Now())
+			}
+		}
+	}()
+}
+
+func (r *MemoryRepository) Close() error {
+	close(r.stop)
+	r.wg.Wait()
+	return nil
+}
+
+func (r *MemoryRepository) Save(ctx context.Context, notification *domain.Notification) error {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
 	r.logger.WithField("notificationID", notification.ID).Debug("Сохранение уведомления")
 
-	r.notifications[notification.ID] = notification
+	if existing, exists := r.notifications[notification.ID]; exists {
+		r.removeFromUserIndex(existing)
+	}
+
+	stored := cloneNotification(notification)
+	r.notifications[stored.ID] = stored
 
-	r.userIndex[notification.UserID] = append(r.userIndex[notification.UserID], notification)
+	r.userIndex[stored.UserID] = append(r.userIndex[stored.UserID], stored)
+
+	metrics.StoredNotifications.Set(float64(len(r.notifications)))
 
 	return nil
 }
 
-func (r *MemoryRepository) FindByID(id string) (*domain.Notification, error) {
+func (r *MemoryRepository) FindByID(ctx context.Context, id string) (*domain.Notification, error) {
 	r.mutex.RLock()
 	defer r.mutex.RUnlock()
 
@@ -44,10 +105,10 @@ func (r *MemoryRepository) FindByID(id string) (*domain.Notification, error) {
 		return nil, domain.ErrNotFound
 	}
 
-	return notification, nil
+	return cloneNotification(notification), nil
 }
 
-func (r *MemoryRepository) FindByUserID(userID string) ([]*domain.Notification, error) {
+func (r *MemoryRepository) FindByUserID(ctx context.Context, userID string) ([]*domain.Notification, error) {
 	r.mutex.RLock()
 	defer r.mutex.RUnlock()
 
@@ -56,26 +117,34 @@ func (r *MemoryRepository) FindByUserID(userID string) ([]*domain.Notification,
 		return []*domain.Notification{}, nil
 	}
 
-	result := make([]*domain.Notification, len(notifications))
-	copy(result, notifications)
+	result := make([]*domain.Notification, 0, len(notifications))
+	for _, notification := range notifications {
+		result = append(result, cloneNotification(notification))
+	}
 
 	return result, nil
 }
 
-func (r *MemoryRepository) Update(notification *domain.Notification) error {
+func (r *MemoryRepository) Update(ctx context.Context, notification *domain.Notification) error {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
-	_, exists := r.notifications[notification.ID]
+	existing, exists := r.notifications[notification.ID]
 	if !exists {
 		return domain.ErrNotFound
 	}
 
-	r.notifications[notification.ID] = notification
-	
-	for i, n := range r.userIndex[notification.UserID] {
-		if n.ID == notification.ID {
-			r.userIndex[notification.UserID][i] = notification
+	updated := cloneNotification(notification)
+	// Отметку доставки, сделанную после чтения уведомления, не затираем
+	if updated.DeliveredAt == nil {
+		updated.DeliveredAt = existing.DeliveredAt
+	}
+
+	r.notifications[updated.ID] = updated
+
+	for i, n := range r.userIndex[updated.UserID] {
+		if n.ID == updated.ID {
+			r.userIndex[updated.UserID][i] = updated
 			break
 		}
 	}
@@ -84,3 +153,183 @@ func (r *MemoryRepository) Update(notification *domain.Notification) error {
 
 	return nil
 }
+
+func (r *MemoryRepository) MarkDelivered(ctx context.Context, id string, at time.Time) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	existing, exists := r.notifications[id]
+	if !exists || existing.DeliveredAt != nil {
+		return nil
+	}
+
+	existing.DeliveredAt = &at
+
+	return nil
+}
+
+func (r *MemoryRepository) FindExpired(ctx context.Context, now time.Time, limit int) ([]*domain.Notification, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	expired := []*domain.Notification{}
+	for _, notification := range r.notifications {
+		if notification.IsExpired(now) {
+			expired = append(expired, cloneNotification(notification))
+			if limit > 0 && len(expired) >= limit {
+				break
+			}
+		}
+	}
+
+	return expired, nil
+}
+
+func (r *MemoryRepository) FindUnreadByCollapseKey(ctx context.Context, userID string, collapseKey string) (*domain.Notification, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	notifications := r.userIndex[userID]
+	for i := len(notifications) - 1; i >= 0; i-- {
+		if notifications[i].CollapseKey == collapseKey && !notifications[i].IsRead {
+			return cloneNotification(notifications[i]), nil
+		}
+	}
+
+	return nil, domain.ErrNotFound
+}
+
+func (r *MemoryRepository) Delete(ctx context.Context, id string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	notification, exists := r.notifications[id]
+	if !exists {
+		return domain.ErrNotFound
+	}
+
+	r.evict(notification)
+	metrics.StoredNotifications.Set(float64(len(r.notifications)))
+
+	return nil
+}
+
+// Compact удаляет уведомления старше MaxAge, затем сокращает историю
+// пользователей до MaxPerUser и общий объём до MaxTotal. В первую очередь
+// вытесняются самые старые прочитанные уведомления.
+func (r *MemoryRepository) Compact(now time.Time) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	evicted := map[string]int{}
+
+	if r.retention.MaxAge > 0 {
+		threshold := now.Add(-r.retention.MaxAge)
+		for _, notification := range r.notifications {
+			if notification.CreatedAt.Before(threshold) {
+				r.evict(notification)
+				evicted["max_age"]++
+			}
+		}
+	}
+
+	if r.retention.MaxPerUser > 0 {
+		for _, notifications := range r.userIndex {
+			excess := len(notifications) - r.retention.MaxPerUser
+			if excess <= 0 {
+				continue
+			}
+			for _, notification := range evictionOrder(notifications)[:excess] {
+				r.evict(notification)
+				evicted["max_per_user"]++
+			}
+		}
+	}
+
+	if r.retention.MaxTotal > 0 {
+		if excess := len(r.notifications) - r.retention.MaxTotal; excess > 0 {
+			all := make([]*domain.Notification, 0, len(r.notifications))
+			for _, notification := range r.notifications {
+				all = append(all, notification)
+			}
+			for _, notification := range evictionOrder(all)[:excess] {
+				r.evict(notification)
+				evicted["max_total"]++
+			}
+		}
+	}
+
+	metrics.StoredNotifications.Set(float64(len(r.notifications)))
+
+	total := 0
+	for reason, count := range evicted {
+		metrics.EvictedNotifications.WithLabelValues(reason).Add(float64(count))
+		total += count
+	}
+
+	if total > 0 {
+		r.logger.WithFields(map[string]interface{}{
+			"evicted": total,
+			"stored":  len(r.notifications),
+		}).Info("Применены политики хранения уведомлений")
+	}
+}
+
+func (r *MemoryRepository) evict(notification *domain.Notification) {
+	delete(r.notifications, notification.ID)
+	r.removeFromUserIndex(notification)
+}
+
+func (r *MemoryRepository) removeFromUserIndex(notification *domain.Notification) {
+	notifications := r.userIndex[notification.UserID]
+	for i, n := range notifications {
+		if n.ID == notification.ID {
+			notifications = append(notifications[:i], notifications[i+1:]...)
+			break
+		}
+	}
+
+	if len(notifications) == 0 {
+		delete(r.userIndex, notification.UserID)
+	} else {
+		r.userIndex[notification.UserID] = notifications
+	}
+}
+
+// cloneNotification защищает хранимое уведомление от изменения вызывающим
+// кодом без блокировки.
+func cloneNotification(notification *domain.Notification) *domain.Notification {
+	clone := *notification
+	if notification.GroupedIDs != nil {
+		clone.GroupedIDs = append([]string(nil), notification.GroupedIDs...)
+	}
+	clone.ExpiresAt = cloneTime(notification.ExpiresAt)
+	clone.SendAt = cloneTime(notification.SendAt)
+	clone.DeliveredAt = cloneTime(notification.DeliveredAt)
+
+	return &clone
+}
+
+func cloneTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	clone := *t
+	return &clone
+}
+
+// evictionOrder возвращает копию списка, отсортированную в порядке вытеснения:
+// сначала прочитанные, внутри группы - от старых к новым.
+func evictionOrder(notifications []*domain.Notification) []*domain.Notification {
+	ordered := make([]*domain.Notification, len(notifications))
+	copy(ordered, notifications)
+
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if ordered[i].IsRead != ordered[j].IsRead {
+			return ordered[i].IsRead
+		}
+		return ordered[i].CreatedAt.Before(ordered[j].CreatedAt)
+	})
+
+	return ordered
+}
@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMemoryRepositoryCopies(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	repository := NewMemoryRepository(nil, newTestLogger(t))
+
+	expiresAt := now.Add(time.Hour)
+	notification := testNotification("n1", now)
+	notificationExpiry := expiresAt
+	notification.ExpiresAt = &notificationExpiry
+	notification.GroupedIDs = []string{"a", "b"}
+	if err := repository.Save(ctx, notification); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	// Изменения сохранённого и полученного объектов не видны хранилищу
+	notification.Title = "changed"
+	notification.GroupedIDs[0] = "changed"
+	*notification.ExpiresAt = now
+
+	found, err := repository.FindByID(ctx, "n1")
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	found.IsRead = true
+
+	notifications, err := repository.FindByUserID(ctx, "user-1")
+	if err != nil {
+		t.Fatalf("FindByUserID: %v", err)
+	}
+	notifications[0].Content = "changed"
+
+	stored, err := repository.FindByID(ctx, "n1")
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	if stored.Title != "Title n1" || stored.Content != "Content n1" || stored.IsRead {
+		t.Errorf("хранимое уведомление изменено вызывающим кодом: %+v", stored)
+	}
+	if stored.GroupedIDs[0] != "a" || !stored.ExpiresAt.Equal(expiresAt) {
+		t.Errorf("GroupedIDs = %v, ExpiresAt = %v, want [a b], %v", stored.GroupedIDs, stored.ExpiresAt, expiresAt)
+	}
+
+	// Изменение попадает в хранилище только через Update
+	if err := repository.Update(ctx, found); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	stored, err = repository.FindByID(ctx, "n1")
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	if !stored.IsRead {
+		t.Error("IsRead = false после Update")
+	}
+}
+
+// TestMemoryRepositoryConcurrentUpdates отмечает уведомления прочитанными,
+// как MarkAsRead, пока схлопывание и Compact читают IsRead под mutex.
+// Гонку ловит go test -race.
+func TestMemoryRepositoryConcurrentUpdates(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	const count = 500
+	repository := NewMemoryRepository(&RetentionConfig{MaxPerUser: count}, newTestLogger(t))
+
+	for i := 0; i < count; i++ {
+		notification := testNotification(fmt.Sprintf("n%d", i), now.Add(time.Duration(i)*time.Second))
+		notification.CollapseKey = "order-42"
+		if err := repository.Save(ctx, notification); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			repository.FindUnreadByCollapseKey(ctx, "user-1", "order-42")
+			repository.Compact(now)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		defer close(done)
+		// От новых к старым: схлопывание читает как раз обновляемое уведомление
+		for i := count - 1; i >= 0; i-- {
+			notification, err := repository.FindByID(ctx, fmt.Sprintf("n%d", i))
+			if err != nil {
+				t.Errorf("FindByID: %v", err)
+				return
+			}
+			notification.IsRead = true
+			if err := repository.Update(ctx, notification); err != nil {
+				t.Errorf("Update: %v", err)
+				return
+			}
+		}
+	}()
+	wg.Wait()
+
+	notifications, err := repository.FindByUserID(ctx, "user-1")
+	if err != nil {
+		t.Fatalf("FindByUserID: %v", err)
+	}
+	if len(notifications) != count {
+		t.Fatalf("осталось %d уведомлений, want %d", len(notifications), count)
+	}
+	for _, notification := range notifications {
+		if !notification.IsRead {
+			t.Errorf("%s не отмечено прочитанным", notification.ID)
+		}
+	}
+}
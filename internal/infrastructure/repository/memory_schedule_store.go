@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+)
+
+// MemoryScheduleStore хранит отложенные уведомления в памяти в порядке времени доставки.
+type MemoryScheduleStore struct {
+	items  map[string]*domain.ScheduledNotification
+	queue  []*domain.ScheduledNotification
+	claims map[string]time.Time
+	mutex  sync.RWMutex
+}
+
+func NewMemoryScheduleStore() *MemoryScheduleStore {
+	return &MemoryScheduleStore{
+		items:  make(map[string]*domain.ScheduledNotification),
+		claims: make(map[string]time.Time),
+	}
+}
+
+func (s *MemoryScheduleStore) Add(ctx context.Context, item *domain.ScheduledNotification) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.items[item.ID]; ok {
+		s.removeFromQueue(item.ID)
+	}
+
+	s.items[item.ID] = item
+	delete(s.claims, item.ID)
+
+	i := sort.Search(len(s.queue), func(i int) bool {
+		return s.queue[i].SendAt.After(item.SendAt)
+	})
+	s.queue = append(s.queue, nil)
+	copy(s.queue[i+1:], s.queue[i:])
+	s.queue[i] = item
+
+	return nil
+}
+
+func (s *MemoryScheduleStore) Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*domain.ScheduledNotification, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	due := []*domain.ScheduledNotification{}
+	for _, item := range s.queue {
+		if item.SendAt.After(now) || (limit > 0 && len(due) >= limit) {
+			break
+		}
+		if claimedUntil, ok := s.claims[item.ID]; ok && claimedUntil.After(now) {
+			continue
+		}
+
+		s.claims[item.ID] = now.Add(lease)
+		due = append(due, item)
+	}
+
+	return due, nil
+}
+
+func (s *MemoryScheduleStore) FindByID(ctx context.Context, id string) (*domain.ScheduledNotification, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	item, ok := s.items[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+
+	return item, nil
+}
+
+func (s *MemoryScheduleStore) FindByUserID(ctx context.Context, userID string) ([]*domain.ScheduledNotification, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	items := []*domain.ScheduledNotification{}
+	for _, item := range s.queue {
+		if item.UserID == userID {
+			items = append(items, item)
+		}
+	}
+
+	return items, nil
+}
+
+func (s *MemoryScheduleStore) Remove(ctx context.Context, id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.items[id]; !ok {
+		return domain.ErrNotFound
+	}
+
+	delete(s.items, id)
+	delete(s.claims, id)
+	s.removeFromQueue(id)
+
+	return nil
+}
+
+func (s *MemoryScheduleStore) removeFromQueue(id string) {
+	for i, item := range s.queue {
+		if item.ID == id {
+			s.queue = append(s.queue[:i], s.queue[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+)
+
+type MemoryWebhookStore struct {
+	webhooks map[string]*domain.Webhook
+	mutex    sync.RWMutex
+}
+
+func NewMemoryWebhookStore() *MemoryWebhookStore {
+	return &MemoryWebhookStore{
+		webhooks: make(map[string]*domain.Webhook),
+	}
+}
+
+func (s *MemoryWebhookStore) Save(ctx context.Context, webhook *domain.Webhook) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	stored := *webhook
+	s.webhooks[webhook.ID] = &stored
+	return nil
+}
+
+func (s *MemoryWebhookStore) FindByID(ctx context.Context, id string) (*domain.Webhook, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	webhook, ok := s.webhooks[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+
+	found := *webhook
+	return &found, nil
+}
+
+func (s *MemoryWebhookStore) FindAll(ctx context.Context) ([]*domain.Webhook, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	webhooks := make([]*domain.Webhook, 0, len(s.webhooks))
+	for _, webhook := range s.webhooks {
+		found := *webhook
+		webhooks = append(webhooks, &found)
+	}
+
+	sort.Slice(webhooks, func(i, j int) bool {
+		return webhooks[i].CreatedAt.Before(webhooks[j].CreatedAt)
+	})
+
+	return webhooks, nil
+}
+
+func (s *MemoryWebhookStore) Delete(ctx context.Context, id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.webhooks[id]; !ok {
+		return domain.ErrNotFound
+	}
+
+	delete(s.webhooks, id)
+	return nil
+}
+
+// MemoryWebhookDeliveryStore хранит копии записей: доставки обновляются
+// воркером, пока журнал читают через API.
+type MemoryWebhookDeliveryStore struct {
+	deliveries map[string]*domain.WebhookDelivery
+	claims     map[string]time.Time
+	mutex      sync.RWMutex
+}
+
+func NewMemoryWebhookDeliveryStore() *MemoryWebhookDeliveryStore {
+	return &MemoryWebhookDeliveryStore{
+		deliveries: make(map[string]*domain.WebhookDelivery),
+		claims:     make(map[string]time.Time),
+	}
+}
+
+func (s *MemoryWebhookDeliveryStore) Save(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	stored := *delivery
+	s.deliveries[delivery.ID] = &stored
+	delete(s.claims, delivery.ID)
+	return nil
+}
+
+func (s *MemoryWebhookDeliveryStore) FindByID(ctx context.Context, id string) (*domain.WebhookDelivery, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	delivery, ok := s.deliveries[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+
+	found := *delivery
+	return &found, nil
+}
+
+func (s *MemoryWebhookDeliveryStore) FindByWebhookID(ctx context.Context, webhookID string, limit int) ([]*domain.WebhookDelivery, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	deliveries := []*domain.WebhookDelivery{}
+	for _, delivery := range s.deliveries {
+		if delivery.WebhookID == webhookID {
+			found := *delivery
+			deliveries = append(deliveries, &found)
+		}
+	}
+
+	sort.Slice(deliveries, func(i, j int) bool {
+		return deliveries[i].CreatedAt.After(deliveries[j].CreatedAt)
+	})
+
+	if limit > 0 && len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+	}
+
+	return deliveries, nil
+}
+
+func (s *MemoryWebhookDeliveryStore) Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*domain.WebhookDelivery, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	due := []*domain.WebhookDelivery{}
+	for _, delivery := range s.deliveries {
+		if delivery.Status != domain.WebhookPending || delivery.NextAttemptAt.After(now) {
+			continue
+		}
+		if claimedUntil, ok := s.claims[delivery.ID]; ok && claimedUntil.After(now) {
+			continue
+		}
+
+		found := *delivery
+		due = append(due, &found)
+	}
+
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].NextAttemptAt.Before(due[j].NextAttemptAt)
+	})
+
+	if limit > 0 && len(due) > limit {
+		due = due[:limit]
+	}
+
+	for _, delivery := range due {
+		s.claims[delivery.ID] = now.Add(lease)
+	}
+
+	return due, nil
+}
+
+func (s *MemoryWebhookDeliveryStore) DeleteFinishedBefore(ctx context.Context, before time.Time) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	deleted := 0
+	for id, delivery := range s.deliveries {
+		if delivery.Status != domain.WebhookPending && delivery.CreatedAt.Before(before) {
+			delete(s.deliveries, id)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
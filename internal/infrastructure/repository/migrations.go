@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anatoly_dev/go-ws-notifications/pkg/logger"
+)
+
+//go:embed migrations
+var migrationFiles embed.FS
+
+type migration struct {
+	version int
+	name    string
+	query   string
+}
+
+// migrate применяет ещё не выполненные миграции из migrations/<dialect>.
+// Каждая миграция выполняется в отдельной транзакции вместе с записью
+// в schema_migrations, поэтому прерванный запуск можно безопасно повторить.
+func migrate(ctx context.Context, db *sql.DB, d dialect, logger *logger.Logger) error {
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version    INTEGER PRIMARY KEY,
+    applied_at TIMESTAMP NOT NULL
+)`)
+	if err != nil {
+		return fmt.Errorf("ошибка создания таблицы миграций: %w", err)
+	}
+
+	migrations, err := loadMigrations(d.name)
+	if err != nil {
+		return err
+	}
+
+	applied := make(map[int]bool)
+	rows, err := db.QueryContext(ctx, "SELECT version FROM schema_migrations")
+	if err != nil {
+		return fmt.Errorf("ошибка чтения применённых миграций: %w", err)
+	}
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			rows.Close()
+			return fmt.Errorf("ошибка чтения применённых миграций: %w", err)
+		}
+		applied[version] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("ошибка чтения применённых миграций: %w", err)
+	}
+
+	for _, m := range migrations {
+		if applied[m.version] {
+			continue
+		}
+
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, m.query); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("ошибка применения миграции %s: %w", m.name, err)
+		}
+
+		if _, err := tx.ExecContext(ctx, d.rebind("INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)"),
+			m.version, time.Now().UTC()); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("ошибка записи миграции %s: %w", m.name, err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("ошибка применения миграции %s: %w", m.name, err)
+		}
+
+		logger.WithField("migration", m.name).Info("Применена миграция базы данных")
+	}
+
+	return nil
+}
+
+func loadMigrations(dialect string) ([]migration, error) {
+	dir := path.Join("migrations", dialect)
+
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, fmt.Errorf("не найдены миграции для %s: %w", dialect, err)
+	}
+
+	migrations := make([]migration, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+
+		prefix, _, _ := strings.Cut(entry.Name(), "_")
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("некорректное имя миграции %s", entry.Name())
+		}
+
+		query, err := fs.ReadFile(migrationFiles, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migrations = append(migrations, migration{
+			version: version,
+			name:    entry.Name(),
+			query:   string(query),
+		})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].version < migrations[j].version
+	})
+
+	return migrations, nil
+}
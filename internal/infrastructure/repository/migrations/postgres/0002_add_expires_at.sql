@@ -0,0 +1,5 @@
+ALTER TABLE notifications ADD COLUMN expires_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_notifications_expires_at
+    ON notifications (expires_at)
+    WHERE expires_at IS NOT NULL;
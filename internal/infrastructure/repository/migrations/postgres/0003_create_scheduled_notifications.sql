@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS scheduled_notifications (
+    id         TEXT PRIMARY KEY,
+    user_id    TEXT NOT NULL,
+    send_at    TIMESTAMPTZ NOT NULL,
+    payload    TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_notifications_send_at
+    ON scheduled_notifications (send_at);
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_notifications_user_id
+    ON scheduled_notifications (user_id, send_at);
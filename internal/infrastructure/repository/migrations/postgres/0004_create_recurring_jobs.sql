@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS recurring_jobs (
+    id         TEXT PRIMARY KEY,
+    payload    TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS webhooks (
+    id         TEXT PRIMARY KEY,
+    payload    TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id              TEXT PRIMARY KEY,
+    webhook_id      TEXT NOT NULL,
+    status          TEXT NOT NULL,
+    next_attempt_at TIMESTAMPTZ NOT NULL,
+    payload         TEXT NOT NULL,
+    created_at      TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due
+    ON webhook_deliveries (status, next_attempt_at);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook_id
+    ON webhook_deliveries (webhook_id, created_at);
@@ -0,0 +1 @@
+ALTER TABLE scheduled_notifications ADD COLUMN fallback_step INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1 @@
+ALTER TABLE recurring_jobs ADD COLUMN next_run TEXT;
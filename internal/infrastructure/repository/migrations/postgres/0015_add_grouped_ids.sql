@@ -0,0 +1 @@
+ALTER TABLE notifications ADD COLUMN grouped_ids TEXT NOT NULL DEFAULT '';
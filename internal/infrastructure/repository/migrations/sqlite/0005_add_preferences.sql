@@ -0,0 +1,7 @@
+ALTER TABLE notifications ADD COLUMN category TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS user_preferences (
+    user_id    TEXT PRIMARY KEY,
+    payload    TEXT NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
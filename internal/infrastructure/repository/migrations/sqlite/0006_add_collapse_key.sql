@@ -0,0 +1,5 @@
+ALTER TABLE notifications ADD COLUMN collapse_key TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user_collapse_key
+    ON notifications (user_id, collapse_key, created_at)
+    WHERE is_read = FALSE AND collapse_key <> '';
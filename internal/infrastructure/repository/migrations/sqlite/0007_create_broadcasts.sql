@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS broadcasts (
+    id         TEXT PRIMARY KEY,
+    payload    TEXT NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_broadcasts_expires_at
+    ON broadcasts (expires_at);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS offline_queue (
+    id         TEXT PRIMARY KEY,
+    user_id    TEXT NOT NULL,
+    payload    TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_offline_queue_user_id
+    ON offline_queue (user_id, created_at);
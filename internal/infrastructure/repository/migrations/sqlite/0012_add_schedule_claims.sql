@@ -0,0 +1 @@
+ALTER TABLE scheduled_notifications ADD COLUMN claimed_until TIMESTAMP;
@@ -0,0 +1 @@
+ALTER TABLE notifications ADD COLUMN delivered_at TIMESTAMP;
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOfflineQueue(t *testing.T) {
+	tests := []struct {
+		name   string
+		pushed map[string][]string
+		userID string
+		want   []string
+	}{
+		{
+			name:   "empty",
+			userID: "user-1",
+		},
+		{
+			name:   "push order",
+			pushed: map[string][]string{"user-1": {"n1", "n2", "n3"}},
+			userID: "user-1",
+			want:   []string{"n1", "n2", "n3"},
+		},
+		{
+			name:   "other users untouched",
+			pushed: map[string][]string{"user-1": {"n1"}, "user-2": {"n2"}},
+			userID: "user-2",
+			want:   []string{"n2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			forEachBackend(t, func(t *testing.T, backend *testBackend) {
+				ctx, queue := backend.ctx, backend.offlineQueue(t)
+
+				expiresAt := backend.now.Add(time.Hour)
+				for userID, ids := range tt.pushed {
+					for _, id := range ids {
+						notification := testNotification(id, backend.now)
+						notification.UserID = userID
+						notification.ExpiresAt = &expiresAt
+						if err := queue.Push(ctx, notification); err != nil {
+							t.Fatalf("Push: %v", err)
+						}
+						// Время постановки задаёт порядок выдачи
+						time.Sleep(2 * time.Millisecond)
+					}
+				}
+
+				notifications, err := queue.PopAll(ctx, tt.userID)
+				if err != nil {
+					t.Fatalf("PopAll: %v", err)
+				}
+				assertIDs(t, idsOf(notifications, notificationID), tt.want...)
+				for _, notification := range notifications {
+					// Офлайн-сервис проверяет срок по копии из очереди
+					if notification.UserID != tt.userID || !equalTimePtr(notification.ExpiresAt, &expiresAt) {
+						t.Errorf("уведомление из очереди = %+v", notification)
+					}
+				}
+
+				// Очередь выдаётся один раз
+				notifications, err = queue.PopAll(ctx, tt.userID)
+				if err != nil {
+					t.Fatalf("повторный PopAll: %v", err)
+				}
+				assertIDs(t, idsOf(notifications, notificationID))
+
+				// Очереди других пользователей не затронуты
+				for userID, ids := range tt.pushed {
+					if userID == tt.userID {
+						continue
+					}
+					notifications, err := queue.PopAll(ctx, userID)
+					if err != nil {
+						t.Fatalf("PopAll(%s): %v", userID, err)
+					}
+					assertIDs(t, idsOf(notifications, notificationID), ids...)
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+)
+
+func TestPreferenceStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	tests := []struct {
+		name  string
+		saved []*domain.Preferences
+		want  *domain.Preferences
+	}{
+		{
+			name: "defaults",
+			want: domain.NewPreferences("user-1"),
+		},
+		{
+			name: "round trip",
+			saved: []*domain.Preferences{{
+				UserID:     "user-1",
+				Types:      map[domain.NotificationType]bool{"message": false},
+				Categories: map[string]bool{"marketing": false},
+				QuietHours: &domain.QuietHours{Start: "22:00", End: "07:00", TimeZone: "Europe/Berlin"},
+				Email:      "user@example.com",
+				PendingEmail: &domain.EmailVerification{
+					Address:   "new@example.com",
+					CodeHash:  "hash",
+					Attempts:  1,
+					SentAt:    now,
+					ExpiresAt: now.Add(15 * time.Minute),
+				},
+				UpdatedAt: now,
+			}},
+			want: &domain.Preferences{
+				UserID:     "user-1",
+				Types:      map[domain.NotificationType]bool{"message": false},
+				Categories: map[string]bool{"marketing": false},
+				QuietHours: &domain.QuietHours{Start: "22:00", End: "07:00", TimeZone: "Europe/Berlin"},
+				Email:      "user@example.com",
+				PendingEmail: &domain.EmailVerification{
+					Address:   "new@example.com",
+					CodeHash:  "hash",
+					Attempts:  1,
+					SentAt:    now,
+					ExpiresAt: now.Add(15 * time.Minute),
+				},
+				UpdatedAt: now,
+			},
+		},
+		{
+			name: "other user keeps defaults",
+			saved: []*domain.Preferences{
+				{UserID: "user-2", Types: map[domain.NotificationType]bool{"alert": false}, Email: "other@example.com", UpdatedAt: now},
+			},
+			want: domain.NewPreferences("user-1"),
+		},
+		{
+			name: "overwrite",
+			saved: []*domain.Preferences{
+				{UserID: "user-1", Categories: map[string]bool{"marketing": false}, Email: "user@example.com", UpdatedAt: now},
+				{UserID: "user-1", Types: map[domain.NotificationType]bool{"alert": false}, UpdatedAt: now.Add(time.Minute)},
+			},
+			want: &domain.Preferences{
+				UserID:     "user-1",
+				Types:      map[domain.NotificationType]bool{"alert": false},
+				Categories: map[string]bool{},
+				UpdatedAt:  now.Add(time.Minute),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			forEachBackend(t, func(t *testing.T, backend *testBackend) {
+				store := backend.preferences(t)
+
+				for _, preferences := range tt.saved {
+					if err := store.Save(ctx, preferences); err != nil {
+						t.Fatalf("Save: %v", err)
+					}
+				}
+
+				got, err := store.Get(ctx, "user-1")
+				if err != nil {
+					t.Fatalf("Get: %v", err)
+				}
+				assertPreferences(t, got, tt.want)
+			})
+		})
+	}
+}
+
+func assertPreferences(t *testing.T, got *domain.Preferences, want *domain.Preferences) {
+	t.Helper()
+
+	if got.UserID != want.UserID || got.Email != want.Email || !got.UpdatedAt.Equal(want.UpdatedAt) {
+		t.Errorf("настройки = %+v, want %+v", got, want)
+	}
+	if got.Types == nil || got.Categories == nil {
+		t.Fatalf("карты не инициализированы: %+v", got)
+	}
+	if len(got.Types) != len(want.Types) || len(got.Categories) != len(want.Categories) {
+		t.Errorf("Types = %v, Categories = %v, want %v, %v", got.Types, got.Categories, want.Types, want.Categories)
+	}
+	for notificationType, enabled := range want.Types {
+		if value, ok := got.Types[notificationType]; !ok || value != enabled {
+			t.Errorf("Types[%s] = %v, want %v", notificationType, value, enabled)
+		}
+	}
+	for category, enabled := range want.Categories {
+		if value, ok := got.Categories[category]; !ok || value != enabled {
+			t.Errorf("Categories[%s] = %v, want %v", category, value, enabled)
+		}
+	}
+
+	if (got.QuietHours == nil) != (want.QuietHours == nil) || (got.QuietHours != nil && *got.QuietHours != *want.QuietHours) {
+		t.Errorf("QuietHours = %+v, want %+v", got.QuietHours, want.QuietHours)
+	}
+
+	if (got.PendingEmail == nil) != (want.PendingEmail == nil) {
+		t.Fatalf("PendingEmail = %+v, want %+v", got.PendingEmail, want.PendingEmail)
+	}
+	if pending := got.PendingEmail; pending != nil {
+		if pending.Address != want.PendingEmail.Address || pending.CodeHash != want.PendingEmail.CodeHash ||
+			pending.Attempts != want.PendingEmail.Attempts || !pending.SentAt.Equal(want.PendingEmail.SentAt) ||
+			!pending.ExpiresAt.Equal(want.PendingEmail.ExpiresAt) {
+			t.Errorf("PendingEmail = %+v, want %+v", pending, want.PendingEmail)
+		}
+	}
+}
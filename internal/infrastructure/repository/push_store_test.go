@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+)
+
+func testPushSubscription(userID string, endpoint string, createdAt time.Time) *domain.PushSubscription {
+	return &domain.PushSubscription{
+		UserID:    userID,
+		Endpoint:  endpoint,
+		Keys:      domain.PushKeys{P256dh: "p256dh-" + userID, Auth: "auth-" + userID},
+		CreatedAt: createdAt,
+	}
+}
+
+func TestPushSubscriptionStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+	const (
+		endpointA = "https://push.example.com/a"
+		endpointB = "https://push.example.com/b"
+	)
+
+	tests := []struct {
+		name  string
+		saved []*domain.PushSubscription
+		// deleted - пары пользователь/endpoint и ожидаемая ошибка
+		deleted []struct {
+			userID   string
+			endpoint string
+			err      error
+		}
+		want map[string][]string
+	}{
+		{
+			name: "find by user",
+			saved: []*domain.PushSubscription{
+				testPushSubscription("user-1", endpointB, now.Add(time.Minute)),
+				testPushSubscription("user-1", endpointA, now),
+			},
+			want: map[string][]string{"user-1": {endpointA, endpointB}, "user-2": {}},
+		},
+		{
+			name: "endpoint moves to last user",
+			saved: []*domain.PushSubscription{
+				testPushSubscription("user-1", endpointA, now),
+				testPushSubscription("user-2", endpointA, now.Add(time.Minute)),
+			},
+			want: map[string][]string{"user-1": {}, "user-2": {endpointA}},
+		},
+		{
+			name: "resubscribe updates keys",
+			saved: []*domain.PushSubscription{
+				{UserID: "user-1", Endpoint: endpointA, Keys: domain.PushKeys{P256dh: "old", Auth: "old"}, CreatedAt: now},
+				testPushSubscription("user-1", endpointA, now.Add(time.Minute)),
+			},
+			want: map[string][]string{"user-1": {endpointA}},
+		},
+		{
+			name: "delete only own subscription",
+			saved: []*domain.PushSubscription{
+				testPushSubscription("user-1", endpointA, now),
+				testPushSubscription("user-2", endpointB, now),
+			},
+			deleted: []struct {
+				userID   string
+				endpoint string
+				err      error
+			}{
+				{"user-1", endpointB, domain.ErrNotFound},
+				{"user-1", endpointA, nil},
+				{"user-1", endpointA, domain.ErrNotFound},
+			},
+			want: map[string][]string{"user-1": {}, "user-2": {endpointB}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			forEachBackend(t, func(t *testing.T, backend *testBackend) {
+				store := backend.pushSubscriptions(t)
+
+				for _, subscription := range tt.saved {
+					if err := store.Save(ctx, subscription); err != nil {
+						t.Fatalf("Save: %v", err)
+					}
+				}
+				for _, deleted := range tt.deleted {
+					if err := store.Delete(ctx, deleted.userID, deleted.endpoint); !errors.Is(err, deleted.err) {
+						t.Errorf("Delete(%s, %s) = %v, want %v", deleted.userID, deleted.endpoint, err, deleted.err)
+					}
+				}
+
+				for userID, want := range tt.want {
+					subscriptions, err := store.FindByUserID(ctx, userID)
+					if err != nil {
+						t.Fatalf("FindByUserID: %v", err)
+					}
+
+					for _, subscription := range subscriptions {
+						if subscription.UserID != userID || subscription.Keys.Auth != "auth-"+userID {
+							t.Errorf("подписка %s = %+v", subscription.Endpoint, subscription)
+						}
+					}
+					assertIDs(t, idsOf(subscriptions, subscriptionEndpoint), want...)
+				}
+			})
+		})
+	}
+}
+
+func subscriptionEndpoint(subscription *domain.PushSubscription) string {
+	return subscription.Endpoint
+}
@@ -0,0 +1,183 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+)
+
+func testRecurringJob(id string, createdAt time.Time, nextRunAt *time.Time) *domain.RecurringJob {
+	return &domain.RecurringJob{
+		ID:        id,
+		Name:      "Weekly " + id,
+		Spec:      "0 9 * * 1",
+		Type:      domain.NotificationType("system"),
+		Title:     "Title",
+		Content:   "Content",
+		NextRunAt: nextRunAt,
+		CreatedAt: createdAt,
+	}
+}
+
+func recurringJobID(job *domain.RecurringJob) string {
+	return job.ID
+}
+
+func TestRecurringJobStore(t *testing.T) {
+	runStoreTests(t, []storeTest{
+		{
+			name: "save and find in creation order",
+			run: func(t *testing.T, backend *testBackend) {
+				ctx, now, store := backend.ctx, backend.now, backend.recurring(t)
+				nextRun := now.Add(time.Hour)
+
+				for i, id := range []string{"r2", "r1"} {
+					if err := store.Save(ctx, testRecurringJob(id, now.Add(time.Duration(i)*time.Minute), &nextRun)); err != nil {
+						t.Fatalf("Save: %v", err)
+					}
+				}
+
+				job, err := store.FindByID(ctx, "r1")
+				if err != nil {
+					t.Fatalf("FindByID: %v", err)
+				}
+				if job.Name != "Weekly r1" || job.NextRunAt == nil || !job.NextRunAt.Equal(nextRun) {
+					t.Errorf("задание = %+v", job)
+				}
+
+				jobs, err := store.FindAll(ctx)
+				if err != nil {
+					t.Fatalf("FindAll: %v", err)
+				}
+				assertIDs(t, idsOf(jobs, recurringJobID), "r2", "r1")
+			},
+		},
+		{
+			name: "delete",
+			run: func(t *testing.T, backend *testBackend) {
+				ctx, now, store := backend.ctx, backend.now, backend.recurring(t)
+
+				if err := store.Save(ctx, testRecurringJob("r1", now, nil)); err != nil {
+					t.Fatalf("Save: %v", err)
+				}
+				if err := store.Delete(ctx, "r1"); err != nil {
+					t.Fatalf("Delete: %v", err)
+				}
+				if err := store.Delete(ctx, "r1"); !errors.Is(err, domain.ErrNotFound) {
+					t.Errorf("повторный Delete = %v, want ErrNotFound", err)
+				}
+				if _, err := store.FindByID(ctx, "r1"); !errors.Is(err, domain.ErrNotFound) {
+					t.Errorf("FindByID = %v, want ErrNotFound", err)
+				}
+			},
+		},
+		{
+			name: "advance once per run",
+			run: func(t *testing.T, backend *testBackend) {
+				ctx, now, store := backend.ctx, backend.now, backend.recurring(t)
+				nextRun, followingRun := now.Add(time.Hour), now.Add(2*time.Hour)
+
+				if err := store.Save(ctx, testRecurringJob("r1", now, &nextRun)); err != nil {
+					t.Fatalf("Save: %v", err)
+				}
+
+				advanced := testRecurringJob("r1", now, &followingRun)
+				advanced.LastRunAt = &nextRun
+
+				ok, err := store.Advance(ctx, advanced, &nextRun)
+				if err != nil || !ok {
+					t.Fatalf("Advance = %v, %v, want true", ok, err)
+				}
+
+				// Второй узел видел тот же запуск и не должен выполнить его повторно
+				ok, err = store.Advance(ctx, advanced, &nextRun)
+				if err != nil || ok {
+					t.Fatalf("повторный Advance = %v, %v, want false", ok, err)
+				}
+
+				job, err := store.FindByID(ctx, "r1")
+				if err != nil {
+					t.Fatalf("FindByID: %v", err)
+				}
+				if job.NextRunAt == nil || !job.NextRunAt.Equal(followingRun) || job.LastRunAt == nil || !job.LastRunAt.Equal(nextRun) {
+					t.Errorf("задание после Advance = %+v", job)
+				}
+			},
+		},
+		{
+			name: "advance last run",
+			run: func(t *testing.T, backend *testBackend) {
+				ctx, now, store := backend.ctx, backend.now, backend.recurring(t)
+				nextRun := now.Add(time.Hour)
+
+				if err := store.Save(ctx, testRecurringJob("r1", now, &nextRun)); err != nil {
+					t.Fatalf("Save: %v", err)
+				}
+
+				// После последнего запуска NextRunAt = nil, и задание больше не выполняется
+				ok, err := store.Advance(ctx, testRecurringJob("r1", now, nil), &nextRun)
+				if err != nil || !ok {
+					t.Fatalf("Advance = %v, %v, want true", ok, err)
+				}
+
+				ok, err = store.Advance(ctx, testRecurringJob("r1", now, nil), &nextRun)
+				if err != nil || ok {
+					t.Fatalf("повторный Advance = %v, %v, want false", ok, err)
+				}
+
+				job, err := store.FindByID(ctx, "r1")
+				if err != nil {
+					t.Fatalf("FindByID: %v", err)
+				}
+				if job.NextRunAt != nil {
+					t.Errorf("NextRunAt = %v, want nil", job.NextRunAt)
+				}
+			},
+		},
+		{
+			name: "advance missing job",
+			run: func(t *testing.T, backend *testBackend) {
+				ctx, now, store := backend.ctx, backend.now, backend.recurring(t)
+				nextRun, followingRun := now.Add(time.Hour), now.Add(2*time.Hour)
+
+				ok, err := store.Advance(ctx, testRecurringJob("missing", now, &followingRun), &nextRun)
+				if err != nil || ok {
+					t.Errorf("Advance = %v, %v, want false", ok, err)
+				}
+			},
+		},
+	})
+}
+
+// TestSQLRecurringStoreLegacyRows проверяет задания, записанные до
+// миграции 0013: у них нет next_run, поэтому первый Advance проходит без
+// сверки, а следующий уже сверяет записанный next_run.
+func TestSQLRecurringStoreLegacyRows(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+	nextRun, followingRun := now.Add(time.Hour), now.Add(2*time.Hour)
+
+	forEachSQLDatabase(t, func(t *testing.T, database *SQLDatabase) {
+		store := NewSQLRecurringStore(database)
+
+		if err := store.Save(ctx, testRecurringJob("r1", now, &nextRun)); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+		if _, err := database.db.Exec("UPDATE recurring_jobs SET next_run = NULL"); err != nil {
+			t.Fatalf("сброс next_run: %v", err)
+		}
+
+		ok, err := store.Advance(ctx, testRecurringJob("r1", now, &followingRun), &nextRun)
+		if err != nil || !ok {
+			t.Fatalf("Advance = %v, %v, want true", ok, err)
+		}
+
+		ok, err = store.Advance(ctx, testRecurringJob("r1", now, &followingRun), &nextRun)
+		if err != nil || ok {
+			t.Fatalf("повторный Advance = %v, %v, want false", ok, err)
+		}
+	})
+}
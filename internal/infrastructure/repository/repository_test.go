@@ -0,0 +1,275 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+)
+
+func TestNotificationRepository(t *testing.T) {
+	runStoreTests(t, []storeTest{
+		{
+			name: "save and find",
+			run: func(t *testing.T, backend *testBackend) {
+				ctx, now, repository := backend.ctx, backend.now, backend.notifications(t)
+
+				expiresAt := now.Add(time.Hour)
+				notification := testNotification("n1", now)
+				notification.ExpiresAt = &expiresAt
+				notification.Category = "billing"
+				notification.CollapseKey = "order-42"
+
+				if err := repository.Save(ctx, notification); err != nil {
+					t.Fatalf("Save: %v", err)
+				}
+
+				found, err := repository.FindByID(ctx, "n1")
+				if err != nil {
+					t.Fatalf("FindByID: %v", err)
+				}
+				assertNotification(t, found, notification)
+			},
+		},
+		{
+			name: "digest keeps grouped ids",
+			run: func(t *testing.T, backend *testBackend) {
+				ctx, now, repository := backend.ctx, backend.now, backend.notifications(t)
+
+				notification := testNotification("digest", now)
+				notification.GroupedIDs = []string{"n1", "n2"}
+				if err := repository.Save(ctx, notification); err != nil {
+					t.Fatalf("Save: %v", err)
+				}
+
+				found, err := repository.FindByID(ctx, "digest")
+				if err != nil {
+					t.Fatalf("FindByID: %v", err)
+				}
+				assertNotification(t, found, notification)
+
+				notification.GroupedIDs = []string{"n1", "n2", "n3"}
+				if err := repository.Update(ctx, notification); err != nil {
+					t.Fatalf("Update: %v", err)
+				}
+
+				notifications, err := repository.FindByUserID(ctx, notification.UserID)
+				if err != nil {
+					t.Fatalf("FindByUserID: %v", err)
+				}
+				assertIDs(t, idsOf(notifications, notificationID), "digest")
+				assertNotification(t, notifications[0], notification)
+			},
+		},
+		{
+			name: "save overwrites",
+			run: func(t *testing.T, backend *testBackend) {
+				ctx, now, repository := backend.ctx, backend.now, backend.notifications(t)
+
+				notification := testNotification("n1", now)
+				if err := repository.Save(ctx, notification); err != nil {
+					t.Fatalf("Save: %v", err)
+				}
+
+				notification.Title = "Updated"
+				notification.IsRead = true
+				if err := repository.Save(ctx, notification); err != nil {
+					t.Fatalf("повторный Save: %v", err)
+				}
+
+				found, err := repository.FindByID(ctx, "n1")
+				if err != nil {
+					t.Fatalf("FindByID: %v", err)
+				}
+				assertNotification(t, found, notification)
+			},
+		},
+		{
+			name: "not found",
+			run: func(t *testing.T, backend *testBackend) {
+				ctx, now, repository := backend.ctx, backend.now, backend.notifications(t)
+
+				if _, err := repository.FindByID(ctx, "missing"); !errors.Is(err, domain.ErrNotFound) {
+					t.Errorf("FindByID = %v, want ErrNotFound", err)
+				}
+				if err := repository.Update(ctx, testNotification("missing", now)); !errors.Is(err, domain.ErrNotFound) {
+					t.Errorf("Update = %v, want ErrNotFound", err)
+				}
+				if err := repository.Delete(ctx, "missing"); !errors.Is(err, domain.ErrNotFound) {
+					t.Errorf("Delete = %v, want ErrNotFound", err)
+				}
+				if err := repository.MarkDelivered(ctx, "missing", now); err != nil {
+					t.Errorf("MarkDelivered = %v, want nil", err)
+				}
+			},
+		},
+		{
+			name: "find by user in creation order",
+			run: func(t *testing.T, backend *testBackend) {
+				ctx, now, repository := backend.ctx, backend.now, backend.notifications(t)
+
+				for i, id := range []string{"n3", "n1", "n2"} {
+					notification := testNotification(id, now.Add(time.Duration(2-i)*time.Minute))
+					if err := repository.Save(ctx, notification); err != nil {
+						t.Fatalf("Save: %v", err)
+					}
+				}
+				other := testNotification("other", now)
+				other.UserID = "user-2"
+				if err := repository.Save(ctx, other); err != nil {
+					t.Fatalf("Save: %v", err)
+				}
+
+				found, err := repository.FindByUserID(ctx, "user-1")
+				if err != nil {
+					t.Fatalf("FindByUserID: %v", err)
+				}
+				assertIDs(t, idsOf(found, notificationID), "n2", "n1", "n3")
+			},
+		},
+		{
+			name: "update and delete",
+			run: func(t *testing.T, backend *testBackend) {
+				ctx, now, repository := backend.ctx, backend.now, backend.notifications(t)
+
+				notification := testNotification("n1", now)
+				if err := repository.Save(ctx, notification); err != nil {
+					t.Fatalf("Save: %v", err)
+				}
+
+				notification.IsRead = true
+				notification.Category = "security"
+				if err := repository.Update(ctx, notification); err != nil {
+					t.Fatalf("Update: %v", err)
+				}
+				found, err := repository.FindByID(ctx, "n1")
+				if err != nil {
+					t.Fatalf("FindByID: %v", err)
+				}
+				assertNotification(t, found, notification)
+
+				if err := repository.Delete(ctx, "n1"); err != nil {
+					t.Fatalf("Delete: %v", err)
+				}
+				if _, err := repository.FindByID(ctx, "n1"); !errors.Is(err, domain.ErrNotFound) {
+					t.Errorf("FindByID после Delete = %v, want ErrNotFound", err)
+				}
+			},
+		},
+		{
+			name: "find expired",
+			run: func(t *testing.T, backend *testBackend) {
+				ctx, now, repository := backend.ctx, backend.now, backend.notifications(t)
+
+				expiries := []struct {
+					id        string
+					expiresAt time.Time
+				}{
+					{"late", now},
+					{"early", now.Add(-time.Hour)},
+					{"future", now.Add(time.Hour)},
+				}
+				for _, expiry := range expiries {
+					expiresAt := expiry.expiresAt
+					notification := testNotification(expiry.id, now)
+					notification.ExpiresAt = &expiresAt
+					if err := repository.Save(ctx, notification); err != nil {
+						t.Fatalf("Save: %v", err)
+					}
+				}
+				if err := repository.Save(ctx, testNotification("forever", now)); err != nil {
+					t.Fatalf("Save: %v", err)
+				}
+
+				expired, err := repository.FindExpired(ctx, now, 0)
+				if err != nil {
+					t.Fatalf("FindExpired: %v", err)
+				}
+				assertIDs(t, idsOf(expired, notificationID), "early", "late")
+
+				expired, err = repository.FindExpired(ctx, now, 1)
+				if err != nil {
+					t.Fatalf("FindExpired с лимитом: %v", err)
+				}
+				assertIDs(t, idsOf(expired, notificationID), "early")
+			},
+		},
+		{
+			name: "find unread by collapse key",
+			run: func(t *testing.T, backend *testBackend) {
+				ctx, now, repository := backend.ctx, backend.now, backend.notifications(t)
+
+				for i, id := range []string{"old", "new", "read"} {
+					notification := testNotification(id, now.Add(time.Duration(i)*time.Minute))
+					notification.CollapseKey = "order-42"
+					notification.IsRead = id == "read"
+					if err := repository.Save(ctx, notification); err != nil {
+						t.Fatalf("Save: %v", err)
+					}
+				}
+
+				found, err := repository.FindUnreadByCollapseKey(ctx, "user-1", "order-42")
+				if err != nil {
+					t.Fatalf("FindUnreadByCollapseKey: %v", err)
+				}
+				if found.ID != "new" {
+					t.Errorf("FindUnreadByCollapseKey = %s, want new", found.ID)
+				}
+
+				if _, err := repository.FindUnreadByCollapseKey(ctx, "user-2", "order-42"); !errors.Is(err, domain.ErrNotFound) {
+					t.Errorf("FindUnreadByCollapseKey для другого пользователя = %v, want ErrNotFound", err)
+				}
+			},
+		},
+		{
+			name: "mark delivered once",
+			run: func(t *testing.T, backend *testBackend) {
+				ctx, now, repository := backend.ctx, backend.now, backend.notifications(t)
+
+				notification := testNotification("n1", now)
+				if err := repository.Save(ctx, notification); err != nil {
+					t.Fatalf("Save: %v", err)
+				}
+
+				if err := repository.MarkDelivered(ctx, "n1", now); err != nil {
+					t.Fatalf("MarkDelivered: %v", err)
+				}
+				if err := repository.MarkDelivered(ctx, "n1", now.Add(time.Hour)); err != nil {
+					t.Fatalf("повторный MarkDelivered: %v", err)
+				}
+
+				// Update не сбрасывает отметку о доставке
+				notification.IsRead = true
+				if err := repository.Update(ctx, notification); err != nil {
+					t.Fatalf("Update: %v", err)
+				}
+
+				found, err := repository.FindByID(ctx, "n1")
+				if err != nil {
+					t.Fatalf("FindByID: %v", err)
+				}
+				if found.DeliveredAt == nil || !found.DeliveredAt.Equal(now) {
+					t.Errorf("DeliveredAt = %v, want %v", found.DeliveredAt, now)
+				}
+			},
+		},
+	})
+}
+
+func assertNotification(t *testing.T, got *domain.Notification, want *domain.Notification) {
+	t.Helper()
+
+	if got.ID != want.ID || got.UserID != want.UserID || got.Type != want.Type ||
+		got.Title != want.Title || got.Content != want.Content || got.IsRead != want.IsRead ||
+		got.Priority != want.Priority || got.Category != want.Category || got.CollapseKey != want.CollapseKey {
+		t.Errorf("уведомление = %+v, want %+v", got, want)
+	}
+	if !got.CreatedAt.Equal(want.CreatedAt) {
+		t.Errorf("CreatedAt = %v, want %v", got.CreatedAt, want.CreatedAt)
+	}
+	if !equalTimePtr(got.ExpiresAt, want.ExpiresAt) {
+		t.Errorf("ExpiresAt = %v, want %v", got.ExpiresAt, want.ExpiresAt)
+	}
+	assertIDs(t, got.GroupedIDs, want.GroupedIDs...)
+}
@@ -0,0 +1,221 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+)
+
+func testScheduled(id string, sendAt time.Time) *domain.ScheduledNotification {
+	return &domain.ScheduledNotification{
+		ID:           id,
+		UserID:       "user-1",
+		SendAt:       sendAt,
+		Notification: testNotification(id, sendAt),
+		CreatedAt:    sendAt.Add(-time.Hour),
+	}
+}
+
+func TestScheduleStore(t *testing.T) {
+	const lease = time.Minute
+
+	runStoreTests(t, []storeTest{
+		{
+			name: "add and find",
+			run: func(t *testing.T, backend *testBackend) {
+				ctx, now, store := backend.ctx, backend.now, backend.schedule(t)
+
+				item := testScheduled("s1", now.Add(time.Hour))
+				item.FallbackStep = 2
+				if err := store.Add(ctx, item); err != nil {
+					t.Fatalf("Add: %v", err)
+				}
+
+				found, err := store.FindByID(ctx, "s1")
+				if err != nil {
+					t.Fatalf("FindByID: %v", err)
+				}
+				if found.UserID != item.UserID || !found.SendAt.Equal(item.SendAt) || !found.CreatedAt.Equal(item.CreatedAt) ||
+					found.FallbackStep != 2 || found.Notification == nil || found.Notification.Title != item.Notification.Title {
+					t.Errorf("отложенное уведомление = %+v, want %+v", found, item)
+				}
+
+				items, err := store.FindByUserID(ctx, "user-1")
+				if err != nil {
+					t.Fatalf("FindByUserID: %v", err)
+				}
+				assertIDs(t, idsOf(items, scheduledID), "s1")
+			},
+		},
+		{
+			name: "find by user in send order",
+			run: func(t *testing.T, backend *testBackend) {
+				ctx, now, store := backend.ctx, backend.now, backend.schedule(t)
+
+				other := testScheduled("other", now)
+				other.UserID = "user-2"
+				for _, item := range []*domain.ScheduledNotification{
+					testScheduled("second", now.Add(2*time.Hour)),
+					testScheduled("first", now.Add(time.Hour)),
+					other,
+				} {
+					if err := store.Add(ctx, item); err != nil {
+						t.Fatalf("Add: %v", err)
+					}
+				}
+
+				items, err := store.FindByUserID(ctx, "user-1")
+				if err != nil {
+					t.Fatalf("FindByUserID: %v", err)
+				}
+				assertIDs(t, idsOf(items, scheduledID), "first", "second")
+			},
+		},
+		{
+			name: "remove",
+			run: func(t *testing.T, backend *testBackend) {
+				ctx, now, store := backend.ctx, backend.now, backend.schedule(t)
+
+				if err := store.Add(ctx, testScheduled("s1", now)); err != nil {
+					t.Fatalf("Add: %v", err)
+				}
+				if err := store.Remove(ctx, "s1"); err != nil {
+					t.Fatalf("Remove: %v", err)
+				}
+				if err := store.Remove(ctx, "s1"); !errors.Is(err, domain.ErrNotFound) {
+					t.Errorf("повторный Remove = %v, want ErrNotFound", err)
+				}
+				if _, err := store.FindByID(ctx, "s1"); !errors.Is(err, domain.ErrNotFound) {
+					t.Errorf("FindByID = %v, want ErrNotFound", err)
+				}
+			},
+		},
+		{
+			name: "claim due in send order",
+			run: func(t *testing.T, backend *testBackend) {
+				ctx, now, store := backend.ctx, backend.now, backend.schedule(t)
+
+				for _, item := range []*domain.ScheduledNotification{
+					testScheduled("later", now.Add(-time.Minute)),
+					testScheduled("earlier", now.Add(-time.Hour)),
+					testScheduled("future", now.Add(time.Hour)),
+				} {
+					if err := store.Add(ctx, item); err != nil {
+						t.Fatalf("Add: %v", err)
+					}
+				}
+
+				claimed, err := store.Claim(ctx, now, lease, 0)
+				if err != nil {
+					t.Fatalf("Claim: %v", err)
+				}
+				assertIDs(t, idsOf(claimed, scheduledID), "earlier", "later")
+			},
+		},
+		{
+			name: "claim limit",
+			run: func(t *testing.T, backend *testBackend) {
+				ctx, now, store := backend.ctx, backend.now, backend.schedule(t)
+
+				for i, id := range []string{"s1", "s2", "s3"} {
+					if err := store.Add(ctx, testScheduled(id, now.Add(-time.Duration(3-i)*time.Minute))); err != nil {
+						t.Fatalf("Add: %v", err)
+					}
+				}
+
+				claimed, err := store.Claim(ctx, now, lease, 2)
+				if err != nil {
+					t.Fatalf("Claim: %v", err)
+				}
+				assertIDs(t, idsOf(claimed, scheduledID), "s1", "s2")
+
+				claimed, err = store.Claim(ctx, now, lease, 2)
+				if err != nil {
+					t.Fatalf("второй Claim: %v", err)
+				}
+				assertIDs(t, idsOf(claimed, scheduledID), "s3")
+			},
+		},
+		{
+			name: "claim lease",
+			run: func(t *testing.T, backend *testBackend) {
+				ctx, now, store := backend.ctx, backend.now, backend.schedule(t)
+
+				if err := store.Add(ctx, testScheduled("s1", now.Add(-time.Minute))); err != nil {
+					t.Fatalf("Add: %v", err)
+				}
+
+				if claimed, err := store.Claim(ctx, now, lease, 0); err != nil || len(claimed) != 1 {
+					t.Fatalf("Claim = %d, %v, want 1", len(claimed), err)
+				}
+
+				// Пока захват действует, другой узел строку не получает
+				claimed, err := store.Claim(ctx, now.Add(lease/2), lease, 0)
+				if err != nil {
+					t.Fatalf("Claim во время захвата: %v", err)
+				}
+				assertIDs(t, idsOf(claimed, scheduledID))
+
+				// Истёкший захват означает, что узел не успел отправить уведомление
+				claimed, err = store.Claim(ctx, now.Add(lease), lease, 0)
+				if err != nil {
+					t.Fatalf("Claim после захвата: %v", err)
+				}
+				assertIDs(t, idsOf(claimed, scheduledID), "s1")
+			},
+		},
+		{
+			name: "remove claimed",
+			run: func(t *testing.T, backend *testBackend) {
+				ctx, now, store := backend.ctx, backend.now, backend.schedule(t)
+
+				if err := store.Add(ctx, testScheduled("s1", now.Add(-time.Minute))); err != nil {
+					t.Fatalf("Add: %v", err)
+				}
+				if _, err := store.Claim(ctx, now, lease, 0); err != nil {
+					t.Fatalf("Claim: %v", err)
+				}
+
+				// Отправленный элемент удаляется до истечения захвата и больше не выдаётся
+				if err := store.Remove(ctx, "s1"); err != nil {
+					t.Fatalf("Remove: %v", err)
+				}
+				claimed, err := store.Claim(ctx, now.Add(lease), lease, 0)
+				if err != nil {
+					t.Fatalf("Claim после захвата: %v", err)
+				}
+				assertIDs(t, idsOf(claimed, scheduledID))
+			},
+		},
+		{
+			name: "add releases claim",
+			run: func(t *testing.T, backend *testBackend) {
+				ctx, now, store := backend.ctx, backend.now, backend.schedule(t)
+
+				item := testScheduled("s1", now.Add(-time.Minute))
+				if err := store.Add(ctx, item); err != nil {
+					t.Fatalf("Add: %v", err)
+				}
+				if _, err := store.Claim(ctx, now, lease, 0); err != nil {
+					t.Fatalf("Claim: %v", err)
+				}
+
+				if err := store.Add(ctx, item); err != nil {
+					t.Fatalf("повторный Add: %v", err)
+				}
+
+				claimed, err := store.Claim(ctx, now, lease, 0)
+				if err != nil {
+					t.Fatalf("Claim: %v", err)
+				}
+				assertIDs(t, idsOf(claimed, scheduledID), "s1")
+			},
+		},
+	})
+}
+
+func scheduledID(item *domain.ScheduledNotification) string {
+	return item.ID
+}
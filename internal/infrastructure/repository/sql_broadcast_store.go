@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+)
+
+type SQLBroadcastStore struct {
+	db      *sql.DB
+	dialect dialect
+}
+
+func NewSQLBroadcastStore(database *SQLDatabase) *SQLBroadcastStore {
+	return &SQLBroadcastStore{
+		db:      database.db,
+		dialect: database.dialect,
+	}
+}
+
+func (s *SQLBroadcastStore) Save(ctx context.Context, broadcast *domain.Broadcast) error {
+	if broadcast.ExpiresAt == nil {
+		return fmt.Errorf("%w: у объявления не задан срок действия", domain.ErrInvalidInput)
+	}
+
+	payload, err := json.Marshal(broadcast)
+	if err != nil {
+		return fmt.Errorf("ошибка сериализации объявления: %w", err)
+	}
+
+	_, err = s.db.ExecContext(ctx, s.dialect.rebind(
+		"INSERT INTO broadcasts (id, payload, expires_at, created_at) VALUES (?, ?, ?, ?) "+
+			"ON CONFLICT (id) DO UPDATE SET payload = excluded.payload, expires_at = excluded.expires_at"),
+		broadcast.ID,
+		string(payload),
+		broadcast.ExpiresAt.UTC(),
+		broadcast.CreatedAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("ошибка сохранения объявления: %w", err)
+	}
+
+	return nil
+}
+
+func (s *SQLBroadcastStore) FindByID(ctx context.Context, id string) (*domain.Broadcast, error) {
+	var payload string
+
+	err := s.db.QueryRowContext(ctx, s.dialect.rebind("SELECT payload FROM broadcasts WHERE id = ?"), id).Scan(&payload)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка поиска объявления: %w", err)
+	}
+
+	return decodeBroadcast(payload)
+}
+
+func (s *SQLBroadcastStore) FindActive(ctx context.Context, now time.Time) ([]*domain.Broadcast, error) {
+	rows, err := s.db.QueryContext(ctx, s.dialect.rebind(
+		"SELECT payload FROM broadcasts WHERE expires_at > ? ORDER BY created_at"), now.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("ошибка поиска объявлений: %w", err)
+	}
+	defer rows.Close()
+
+	broadcasts := []*domain.Broadcast{}
+	for rows.Next() {
+		var payload string
+		if err := rows.Scan(&payload); err != nil {
+			return nil, fmt.Errorf("ошибка чтения объявления: %w", err)
+		}
+
+		broadcast, err := decodeBroadcast(payload)
+		if err != nil {
+			return nil, err
+		}
+		broadcasts = append(broadcasts, broadcast)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка чтения объявлений: %w", err)
+	}
+
+	return broadcasts, nil
+}
+
+func (s *SQLBroadcastStore) Delete(ctx context.Context, id string) error {
+	result, err := s.db.ExecContext(ctx, s.dialect.rebind("DELETE FROM broadcasts WHERE id = ?"), id)
+	if err != nil {
+		return fmt.Errorf("ошибка удаления объявления: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("ошибка удаления объявления: %w", err)
+	}
+	if affected == 0 {
+		return domain.ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *SQLBroadcastStore) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	result, err := s.db.ExecContext(ctx, s.dialect.rebind("DELETE FROM broadcasts WHERE expires_at <= ?"), now.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("ошибка удаления истёкших объявлений: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("ошибка удаления истёкших объявлений: %w", err)
+	}
+
+	return int(affected), nil
+}
+
+func decodeBroadcast(payload string) (*domain.Broadcast, error) {
+	var broadcast domain.Broadcast
+	if err := json.Unmarshal([]byte(payload), &broadcast); err != nil {
+		return nil, fmt.Errorf("ошибка чтения объявления: %w", err)
+	}
+
+	return &broadcast, nil
+}
@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anatoly_dev/go-ws-notifications/pkg/logger"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "modernc.org/sqlite"
+)
+
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+type SQLConfig struct {
+	Driver          string
+	DSN             string
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+}
+
+type dialect struct {
+	name       string
+	driverName string
+	// Postgres использует нумерованные плейсхолдеры $1, $2...
+	numbered bool
+}
+
+func (d dialect) rebind(query string) string {
+	if !d.numbered {
+		return query
+	}
+
+	var builder strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			builder.WriteByte('$')
+			builder.WriteString(strconv.Itoa(n))
+			continue
+		}
+		builder.WriteRune(r)
+	}
+
+	return builder.String()
+}
+
+// skipLocked дописывается к подзапросу захвата строк: параллельные узлы
+// пропускают строки, захватываемые другим узлом, а не ждут их. SQLite
+// сериализует запись и без этого.
+func (d dialect) skipLocked() string {
+	if d.name == DriverPostgres {
+		return " FOR UPDATE SKIP LOCKED"
+	}
+	return ""
+}
+
+var dialects = map[string]dialect{
+	DriverPostgres: {name: DriverPostgres, driverName: "pgx", numbered: true},
+	DriverSQLite:   {name: DriverSQLite, driverName: "sqlite"},
+}
+
+// SQLDatabase - общее подключение, которое используют все SQL-хранилища сервиса.
+type SQLDatabase struct {
+	db      *sql.DB
+	dialect dialect
+}
+
+func OpenSQLDatabase(config *SQLConfig, logger *logger.Logger) (*SQLDatabase, error) {
+	d, ok := dialects[config.Driver]
+	if !ok {
+		return nil, fmt.Errorf("неподдерживаемый драйвер базы данных: %s", config.Driver)
+	}
+
+	db, err := sql.Open(d.driverName, config.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка открытия базы данных: %w", err)
+	}
+
+	if config.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(config.MaxOpenConns)
+	}
+	if config.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(config.MaxIdleConns)
+	}
+	if config.ConnMaxLifetime > 0 {
+		db.SetConnMaxLifetime(config.ConnMaxLifetime)
+	}
+
+	// SQLite не поддерживает конкурентную запись из нескольких соединений
+	if d.name == DriverSQLite {
+		db.SetMaxOpenConns(1)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("ошибка подключения к базе данных: %w", err)
+	}
+
+	if err := migrate(ctx, db, d, logger); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	logger.WithField("driver", config.Driver).Info("Подключено SQL-хранилище уведомлений")
+
+	return &SQLDatabase{
+		db:      db,
+		dialect: d,
+	}, nil
+}
+
+func (d *SQLDatabase) Close() error {
+	return d.db.Close()
+}
@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+	"github.com/google/uuid"
+)
+
+type SQLOfflineQueue struct {
+	db      *sql.DB
+	dialect dialect
+}
+
+func NewSQLOfflineQueue(database *SQLDatabase) *SQLOfflineQueue {
+	return &SQLOfflineQueue{
+		db:      database.db,
+		dialect: database.dialect,
+	}
+}
+
+func (q *SQLOfflineQueue) Push(ctx context.Context, notification *domain.Notification) error {
+	payload, err := json.Marshal(notification)
+	if err != nil {
+		return fmt.Errorf("ошибка сериализации уведомления для офлайн-очереди: %w", err)
+	}
+
+	_, err = q.db.ExecContext(ctx, q.dialect.rebind(
+		"INSERT INTO offline_queue (id, user_id, payload, created_at) VALUES (?, ?, ?, ?)"),
+		uuid.New().String(),
+		notification.UserID,
+		string(payload),
+		time.Now().UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("ошибка сохранения уведомления в офлайн-очередь: %w", err)
+	}
+
+	return nil
+}
+
+func (q *SQLOfflineQueue) PopAll(ctx context.Context, userID string) ([]*domain.Notification, error) {
+	tx, err := q.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения офлайн-очереди: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, q.dialect.rebind(
+		"SELECT id, payload FROM offline_queue WHERE user_id = ? ORDER BY created_at"), userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения офлайн-очереди: %w", err)
+	}
+
+	ids := []string{}
+	notifications := []*domain.Notification{}
+	for rows.Next() {
+		var id, payload string
+		if err := rows.Scan(&id, &payload); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("ошибка чтения офлайн-очереди: %w", err)
+		}
+
+		var notification domain.Notification
+		if err := json.Unmarshal([]byte(payload), &notification); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("ошибка чтения уведомления из офлайн-очереди: %w", err)
+		}
+
+		ids = append(ids, id)
+		notifications = append(notifications, &notification)
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка чтения офлайн-очереди: %w", err)
+	}
+
+	// Удаляются только прочитанные строки: записи, добавленные параллельно, дождутся следующего подключения
+	for _, id := range ids {
+		if _, err := tx.ExecContext(ctx, q.dialect.rebind("DELETE FROM offline_queue WHERE id = ?"), id); err != nil {
+			return nil, fmt.Errorf("ошибка очистки офлайн-очереди: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("ошибка очистки офлайн-очереди: %w", err)
+	}
+
+	return notifications, nil
+}
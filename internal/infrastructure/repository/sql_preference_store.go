@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+)
+
+type SQLPreferenceStore struct {
+	db      *sql.DB
+	dialect dialect
+}
+
+func NewSQLPreferenceStore(database *SQLDatabase) *SQLPreferenceStore {
+	return &SQLPreferenceStore{
+		db:      database.db,
+		dialect: database.dialect,
+	}
+}
+
+func (s *SQLPreferenceStore) Get(ctx context.Context, userID string) (*domain.Preferences, error) {
+	var payload string
+
+	err := s.db.QueryRowContext(ctx, s.dialect.rebind("SELECT payload FROM user_preferences WHERE user_id = ?"), userID).Scan(&payload)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.NewPreferences(userID), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения настроек пользователя: %w", err)
+	}
+
+	return decodePreferences(payload)
+}
+
+func (s *SQLPreferenceStore) Save(ctx context.Context, preferences *domain.Preferences) error {
+	payload, err := json.Marshal(preferences)
+	if err != nil {
+		return fmt.Errorf("ошибка сериализации настроек пользователя: %w", err)
+	}
+
+	_, err = s.db.ExecContext(ctx, s.dialect.rebind(
+		"INSERT INTO user_preferences (user_id, payload, updated_at) VALUES (?, ?, ?) "+
+			"ON CONFLICT (user_id) DO UPDATE SET payload = excluded.payload, updated_at = excluded.updated_at"),
+		preferences.UserID,
+		string(payload),
+		preferences.UpdatedAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("ошибка сохранения настроек пользователя: %w", err)
+	}
+
+	return nil
+}
+
+func decodePreferences(payload string) (*domain.Preferences, error) {
+	var preferences domain.Preferences
+	if err := json.Unmarshal([]byte(payload), &preferences); err != nil {
+		return nil, fmt.Errorf("ошибка чтения настроек пользователя: %w", err)
+	}
+
+	if preferences.Types == nil {
+		preferences.Types = make(map[domain.NotificationType]bool)
+	}
+	if preferences.Categories == nil {
+		preferences.Categories = make(map[string]bool)
+	}
+
+	return &preferences, nil
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+)
+
+type SQLPushStore struct {
+	db      *sql.DB
+	dialect dialect
+}
+
+func NewSQLPushStore(database *SQLDatabase) *SQLPushStore {
+	return &SQLPushStore{
+		db:      database.db,
+		dialect: database.dialect,
+	}
+}
+
+func (s *SQLPushStore) Save(ctx context.Context, subscription *domain.PushSubscription) error {
+	payload, err := json.Marshal(subscription)
+	if err != nil {
+		return fmt.Errorf("ошибка сериализации push-подписки: %w", err)
+	}
+
+	// Браузер мог перейти к другому пользователю: подписка принадлежит последнему
+	_, err = s.db.ExecContext(ctx, s.dialect.rebind(
+		"INSERT INTO push_subscriptions (endpoint, user_id, payload, created_at) VALUES (?, ?, ?, ?) "+
+			"ON CONFLICT (endpoint) DO UPDATE SET user_id = excluded.user_id, payload = excluded.payload, created_at = excluded.created_at"),
+		subscription.Endpoint,
+		subscription.UserID,
+		string(payload),
+		subscription.CreatedAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("ошибка сохранения push-подписки: %w", err)
+	}
+
+	return nil
+}
+
+func (s *SQLPushStore) FindByUserID(ctx context.Context, userID string) ([]*domain.PushSubscription, error) {
+	rows, err := s.db.QueryContext(ctx, s.dialect.rebind(
+		"SELECT payload FROM push_subscriptions WHERE user_id = ? ORDER BY created_at"), userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения push-подписок: %w", err)
+	}
+	defer rows.Close()
+
+	subscriptions := []*domain.PushSubscription{}
+	for rows.Next() {
+		var payload string
+		if err := rows.Scan(&payload); err != nil {
+			return nil, fmt.Errorf("ошибка чтения push-подписок: %w", err)
+		}
+
+		var subscription domain.PushSubscription
+		if err := json.Unmarshal([]byte(payload), &subscription); err != nil {
+			return nil, fmt.Errorf("ошибка чтения push-подписки: %w", err)
+		}
+
+		subscriptions = append(subscriptions, &subscription)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка чтения push-подписок: %w", err)
+	}
+
+	return subscriptions, nil
+}
+
+func (s *SQLPushStore) Delete(ctx context.Context, userID string, endpoint string) error {
+	result, err := s.db.ExecContext(ctx, s.dialect.rebind(
+		"DELETE FROM push_subscriptions WHERE endpoint = ? AND user_id = ?"), endpoint, userID)
+	if err != nil {
+		return fmt.Errorf("ошибка удаления push-подписки: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("ошибка удаления push-подписки: %w", err)
+	}
+
+	if affected == 0 {
+		return domain.ErrNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+)
+
+type SQLRecurringStore struct {
+	db      *sql.DB
+	dialect dialect
+}
+
+func NewSQLRecurringStore(database *SQLDatabase) *SQLRecurringStore {
+	return &SQLRecurringStore{
+		db:      database.db,
+		dialect: database.dialect,
+	}
+}
+
+func (s *SQLRecurringStore) Save(ctx context.Context, job *domain.RecurringJob) error {
+	payload, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("ошибка сериализации периодического объявления: %w", err)
+	}
+
+	_, err = s.db.ExecContext(ctx, s.dialect.rebind(
+		"INSERT INTO recurring_jobs (id, payload, created_at, next_run) VALUES (?, ?, ?, ?) "+
+			"ON CONFLICT (id) DO UPDATE SET payload = excluded.payload, next_run = excluded.next_run"),
+		job.ID,
+		string(payload),
+		job.CreatedAt.UTC(),
+		domain.RunKey(job.NextRunAt),
+	)
+	if err != nil {
+		return fmt.Errorf("ошибка сохранения периодического объявления: %w", err)
+	}
+
+	return nil
+}
+
+func (s *SQLRecurringStore) FindByID(ctx context.Context, id string) (*domain.RecurringJob, error) {
+	var payload string
+
+	err := s.db.QueryRowContext(ctx, s.dialect.rebind("SELECT payload FROM recurring_jobs WHERE id = ?"), id).Scan(&payload)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка поиска периодического объявления: %w", err)
+	}
+
+	return decodeRecurringJob(payload)
+}
+
+func (s *SQLRecurringStore) FindAll(ctx context.Context) ([]*domain.RecurringJob, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT payload FROM recurring_jobs ORDER BY created_at")
+	if err != nil {
+		return nil, fmt.Errorf("ошибка поиска периодических объявлений: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := []*domain.RecurringJob{}
+	for rows.Next() {
+		var payload string
+		if err := rows.Scan(&payload); err != nil {
+			return nil, fmt.Errorf("ошибка чтения периодического объявления: %w", err)
+		}
+
+		job, err := decodeRecurringJob(payload)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка чтения периодических объявлений: %w", err)
+	}
+
+	return jobs, nil
+}
+
+// Advance обновляет задание условным UPDATE: из нескольких узлов, увидевших
+// один и тот же запуск, строку меняет только первый. Строки, записанные до
+// появления next_run, захватываются по NULL.
+func (s *SQLRecurringStore) Advance(ctx context.Context, job *domain.RecurringJob, previous *time.Time) (bool, error) {
+	payload, err := json.Marshal(job)
+	if err != nil {
+		return false, fmt.Errorf("ошибка сериализации периодического объявления: %w", err)
+	}
+
+	result, err := s.db.ExecContext(ctx, s.dialect.rebind(
+		"UPDATE recurring_jobs SET payload = ?, next_run = ? WHERE id = ? AND (next_run = ? OR next_run IS NULL)"),
+		string(payload),
+		domain.RunKey(job.NextRunAt),
+		job.ID,
+		domain.RunKey(previous),
+	)
+	if err != nil {
+		return false, fmt.Errorf("ошибка сохранения периодического объявления: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("ошибка сохранения периодического объявления: %w", err)
+	}
+
+	return affected > 0, nil
+}
+
+func (s *SQLRecurringStore) Delete(ctx context.Context, id string) error {
+	result, err := s.db.ExecContext(ctx, s.dialect.rebind("DELETE FROM recurring_jobs WHERE id = ?"), id)
+	if err != nil {
+		return fmt.Errorf("ошибка удаления периодического объявления: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("ошибка удаления периодического объявления: %w", err)
+	}
+	if affected == 0 {
+		return domain.ErrNotFound
+	}
+
+	return nil
+}
+
+func decodeRecurringJob(payload string) (*domain.RecurringJob, error) {
+	var job domain.RecurringJob
+	if err := json.Unmarshal([]byte(payload), &job); err != nil {
+		return nil, fmt.Errorf("ошибка чтения периодического объявления: %w", err)
+	}
+
+	return &job, nil
+}
@@ -0,0 +1,267 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+	"github.com/anatoly_dev/go-ws-notifications/pkg/logger"
+)
+
+const notificationColumns = "id, user_id, type, title, content, is_read, created_at, priority, expires_at, category, collapse_key, delivered_at, grouped_ids"
+
+type SQLRepository struct {
+	db      *sql.DB
+	dialect dialect
+	logger  *logger.Logger
+}
+
+func NewSQLRepository(database *SQLDatabase, logger *logger.Logger) *SQLRepository {
+	return &SQLRepository{
+		db:      database.db,
+		dialect: database.dialect,
+		logger:  logger,
+	}
+}
+
+func (r *SQLRepository) Save(ctx context.Context, notification *domain.Notification) error {
+	r.logger.WithField("notificationID", notification.ID).Debug("Сохранение уведомления")
+
+	groupedIDs, err := encodeGroupedIDs(notification.GroupedIDs)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx, r.dialect.rebind(
+		// Повторная доставка сообщения из Kafka перезаписывает запись, как и в MemoryRepository
+		"INSERT INTO notifications ("+notificationColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) "+
+			"ON CONFLICT (id) DO UPDATE SET user_id = excluded.user_id, type = excluded.type, "+
+			"title = excluded.title, content = excluded.content, is_read = excluded.is_read, "+
+			"created_at = excluded.created_at, priority = excluded.priority, expires_at = excluded.expires_at, "+
+			"category = excluded.category, collapse_key = excluded.collapse_key, grouped_ids = excluded.grouped_ids"),
+		notification.ID,
+		notification.UserID,
+		string(notification.Type),
+		notification.Title,
+		notification.Content,
+		notification.IsRead,
+		notification.CreatedAt.UTC(),
+		notification.Priority,
+		nullTime(notification.ExpiresAt),
+		notification.Category,
+		notification.CollapseKey,
+		nullTime(notification.DeliveredAt),
+		groupedIDs,
+	)
+	if err != nil {
+		return fmt.Errorf("ошибка сохранения уведомления: %w", err)
+	}
+
+	return nil
+}
+
+func (r *SQLRepository) FindByID(ctx context.Context, id string) (*domain.Notification, error) {
+	row := r.db.QueryRowContext(ctx, r.dialect.rebind(
+		"SELECT "+notificationColumns+" FROM notifications WHERE id = ?"), id)
+
+	notification, err := scanNotification(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка поиска уведомления: %w", err)
+	}
+
+	return notification, nil
+}
+
+func (r *SQLRepository) FindByUserID(ctx context.Context, userID string) ([]*domain.Notification, error) {
+	return r.query(ctx, "SELECT "+notificationColumns+" FROM notifications WHERE user_id = ? ORDER BY created_at", userID)
+}
+
+func (r *SQLRepository) Update(ctx context.Context, notification *domain.Notification) error {
+	groupedIDs, err := encodeGroupedIDs(notification.GroupedIDs)
+	if err != nil {
+		return err
+	}
+
+	result, err := r.db.ExecContext(ctx, r.dialect.rebind(
+		"UPDATE notifications SET user_id = ?, type = ?, title = ?, content = ?, is_read = ?, created_at = ?, priority = ?, expires_at = ?, category = ?, collapse_key = ?, grouped_ids = ? WHERE id = ?"),
+		notification.UserID,
+		string(notification.Type),
+		notification.Title,
+		notification.Content,
+		notification.IsRead,
+		notification.CreatedAt.UTC(),
+		notification.Priority,
+		nullTime(notification.ExpiresAt),
+		notification.Category,
+		notification.CollapseKey,
+		groupedIDs,
+		notification.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("ошибка обновления уведомления: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("ошибка обновления уведомления: %w", err)
+	}
+	if affected == 0 {
+		return domain.ErrNotFound
+	}
+
+	r.logger.WithField("notificationID", notification.ID).Debug("Уведомление обновлено")
+
+	return nil
+}
+
+func (r *SQLRepository) FindExpired(ctx context.Context, now time.Time, limit int) ([]*domain.Notification, error) {
+	query := "SELECT " + notificationColumns + " FROM notifications WHERE expires_at IS NOT NULL AND expires_at <= ? ORDER BY expires_at"
+	args := []interface{}{now.UTC()}
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+
+	return r.query(ctx, query, args...)
+}
+
+func (r *SQLRepository) FindUnreadByCollapseKey(ctx context.Context, userID string, collapseKey string) (*domain.Notification, error) {
+	row := r.db.QueryRowContext(ctx, r.dialect.rebind(
+		"SELECT "+notificationColumns+" FROM notifications "+
+			"WHERE user_id = ? AND collapse_key = ? AND is_read = FALSE ORDER BY created_at DESC LIMIT 1"),
+		userID, collapseKey)
+
+	notification, err := scanNotification(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка поиска уведомления по ключу схлопывания: %w", err)
+	}
+
+	return notification, nil
+}
+
+func (r *SQLRepository) MarkDelivered(ctx context.Context, id string, at time.Time) error {
+	_, err := r.db.ExecContext(ctx, r.dialect.rebind(
+		"UPDATE notifications SET delivered_at = ? WHERE id = ? AND delivered_at IS NULL"), at.UTC(), id)
+	if err != nil {
+		return fmt.Errorf("ошибка отметки доставки уведомления: %w", err)
+	}
+
+	return nil
+}
+
+func (r *SQLRepository) Delete(ctx context.Context, id string) error {
+	result, err := r.db.ExecContext(ctx, r.dialect.rebind("DELETE FROM notifications WHERE id = ?"), id)
+	if err != nil {
+		return fmt.Errorf("ошибка удаления уведомления: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("ошибка удаления уведомления: %w", err)
+	}
+	if affected == 0 {
+		return domain.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *SQLRepository) query(ctx context.Context, query string, args ...interface{}) ([]*domain.Notification, error) {
+	rows, err := r.db.QueryContext(ctx, r.dialect.rebind(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка поиска уведомлений: %w", err)
+	}
+	defer rows.Close()
+
+	notifications := []*domain.Notification{}
+	for rows.Next() {
+		notification, err := scanNotification(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка чтения уведомления: %w", err)
+		}
+		notifications = append(notifications, notification)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка чтения уведомлений: %w", err)
+	}
+
+	return notifications, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanNotification(row rowScanner) (*domain.Notification, error) {
+	var notification domain.Notification
+	var notificationType string
+	var expiresAt sql.NullTime
+	var deliveredAt sql.NullTime
+	var groupedIDs string
+
+	err := row.Scan(
+		&notification.ID,
+		&notification.UserID,
+		&notificationType,
+		&notification.Title,
+		&notification.Content,
+		&notification.IsRead,
+		&notification.CreatedAt,
+		&notification.Priority,
+		&expiresAt,
+		&notification.Category,
+		&notification.CollapseKey,
+		&deliveredAt,
+		&groupedIDs,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	notification.Type = domain.NotificationType(notificationType)
+	if expiresAt.Valid {
+		notification.ExpiresAt = &expiresAt.Time
+	}
+	if deliveredAt.Valid {
+		notification.DeliveredAt = &deliveredAt.Time
+	}
+	if groupedIDs != "" {
+		if err := json.Unmarshal([]byte(groupedIDs), &notification.GroupedIDs); err != nil {
+			return nil, fmt.Errorf("ошибка разбора уведомлений дайджеста: %w", err)
+		}
+	}
+
+	return &notification, nil
+}
+
+// encodeGroupedIDs сохраняет состав дайджеста в JSON; пустая строка -
+// обычное уведомление.
+func encodeGroupedIDs(ids []string) (string, error) {
+	if len(ids) == 0 {
+		return "", nil
+	}
+
+	data, err := json.Marshal(ids)
+	if err != nil {
+		return "", fmt.Errorf("ошибка сериализации уведомлений дайджеста: %w", err)
+	}
+	return string(data), nil
+}
+
+func nullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: t.UTC(), Valid: true}
+}
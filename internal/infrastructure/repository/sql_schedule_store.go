@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+)
+
+const scheduledColumns = "id, user_id, send_at, payload, created_at, fallback_step"
+
+type SQLScheduleStore struct {
+	db      *sql.DB
+	dialect dialect
+}
+
+func NewSQLScheduleStore(database *SQLDatabase) *SQLScheduleStore {
+	return &SQLScheduleStore{
+		db:      database.db,
+		dialect: database.dialect,
+	}
+}
+
+func (s *SQLScheduleStore) Add(ctx context.Context, item *domain.ScheduledNotification) error {
+	payload, err := json.Marshal(item.Notification)
+	if err != nil {
+		return fmt.Errorf("ошибка сериализации отложенного уведомления: %w", err)
+	}
+
+	_, err = s.db.ExecContext(ctx, s.dialect.rebind(
+		"INSERT INTO scheduled_notifications ("+scheduledColumns+") VALUES (?, ?, ?, ?, ?, ?) "+
+			"ON CONFLICT (id) DO UPDATE SET user_id = excluded.user_id, send_at = excluded.send_at, "+
+			"payload = excluded.payload, created_at = excluded.created_at, fallback_step = excluded.fallback_step, "+
+			"claimed_until = NULL"),
+		item.ID,
+		item.UserID,
+		item.SendAt.UTC(),
+		string(payload),
+		item.CreatedAt.UTC(),
+		item.FallbackStep,
+	)
+	if err != nil {
+		return fmt.Errorf("ошибка сохранения отложенного уведомления: %w", err)
+	}
+
+	return nil
+}
+
+func (s *SQLScheduleStore) Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*domain.ScheduledNotification, error) {
+	subquery := "SELECT id FROM scheduled_notifications WHERE send_at <= ? AND (claimed_until IS NULL OR claimed_until <= ?) ORDER BY send_at"
+	args := []interface{}{now.Add(lease).UTC(), now.UTC(), now.UTC()}
+	if limit > 0 {
+		subquery += " LIMIT ?"
+		args = append(args, limit)
+	}
+
+	items, err := s.query(ctx, "UPDATE scheduled_notifications SET claimed_until = ? WHERE id IN ("+
+		subquery+s.dialect.skipLocked()+") RETURNING "+scheduledColumns, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	// RETURNING не сохраняет порядок подзапроса
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].SendAt.Before(items[j].SendAt)
+	})
+
+	return items, nil
+}
+
+func (s *SQLScheduleStore) FindByID(ctx context.Context, id string) (*domain.ScheduledNotification, error) {
+	row := s.db.QueryRowContext(ctx, s.dialect.rebind(
+		"SELECT "+scheduledColumns+" FROM scheduled_notifications WHERE id = ?"), id)
+
+	item, err := scanScheduled(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка поиска отложенного уведомления: %w", err)
+	}
+
+	return item, nil
+}
+
+func (s *SQLScheduleStore) FindByUserID(ctx context.Context, userID string) ([]*domain.ScheduledNotification, error) {
+	return s.query(ctx, "SELECT "+scheduledColumns+" FROM scheduled_notifications WHERE user_id = ? ORDER BY send_at", userID)
+}
+
+func (s *SQLScheduleStore) Remove(ctx context.Context, id string) error {
+	result, err := s.db.ExecContext(ctx, s.dialect.rebind("DELETE FROM scheduled_notifications WHERE id = ?"), id)
+	if err != nil {
+		return fmt.Errorf("ошибка удаления отложенного уведомления: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("ошибка удаления отложенного уведомления: %w", err)
+	}
+	if affected == 0 {
+		return domain.ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *SQLScheduleStore) query(ctx context.Context, query string, args ...interface{}) ([]*domain.ScheduledNotification, error) {
+	rows, err := s.db.QueryContext(ctx, s.dialect.rebind(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка поиска отложенных уведомлений: %w", err)
+	}
+	defer rows.Close()
+
+	items := []*domain.ScheduledNotification{}
+	for rows.Next() {
+		item, err := scanScheduled(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка чтения отложенного уведомления: %w", err)
+		}
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка чтения отложенных уведомлений: %w", err)
+	}
+
+	return items, nil
+}
+
+func scanScheduled(row rowScanner) (*domain.ScheduledNotification, error) {
+	var item domain.ScheduledNotification
+	var payload string
+
+	if err := row.Scan(&item.ID, &item.UserID, &item.SendAt, &payload, &item.CreatedAt, &item.FallbackStep); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(payload), &item.Notification); err != nil {
+		return nil, err
+	}
+
+	return &item, nil
+}
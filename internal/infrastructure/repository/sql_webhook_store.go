@@ -0,0 +1,247 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+)
+
+type SQLWebhookStore struct {
+	db      *sql.DB
+	dialect dialect
+}
+
+func NewSQLWebhookStore(database *SQLDatabase) *SQLWebhookStore {
+	return &SQLWebhookStore{
+		db:      database.db,
+		dialect: database.dialect,
+	}
+}
+
+func (s *SQLWebhookStore) Save(ctx context.Context, webhook *domain.Webhook) error {
+	payload, err := json.Marshal(webhook)
+	if err != nil {
+		return fmt.Errorf("ошибка сериализации вебхука: %w", err)
+	}
+
+	_, err = s.db.ExecContext(ctx, s.dialect.rebind(
+		"INSERT INTO webhooks (id, payload, created_at) VALUES (?, ?, ?) "+
+			"ON CONFLICT (id) DO UPDATE SET payload = excluded.payload"),
+		webhook.ID,
+		string(payload),
+		webhook.CreatedAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("ошибка сохранения вебхука: %w", err)
+	}
+
+	return nil
+}
+
+func (s *SQLWebhookStore) FindByID(ctx context.Context, id string) (*domain.Webhook, error) {
+	var payload string
+
+	err := s.db.QueryRowContext(ctx, s.dialect.rebind("SELECT payload FROM webhooks WHERE id = ?"), id).Scan(&payload)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка поиска вебхука: %w", err)
+	}
+
+	var webhook domain.Webhook
+	if err := json.Unmarshal([]byte(payload), &webhook); err != nil {
+		return nil, fmt.Errorf("ошибка чтения вебхука: %w", err)
+	}
+
+	return &webhook, nil
+}
+
+func (s *SQLWebhookStore) FindAll(ctx context.Context) ([]*domain.Webhook, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT payload FROM webhooks ORDER BY created_at")
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения вебхуков: %w", err)
+	}
+	defer rows.Close()
+
+	webhooks := []*domain.Webhook{}
+	for rows.Next() {
+		var payload string
+		if err := rows.Scan(&payload); err != nil {
+			return nil, fmt.Errorf("ошибка чтения вебхуков: %w", err)
+		}
+
+		var webhook domain.Webhook
+		if err := json.Unmarshal([]byte(payload), &webhook); err != nil {
+			return nil, fmt.Errorf("ошибка чтения вебхука: %w", err)
+		}
+
+		webhooks = append(webhooks, &webhook)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка чтения вебхуков: %w", err)
+	}
+
+	return webhooks, nil
+}
+
+func (s *SQLWebhookStore) Delete(ctx context.Context, id string) error {
+	result, err := s.db.ExecContext(ctx, s.dialect.rebind("DELETE FROM webhooks WHERE id = ?"), id)
+	if err != nil {
+		return fmt.Errorf("ошибка удаления вебхука: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("ошибка удаления вебхука: %w", err)
+	}
+	if affected == 0 {
+		return domain.ErrNotFound
+	}
+
+	return nil
+}
+
+type SQLWebhookDeliveryStore struct {
+	db      *sql.DB
+	dialect dialect
+}
+
+func NewSQLWebhookDeliveryStore(database *SQLDatabase) *SQLWebhookDeliveryStore {
+	return &SQLWebhookDeliveryStore{
+		db:      database.db,
+		dialect: database.dialect,
+	}
+}
+
+func (s *SQLWebhookDeliveryStore) Save(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	payload, err := json.Marshal(delivery)
+	if err != nil {
+		return fmt.Errorf("ошибка сериализации доставки вебхука: %w", err)
+	}
+
+	_, err = s.db.ExecContext(ctx, s.dialect.rebind(
+		"INSERT INTO webhook_deliveries (id, webhook_id, status, next_attempt_at, payload, created_at) VALUES (?, ?, ?, ?, ?, ?) "+
+			"ON CONFLICT (id) DO UPDATE SET status = excluded.status, next_attempt_at = excluded.next_attempt_at, payload = excluded.payload"),
+		delivery.ID,
+		delivery.WebhookID,
+		string(delivery.Status),
+		delivery.NextAttemptAt.UTC(),
+		string(payload),
+		delivery.CreatedAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("ошибка сохранения доставки вебхука: %w", err)
+	}
+
+	return nil
+}
+
+func (s *SQLWebhookDeliveryStore) FindByID(ctx context.Context, id string) (*domain.WebhookDelivery, error) {
+	var payload string
+
+	err := s.db.QueryRowContext(ctx, s.dialect.rebind("SELECT payload FROM webhook_deliveries WHERE id = ?"), id).Scan(&payload)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка поиска доставки вебхука: %w", err)
+	}
+
+	return decodeWebhookDelivery(payload)
+}
+
+func (s *SQLWebhookDeliveryStore) FindByWebhookID(ctx context.Context, webhookID string, limit int) ([]*domain.WebhookDelivery, error) {
+	query := "SELECT payload FROM webhook_deliveries WHERE webhook_id = ? ORDER BY created_at DESC"
+	args := []interface{}{webhookID}
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+
+	return s.query(ctx, query, args...)
+}
+
+// Claim сдвигает next_attempt_at захваченных строк на время захвата; в
+// payload остаётся настоящее время попытки, и Save после неё возвращает
+// столбцу значение из доставки.
+func (s *SQLWebhookDeliveryStore) Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*domain.WebhookDelivery, error) {
+	subquery := "SELECT id FROM webhook_deliveries WHERE status = ? AND next_attempt_at <= ? ORDER BY next_attempt_at"
+	args := []interface{}{now.Add(lease).UTC(), string(domain.WebhookPending), now.UTC()}
+	if limit > 0 {
+		subquery += " LIMIT ?"
+		args = append(args, limit)
+	}
+
+	deliveries, err := s.query(ctx, "UPDATE webhook_deliveries SET next_attempt_at = ? WHERE id IN ("+
+		subquery+s.dialect.skipLocked()+") RETURNING payload", args...)
+	if err != nil {
+		return nil, err
+	}
+
+	// RETURNING не сохраняет порядок подзапроса
+	sort.Slice(deliveries, func(i, j int) bool {
+		return deliveries[i].NextAttemptAt.Before(deliveries[j].NextAttemptAt)
+	})
+
+	return deliveries, nil
+}
+
+func (s *SQLWebhookDeliveryStore) DeleteFinishedBefore(ctx context.Context, before time.Time) (int, error) {
+	result, err := s.db.ExecContext(ctx, s.dialect.rebind(
+		"DELETE FROM webhook_deliveries WHERE status <> ? AND created_at < ?"), string(domain.WebhookPending), before.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("ошибка удаления журнала доставок вебхуков: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("ошибка удаления журнала доставок вебхуков: %w", err)
+	}
+
+	return int(affected), nil
+}
+
+func (s *SQLWebhookDeliveryStore) query(ctx context.Context, query string, args ...interface{}) ([]*domain.WebhookDelivery, error) {
+	rows, err := s.db.QueryContext(ctx, s.dialect.rebind(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка поиска доставок вебхуков: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []*domain.WebhookDelivery{}
+	for rows.Next() {
+		var payload string
+		if err := rows.Scan(&payload); err != nil {
+			return nil, fmt.Errorf("ошибка чтения доставки вебхука: %w", err)
+		}
+
+		delivery, err := decodeWebhookDelivery(payload)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка чтения доставок вебхуков: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+func decodeWebhookDelivery(payload string) (*domain.WebhookDelivery, error) {
+	var delivery domain.WebhookDelivery
+	if err := json.Unmarshal([]byte(payload), &delivery); err != nil {
+		return nil, fmt.Errorf("ошибка чтения доставки вебхука: %w", err)
+	}
+
+	return &delivery, nil
+}
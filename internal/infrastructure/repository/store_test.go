@@ -0,0 +1,235 @@
+package repository
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+	"github.com/anatoly_dev/go-ws-notifications/pkg/logger"
+)
+
+// testBackend - пустое хранилище одного из бэкендов. Контрактные тесты
+// получают через него реализации интерфейсов domain и проверяют, что все
+// бэкенды ведут себя одинаково.
+type testBackend struct {
+	ctx context.Context
+	// now - общее время сценария; SQLite хранит время с точностью до секунд
+	now time.Time
+	log *logger.Logger
+	// Открыт один из бэкендов
+	sql  *SQLDatabase
+	bolt *BoltDatabase
+}
+
+func (b *testBackend) notifications(t *testing.T) domain.NotificationRepository {
+	if b.bolt == nil {
+		return NewSQLRepository(b.sql, b.log)
+	}
+
+	repository, err := NewBoltRepository(b.bolt, b.log)
+	mustOpen(t, err)
+	return repository
+}
+
+func (b *testBackend) schedule(t *testing.T) domain.ScheduleStore {
+	if b.bolt == nil {
+		return NewSQLScheduleStore(b.sql)
+	}
+
+	store, err := NewBoltScheduleStore(b.bolt)
+	mustOpen(t, err)
+	return store
+}
+
+func (b *testBackend) recurring(t *testing.T) domain.RecurringJobStore {
+	if b.bolt == nil {
+		return NewSQLRecurringStore(b.sql)
+	}
+
+	store, err := NewBoltRecurringStore(b.bolt)
+	mustOpen(t, err)
+	return store
+}
+
+func (b *testBackend) preferences(t *testing.T) domain.PreferenceStore {
+	if b.bolt == nil {
+		return NewSQLPreferenceStore(b.sql)
+	}
+
+	store, err := NewBoltPreferenceStore(b.bolt)
+	mustOpen(t, err)
+	return store
+}
+
+func (b *testBackend) broadcasts(t *testing.T) domain.BroadcastStore {
+	if b.bolt == nil {
+		return NewSQLBroadcastStore(b.sql)
+	}
+
+	store, err := NewBoltBroadcastStore(b.bolt)
+	mustOpen(t, err)
+	return store
+}
+
+func (b *testBackend) offlineQueue(t *testing.T) domain.OfflineQueue {
+	if b.bolt == nil {
+		return NewSQLOfflineQueue(b.sql)
+	}
+
+	queue, err := NewBoltOfflineQueue(b.bolt)
+	mustOpen(t, err)
+	return queue
+}
+
+func (b *testBackend) pushSubscriptions(t *testing.T) domain.PushSubscriptionStore {
+	if b.bolt == nil {
+		return NewSQLPushStore(b.sql)
+	}
+
+	store, err := NewBoltPushStore(b.bolt)
+	mustOpen(t, err)
+	return store
+}
+
+func (b *testBackend) webhooks(t *testing.T) domain.WebhookStore {
+	if b.bolt == nil {
+		return NewSQLWebhookStore(b.sql)
+	}
+
+	store, err := NewBoltWebhookStore(b.bolt)
+	mustOpen(t, err)
+	return store
+}
+
+func (b *testBackend) webhookDeliveries(t *testing.T) domain.WebhookDeliveryStore {
+	if b.bolt == nil {
+		return NewSQLWebhookDeliveryStore(b.sql)
+	}
+
+	store, err := NewBoltWebhookDeliveryStore(b.bolt)
+	mustOpen(t, err)
+	return store
+}
+
+// mustOpen завершает тест, если хранилище bolt не создало свои бакеты.
+func mustOpen(t *testing.T, err error) {
+	t.Helper()
+
+	if err != nil {
+		t.Fatalf("создание хранилища: %v", err)
+	}
+}
+
+// storeTest - сценарий, который выполняется на каждом бэкенде в пустой базе.
+type storeTest struct {
+	name string
+	run  func(t *testing.T, backend *testBackend)
+}
+
+func runStoreTests(t *testing.T, tests []storeTest) {
+	t.Helper()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			forEachBackend(t, tt.run)
+		})
+	}
+}
+
+// forEachBackend запускает test на SQL-базах из forEachSQLConfig и на
+// bolt в t.TempDir().
+func forEachBackend(t *testing.T, test func(t *testing.T, backend *testBackend)) {
+	t.Helper()
+
+	forEachSQLDatabase(t, func(t *testing.T, database *SQLDatabase) {
+		backend := newTestBackend(t)
+		backend.sql = database
+		test(t, backend)
+	})
+
+	t.Run(DriverBolt, func(t *testing.T) {
+		backend := newTestBackend(t)
+		backend.bolt = openTestBoltDatabase(t, filepath.Join(t.TempDir(), "notifications.db"))
+		test(t, backend)
+	})
+}
+
+func newTestBackend(t *testing.T) *testBackend {
+	return &testBackend{
+		ctx: context.Background(),
+		now: time.Now().UTC().Truncate(time.Second),
+		log: newTestLogger(t),
+	}
+}
+
+func openTestBoltDatabase(t *testing.T, path string) *BoltDatabase {
+	t.Helper()
+
+	database, err := OpenBoltDatabase(&BoltConfig{Path: path}, newTestLogger(t))
+	if err != nil {
+		t.Fatalf("OpenBoltDatabase: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	return database
+}
+
+func newTestLogger(t *testing.T) *logger.Logger {
+	t.Helper()
+
+	log, err := logger.NewLogger("error", false)
+	if err != nil {
+		t.Fatalf("logger.NewLogger: %v", err)
+	}
+
+	return log
+}
+
+func testNotification(id string, createdAt time.Time) *domain.Notification {
+	return &domain.Notification{
+		ID:        id,
+		UserID:    "user-1",
+		Type:      domain.NotificationType("message"),
+		Title:     "Title " + id,
+		Content:   "Content " + id,
+		CreatedAt: createdAt,
+		Priority:  1,
+	}
+}
+
+// idsOf собирает идентификаторы элементов в порядке выдачи хранилищем.
+func idsOf[T any](items []T, id func(item T) string) []string {
+	ids := make([]string, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, id(item))
+	}
+	return ids
+}
+
+func notificationID(notification *domain.Notification) string {
+	return notification.ID
+}
+
+func assertIDs(t *testing.T, got []string, want ...string) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Errorf("ID = %v, want %v", got, want)
+		return
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Errorf("ID = %v, want %v", got, want)
+			return
+		}
+	}
+}
+
+func equalTimePtr(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
@@ -0,0 +1,284 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+)
+
+func TestWebhookStore(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend *testBackend) {
+		ctx, now, store := backend.ctx, backend.now, backend.webhooks(t)
+
+		for i, id := range []string{"w2", "w1"} {
+			webhook := &domain.Webhook{
+				ID:        id,
+				URL:       "https://hooks.example.com/" + id,
+				Secret:    "secret-" + id,
+				Types:     []domain.NotificationType{"alert"},
+				CreatedAt: now.Add(time.Duration(i) * time.Minute),
+			}
+			if err := store.Save(ctx, webhook); err != nil {
+				t.Fatalf("Save: %v", err)
+			}
+		}
+
+		webhook, err := store.FindByID(ctx, "w1")
+		if err != nil {
+			t.Fatalf("FindByID: %v", err)
+		}
+		if webhook.URL != "https://hooks.example.com/w1" || webhook.Secret != "secret-w1" || len(webhook.Types) != 1 {
+			t.Errorf("вебхук = %+v", webhook)
+		}
+
+		webhooks, err := store.FindAll(ctx)
+		if err != nil {
+			t.Fatalf("FindAll: %v", err)
+		}
+		assertIDs(t, idsOf(webhooks, webhookID), "w2", "w1")
+
+		if err := store.Delete(ctx, "w1"); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if err := store.Delete(ctx, "w1"); !errors.Is(err, domain.ErrNotFound) {
+			t.Errorf("повторный Delete = %v, want ErrNotFound", err)
+		}
+		if _, err := store.FindByID(ctx, "w1"); !errors.Is(err, domain.ErrNotFound) {
+			t.Errorf("FindByID = %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func testWebhookDelivery(id string, status domain.WebhookDeliveryStatus, createdAt time.Time, nextAttemptAt time.Time) *domain.WebhookDelivery {
+	return &domain.WebhookDelivery{
+		ID:             id,
+		WebhookID:      "w1",
+		NotificationID: "n-" + id,
+		UserID:         "user-1",
+		Payload:        json.RawMessage(`{"id":"n-` + id + `"}`),
+		Status:         status,
+		NextAttemptAt:  nextAttemptAt,
+		CreatedAt:      createdAt,
+		UpdatedAt:      createdAt,
+	}
+}
+
+func TestWebhookDeliveryStore(t *testing.T) {
+	const lease = time.Minute
+
+	runStoreTests(t, []storeTest{
+		{
+			name: "save and find",
+			run: func(t *testing.T, backend *testBackend) {
+				ctx, now, store := backend.ctx, backend.now, backend.webhookDeliveries(t)
+
+				delivery := testWebhookDelivery("d1", domain.WebhookFailed, now, now)
+				delivery.Attempts = 3
+				delivery.LastStatusCode = 500
+				delivery.LastError = "Internal Server Error"
+				delivery.ReplayOf = "d0"
+				if err := store.Save(ctx, delivery); err != nil {
+					t.Fatalf("Save: %v", err)
+				}
+
+				found, err := store.FindByID(ctx, "d1")
+				if err != nil {
+					t.Fatalf("FindByID: %v", err)
+				}
+				if found.Status != domain.WebhookFailed || found.Attempts != 3 || found.LastStatusCode != 500 ||
+					found.ReplayOf != "d0" || string(found.Payload) != string(delivery.Payload) {
+					t.Errorf("доставка = %+v, want %+v", found, delivery)
+				}
+
+				if _, err := store.FindByID(ctx, "missing"); !errors.Is(err, domain.ErrNotFound) {
+					t.Errorf("FindByID = %v, want ErrNotFound", err)
+				}
+			},
+		},
+		{
+			name: "find by webhook newest first",
+			run: func(t *testing.T, backend *testBackend) {
+				ctx, now, store := backend.ctx, backend.now, backend.webhookDeliveries(t)
+
+				for i, id := range []string{"d1", "d2", "d3"} {
+					if err := store.Save(ctx, testWebhookDelivery(id, domain.WebhookSucceeded, now.Add(time.Duration(i)*time.Minute), now)); err != nil {
+						t.Fatalf("Save: %v", err)
+					}
+				}
+				other := testWebhookDelivery("other", domain.WebhookSucceeded, now, now)
+				other.WebhookID = "w2"
+				if err := store.Save(ctx, other); err != nil {
+					t.Fatalf("Save: %v", err)
+				}
+
+				deliveries, err := store.FindByWebhookID(ctx, "w1", 0)
+				if err != nil {
+					t.Fatalf("FindByWebhookID: %v", err)
+				}
+				assertIDs(t, idsOf(deliveries, deliveryID), "d3", "d2", "d1")
+
+				deliveries, err = store.FindByWebhookID(ctx, "w1", 2)
+				if err != nil {
+					t.Fatalf("FindByWebhookID с лимитом: %v", err)
+				}
+				assertIDs(t, idsOf(deliveries, deliveryID), "d3", "d2")
+			},
+		},
+		{
+			name: "claim pending due",
+			run: func(t *testing.T, backend *testBackend) {
+				ctx, now, store := backend.ctx, backend.now, backend.webhookDeliveries(t)
+
+				for _, delivery := range []*domain.WebhookDelivery{
+					testWebhookDelivery("later", domain.WebhookPending, now, now.Add(-time.Minute)),
+					testWebhookDelivery("earlier", domain.WebhookPending, now, now.Add(-time.Hour)),
+					testWebhookDelivery("future", domain.WebhookPending, now, now.Add(time.Hour)),
+					testWebhookDelivery("done", domain.WebhookSucceeded, now, now.Add(-time.Hour)),
+				} {
+					if err := store.Save(ctx, delivery); err != nil {
+						t.Fatalf("Save: %v", err)
+					}
+				}
+
+				claimed, err := store.Claim(ctx, now, lease, 0)
+				if err != nil {
+					t.Fatalf("Claim: %v", err)
+				}
+				assertIDs(t, idsOf(claimed, deliveryID), "earlier", "later")
+
+				// В payload остаётся настоящее время попытки
+				if !claimed[0].NextAttemptAt.Equal(now.Add(-time.Hour)) {
+					t.Errorf("NextAttemptAt = %v, want %v", claimed[0].NextAttemptAt, now.Add(-time.Hour))
+				}
+			},
+		},
+		{
+			name: "claim limit and lease",
+			run: func(t *testing.T, backend *testBackend) {
+				ctx, now, store := backend.ctx, backend.now, backend.webhookDeliveries(t)
+
+				for i, id := range []string{"d1", "d2"} {
+					if err := store.Save(ctx, testWebhookDelivery(id, domain.WebhookPending, now, now.Add(-time.Duration(2-i)*time.Minute))); err != nil {
+						t.Fatalf("Save: %v", err)
+					}
+				}
+
+				claimed, err := store.Claim(ctx, now, lease, 1)
+				if err != nil {
+					t.Fatalf("Claim: %v", err)
+				}
+				assertIDs(t, idsOf(claimed, deliveryID), "d1")
+
+				claimed, err = store.Claim(ctx, now.Add(lease/2), lease, 0)
+				if err != nil {
+					t.Fatalf("Claim во время захвата: %v", err)
+				}
+				assertIDs(t, idsOf(claimed, deliveryID), "d2")
+
+				// Узел, захвативший d1, не сохранил результат: после захвата доставка снова доступна
+				claimed, err = store.Claim(ctx, now.Add(lease), lease, 0)
+				if err != nil {
+					t.Fatalf("Claim после захвата: %v", err)
+				}
+				assertIDs(t, idsOf(claimed, deliveryID), "d1")
+			},
+		},
+		{
+			name: "save releases claim",
+			run: func(t *testing.T, backend *testBackend) {
+				ctx, now, store := backend.ctx, backend.now, backend.webhookDeliveries(t)
+
+				delivery := testWebhookDelivery("d1", domain.WebhookPending, now, now.Add(-time.Minute))
+				if err := store.Save(ctx, delivery); err != nil {
+					t.Fatalf("Save: %v", err)
+				}
+				if _, err := store.Claim(ctx, now, lease, 0); err != nil {
+					t.Fatalf("Claim: %v", err)
+				}
+
+				// Повторная попытка назначена раньше окончания захвата
+				delivery.Attempts = 1
+				delivery.NextAttemptAt = now.Add(time.Second)
+				if err := store.Save(ctx, delivery); err != nil {
+					t.Fatalf("Save: %v", err)
+				}
+
+				claimed, err := store.Claim(ctx, now.Add(time.Second), lease, 0)
+				if err != nil {
+					t.Fatalf("Claim: %v", err)
+				}
+				assertIDs(t, idsOf(claimed, deliveryID), "d1")
+			},
+		},
+		{
+			name: "finished delivery is not reclaimed",
+			run: func(t *testing.T, backend *testBackend) {
+				ctx, now, store := backend.ctx, backend.now, backend.webhookDeliveries(t)
+
+				delivery := testWebhookDelivery("d1", domain.WebhookPending, now, now.Add(-time.Minute))
+				if err := store.Save(ctx, delivery); err != nil {
+					t.Fatalf("Save: %v", err)
+				}
+				if _, err := store.Claim(ctx, now, lease, 0); err != nil {
+					t.Fatalf("Claim: %v", err)
+				}
+
+				delivery.Status = domain.WebhookSucceeded
+				delivery.Attempts = 1
+				if err := store.Save(ctx, delivery); err != nil {
+					t.Fatalf("Save: %v", err)
+				}
+
+				claimed, err := store.Claim(ctx, now.Add(lease), lease, 0)
+				if err != nil {
+					t.Fatalf("Claim после захвата: %v", err)
+				}
+				assertIDs(t, idsOf(claimed, deliveryID))
+			},
+		},
+		{
+			name: "delete finished",
+			run: func(t *testing.T, backend *testBackend) {
+				ctx, now, store := backend.ctx, backend.now, backend.webhookDeliveries(t)
+
+				for _, delivery := range []*domain.WebhookDelivery{
+					testWebhookDelivery("succeeded", domain.WebhookSucceeded, now.Add(-2*time.Hour), now),
+					testWebhookDelivery("failed", domain.WebhookFailed, now.Add(-2*time.Hour), now),
+					testWebhookDelivery("pending", domain.WebhookPending, now.Add(-2*time.Hour), now),
+					testWebhookDelivery("recent", domain.WebhookSucceeded, now, now),
+				} {
+					if err := store.Save(ctx, delivery); err != nil {
+						t.Fatalf("Save: %v", err)
+					}
+				}
+
+				deleted, err := store.DeleteFinishedBefore(ctx, now.Add(-time.Hour))
+				if err != nil {
+					t.Fatalf("DeleteFinishedBefore: %v", err)
+				}
+				if deleted != 2 {
+					t.Errorf("DeleteFinishedBefore = %d, want 2", deleted)
+				}
+
+				deliveries, err := store.FindByWebhookID(ctx, "w1", 0)
+				if err != nil {
+					t.Fatalf("FindByWebhookID: %v", err)
+				}
+				if len(deliveries) != 2 {
+					t.Errorf("осталось %d доставок, want 2", len(deliveries))
+				}
+			},
+		},
+	})
+}
+
+func webhookID(webhook *domain.Webhook) string {
+	return webhook.ID
+}
+
+func deliveryID(delivery *domain.WebhookDelivery) string {
+	return delivery.ID
+}
@@ -0,0 +1,69 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+)
+
+type Config struct {
+	Enabled     bool
+	ServiceName string
+	Endpoint    string
+	Insecure    bool
+	SampleRatio float64
+}
+
+// Init настраивает глобальный TracerProvider и W3C-пропагатор.
+// Пропагатор устанавливается всегда, чтобы контекст трассировки
+// передавался дальше даже при выключенном экспорте спанов.
+func Init(ctx context.Context, config *Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	if !config.Enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	options := []otlptracehttp.Option{}
+	// Переменная окружения OTEL_EXPORTER_OTLP_ENDPOINT имеет приоритет над конфигурацией
+	if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" && config.Endpoint != "" {
+		options = append(options, otlptracehttp.WithEndpoint(config.Endpoint))
+	}
+	if config.Insecure {
+		options = append(options, otlptracehttp.WithInsecure())
+	}
+
+	exporter, err := otlptracehttp.New(ctx, options...)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка создания OTLP экспортера: %w", err)
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+		resource.WithAttributes(semconv.ServiceName(config.ServiceName)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка создания ресурса трассировки: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.SampleRatio))),
+	)
+
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
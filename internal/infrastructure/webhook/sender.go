@@ -0,0 +1,59 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+	"github.com/anatoly_dev/go-ws-notifications/internal/infrastructure/netguard"
+)
+
+// Sender отправляет доставки подписчикам подписанными POST-запросами.
+type Sender struct {
+	client *http.Client
+}
+
+func NewSender(timeout time.Duration) *Sender {
+	client := netguard.NewClient(timeout)
+	// Перенаправление POST на другой адрес не считается доставкой
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	return &Sender{client: client}
+}
+
+func (s *Sender) Send(ctx context.Context, webhook *domain.Webhook, delivery *domain.WebhookDelivery) (int, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("ошибка запроса к вебхуку: %w", err)
+	}
+
+	// Подпись пересчитывается на каждую попытку, чтобы метка времени была свежей
+	now := time.Now()
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(HeaderID, delivery.ID)
+	request.Header.Set(HeaderTimestamp, strconv.FormatInt(now.Unix(), 10))
+	request.Header.Set(HeaderSignature, Sign(webhook.Secret, now, delivery.Payload))
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка запроса к вебхуку: %w", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		details, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+		return response.StatusCode, fmt.Errorf("вебхук вернул статус %d: %s", response.StatusCode, bytes.TrimSpace(details))
+	}
+
+	// Ответ дочитывается, чтобы соединение вернулось в пул
+	_, _ = io.Copy(io.Discard, io.LimitReader(response.Body, 64<<10))
+
+	return response.StatusCode, nil
+}
@@ -0,0 +1,29 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"time"
+)
+
+const (
+	HeaderSignature = "X-Webhook-Signature"
+	HeaderID        = "X-Webhook-ID"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+)
+
+// Sign возвращает значение заголовка подписи "t=<unix>,v1=<hex>", где v1 -
+// HMAC-SHA256 секрета над "<unix>.<тело>". Метка времени входит в подпись,
+// поэтому получатель может отклонять старые запросы и повторы.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	unix := strconv.FormatInt(timestamp.Unix(), 10)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unix))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "t=" + unix + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,113 @@
+package webpush
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+const (
+	// recordSize - размер записи aes128gcm; всё сообщение помещается в одну запись
+	recordSize = 4096
+	// headerSize: salt(16) + rs(4) + idlen(1) + keyid(65)
+	headerSize = 16 + 4 + 1 + 65
+	// MaxPayloadSize - наибольший открытый текст, который влезает в запись
+	// вместе с разделителем (1 байт) и тегом AES-GCM (16 байт)
+	MaxPayloadSize = recordSize - headerSize - 16 - 1
+)
+
+var errPayloadTooLarge = errors.New("сообщение не помещается в одну запись Web Push")
+
+// encrypt шифрует payload для подписки по RFC 8291 в кодировке aes128gcm
+// (RFC 8188) со случайными солью и эфемерным ключом сервера.
+func encrypt(payload []byte, userPublicKey []byte, authSecret []byte) ([]byte, error) {
+	serverKey, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	return encryptWith(payload, userPublicKey, authSecret, salt, serverKey)
+}
+
+// encryptWith шифрует payload с заданными солью и эфемерным ключом:
+// тело = заголовок с солью и открытым ключом сервера + шифротекст.
+func encryptWith(payload []byte, userPublicKey []byte, authSecret []byte, salt []byte, serverKey *ecdh.PrivateKey) ([]byte, error) {
+	if len(payload) > MaxPayloadSize {
+		return nil, errPayloadTooLarge
+	}
+
+	if len(salt) != 16 {
+		return nil, fmt.Errorf("соль должна быть 16 байт")
+	}
+
+	userKey, err := ecdh.P256().NewPublicKey(userPublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("некорректный ключ p256dh: %w", err)
+	}
+
+	serverPublicKey := serverKey.PublicKey().Bytes()
+
+	sharedSecret, err := serverKey.ECDH(userKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// Секрет связывает ключи обеих сторон с auth-секретом подписки
+	keyInfo := append([]byte("WebPush: info\x00"), userPublicKey...)
+	keyInfo = append(keyInfo, serverPublicKey...)
+	ikm := hkdf(authSecret, sharedSecret, keyInfo, 32)
+
+	prk := hkdfExtract(salt, ikm)
+	contentKey := hkdfExpand(prk, []byte("Content-Encoding: aes128gcm\x00"), 16)
+	nonce := hkdfExpand(prk, []byte("Content-Encoding: nonce\x00"), 12)
+
+	block, err := aes.NewCipher(contentKey)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	// 0x02 отмечает последнюю запись; дополнение не используется
+	plaintext := append(append([]byte{}, payload...), 0x02)
+
+	body := make([]byte, headerSize, headerSize+len(plaintext)+gcm.Overhead())
+	copy(body, salt)
+	binary.BigEndian.PutUint32(body[16:20], recordSize)
+	body[20] = byte(len(serverPublicKey))
+	copy(body[21:], serverPublicKey)
+
+	return gcm.Seal(body, nonce, plaintext, nil), nil
+}
+
+func hkdf(salt, ikm, info []byte, length int) []byte {
+	return hkdfExpand(hkdfExtract(salt, ikm), info, length)
+}
+
+func hkdfExtract(salt, ikm []byte) []byte {
+	mac := hmac.New(sha256.New, salt)
+	mac.Write(ikm)
+	return mac.Sum(nil)
+}
+
+// hkdfExpand реализует HKDF-Expand (RFC 5869) для длины не больше одного блока SHA-256.
+func hkdfExpand(prk, info []byte, length int) []byte {
+	mac := hmac.New(sha256.New, prk)
+	mac.Write(info)
+	mac.Write([]byte{1})
+	return mac.Sum(nil)[:length]
+}
@@ -0,0 +1,93 @@
+package webpush
+
+import (
+	"crypto/ecdh"
+	"encoding/base64"
+	"testing"
+)
+
+// Пример из RFC 8291, Appendix A.
+const (
+	rfcPlaintext       = "When I grow up, I want to be a watermelon"
+	rfcServerPrivate   = "yfWPiYE-n46HLnH0KqZOF1fJJU3MYrct3AELtAQ-oRw"
+	rfcUserPublic      = "BCVxsr7N_eNgVRqvHtD0zTZsEc6-VV-JvLexhqUzORcxaOzi6-AYWXvTBHm4bjyPjs7Vd8pZGH6SRpkNtoIAiw4"
+	rfcSalt            = "DGv6ra1nlYgDCS1FRnbzlw"
+	rfcAuthSecret      = "BTBZMqHH6r4Tts7J_aSIgg"
+	rfcEncryptedRecord = "DGv6ra1nlYgDCS1FRnbzlwAAEABBBP4z9KsN6nGRTbVYI_c7VJSPQTBtkgcy27mlmlMoZIIgDll6e3vCYLocInmYWAmS6TlzAC8wEqKK6PBru3jl7A_yl95bQpu6cVPTpK4Mqgkf1CXztLVBSt2Ks3oZwbuwXPXLWyouBWLVWGNWQexSgSxsj_Qulcy4a-fN"
+)
+
+func decode(t *testing.T, value string) []byte {
+	t.Helper()
+
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		t.Fatalf("decode %q: %v", value, err)
+	}
+	return data
+}
+
+func TestEncryptWithRFC8291Vector(t *testing.T) {
+	serverKey, err := ecdh.P256().NewPrivateKey(decode(t, rfcServerPrivate))
+	if err != nil {
+		t.Fatalf("server key: %v", err)
+	}
+
+	body, err := encryptWith([]byte(rfcPlaintext), decode(t, rfcUserPublic), decode(t, rfcAuthSecret), decode(t, rfcSalt), serverKey)
+	if err != nil {
+		t.Fatalf("encryptWith: %v", err)
+	}
+
+	if got := base64.RawURLEncoding.EncodeToString(body); got != rfcEncryptedRecord {
+		t.Fatalf("encrypted record mismatch\n got: %s\nwant: %s", got, rfcEncryptedRecord)
+	}
+}
+
+func TestEncryptRejectsInvalidInput(t *testing.T) {
+	serverKey, err := ecdh.P256().NewPrivateKey(decode(t, rfcServerPrivate))
+	if err != nil {
+		t.Fatalf("server key: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		payload   []byte
+		userKey   []byte
+		salt      []byte
+		wantError error
+	}{
+		{name: "payload too large", payload: make([]byte, MaxPayloadSize+1), userKey: decode(t, rfcUserPublic), salt: decode(t, rfcSalt), wantError: errPayloadTooLarge},
+		{name: "short salt", payload: []byte(rfcPlaintext), userKey: decode(t, rfcUserPublic), salt: []byte("short")},
+		{name: "bad user key", payload: []byte(rfcPlaintext), userKey: []byte{4, 1, 2, 3}, salt: decode(t, rfcSalt)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := encryptWith(tt.payload, tt.userKey, decode(t, rfcAuthSecret), tt.salt, serverKey)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if tt.wantError != nil && err != tt.wantError {
+				t.Fatalf("got %v, want %v", err, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestEncryptUsesFreshSaltAndKey(t *testing.T) {
+	first, err := encrypt([]byte(rfcPlaintext), decode(t, rfcUserPublic), decode(t, rfcAuthSecret))
+	if err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+
+	second, err := encrypt([]byte(rfcPlaintext), decode(t, rfcUserPublic), decode(t, rfcAuthSecret))
+	if err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+
+	if string(first[:headerSize]) == string(second[:headerSize]) {
+		t.Fatal("two messages share salt and server key")
+	}
+	if len(first) != headerSize+len(rfcPlaintext)+1+16 {
+		t.Fatalf("unexpected body length %d", len(first))
+	}
+}
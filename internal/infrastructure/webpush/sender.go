@@ -0,0 +1,84 @@
+package webpush
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+	"github.com/anatoly_dev/go-ws-notifications/internal/infrastructure/netguard"
+)
+
+// Sender отправляет зашифрованные сообщения в push-сервисы браузеров
+// по протоколу RFC 8030 с аутентификацией VAPID.
+type Sender struct {
+	keys    *VAPIDKeys
+	subject string
+	client  *http.Client
+}
+
+func NewSender(keys *VAPIDKeys, subject string, timeout time.Duration) *Sender {
+	return &Sender{
+		keys:    keys,
+		subject: subject,
+		client:  netguard.NewClient(timeout),
+	}
+}
+
+func (s *Sender) Send(ctx context.Context, subscription *domain.PushSubscription, message *domain.PushMessage) error {
+	userPublicKey, err := domain.DecodePushKey(subscription.Keys.P256dh)
+	if err != nil {
+		return fmt.Errorf("некорректный ключ p256dh: %w", err)
+	}
+
+	authSecret, err := domain.DecodePushKey(subscription.Keys.Auth)
+	if err != nil {
+		return fmt.Errorf("некорректный секрет auth: %w", err)
+	}
+
+	body, err := encrypt(message.Payload, userPublicKey, authSecret)
+	if err != nil {
+		return fmt.Errorf("ошибка шифрования push-сообщения: %w", err)
+	}
+
+	authorization, err := s.keys.authorization(subscription.Endpoint, s.subject, time.Now())
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("ошибка запроса к push-сервису: %w", err)
+	}
+
+	request.Header.Set("Authorization", authorization)
+	request.Header.Set("Content-Encoding", "aes128gcm")
+	request.Header.Set("Content-Type", "application/octet-stream")
+	request.Header.Set("TTL", strconv.Itoa(int(message.TTL.Seconds())))
+	if message.Urgency != "" {
+		request.Header.Set("Urgency", message.Urgency)
+	}
+	if message.Topic != "" {
+		request.Header.Set("Topic", message.Topic)
+	}
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		return fmt.Errorf("ошибка запроса к push-сервису: %w", err)
+	}
+	defer response.Body.Close()
+
+	switch {
+	case response.StatusCode == http.StatusNotFound || response.StatusCode == http.StatusGone:
+		return domain.ErrSubscriptionGone
+	case response.StatusCode < 200 || response.StatusCode > 299:
+		details, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+		return fmt.Errorf("push-сервис вернул статус %d: %s", response.StatusCode, bytes.TrimSpace(details))
+	}
+
+	return nil
+}
@@ -0,0 +1,173 @@
+package webpush
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+)
+
+// Закрытый ключ браузера из RFC 8291, Appendix A.
+const rfcUserPrivate = "q1dXpw3UpT5VOmu_cf_v6ih07Aems3njxI-JWgLcM94"
+
+type pushRequest struct {
+	header http.Header
+	body   []byte
+}
+
+func newPushService(t *testing.T, status int) (*httptest.Server, <-chan pushRequest) {
+	t.Helper()
+
+	requests := make(chan pushRequest, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- pushRequest{header: r.Header.Clone(), body: body}
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte("push service says no\n"))
+	}))
+	t.Cleanup(server.Close)
+
+	return server, requests
+}
+
+func newTestSender(t *testing.T) *Sender {
+	t.Helper()
+
+	keys, err := ParseVAPIDKeys(rfcServerPrivate, "")
+	if err != nil {
+		t.Fatalf("ParseVAPIDKeys: %v", err)
+	}
+
+	return &Sender{
+		keys:    keys,
+		subject: "mailto:admin@example.com",
+		client:  &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// decrypt - сторона браузера: расшифровывает тело aes128gcm закрытым ключом подписки.
+func decrypt(t *testing.T, body []byte) []byte {
+	t.Helper()
+
+	if len(body) < headerSize {
+		t.Fatalf("body too short: %d", len(body))
+	}
+
+	salt := body[:16]
+	serverPublicKey := body[21:headerSize]
+
+	userKey, err := ecdh.P256().NewPrivateKey(decode(t, rfcUserPrivate))
+	if err != nil {
+		t.Fatalf("user key: %v", err)
+	}
+
+	serverKey, err := ecdh.P256().NewPublicKey(serverPublicKey)
+	if err != nil {
+		t.Fatalf("server key: %v", err)
+	}
+
+	sharedSecret, err := userKey.ECDH(serverKey)
+	if err != nil {
+		t.Fatalf("ecdh: %v", err)
+	}
+
+	keyInfo := append([]byte("WebPush: info\x00"), userKey.PublicKey().Bytes()...)
+	keyInfo = append(keyInfo, serverPublicKey...)
+	prk := hkdfExtract(salt, hkdf(decode(t, rfcAuthSecret), sharedSecret, keyInfo, 32))
+
+	block, err := aes.NewCipher(hkdfExpand(prk, []byte("Content-Encoding: aes128gcm\x00"), 16))
+	if err != nil {
+		t.Fatalf("cipher: %v", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatalf("gcm: %v", err)
+	}
+
+	plaintext, err := gcm.Open(nil, hkdfExpand(prk, []byte("Content-Encoding: nonce\x00"), 12), body[headerSize:], nil)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+
+	return bytes.TrimSuffix(plaintext, []byte{0x02})
+}
+
+func TestSenderSend(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		wantGone  bool
+		wantError bool
+	}{
+		{name: "created", status: http.StatusCreated},
+		{name: "not found", status: http.StatusNotFound, wantGone: true, wantError: true},
+		{name: "gone", status: http.StatusGone, wantGone: true, wantError: true},
+		{name: "server error", status: http.StatusServiceUnavailable, wantError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, requests := newPushService(t, tt.status)
+			sender := newTestSender(t)
+
+			subscription := &domain.PushSubscription{
+				UserID:   "user1",
+				Endpoint: server.URL + "/push/abc",
+				Keys:     domain.PushKeys{P256dh: rfcUserPublic, Auth: rfcAuthSecret},
+			}
+			message := &domain.PushMessage{
+				Payload: []byte(`{"title":"hello"}`),
+				TTL:     90 * time.Second,
+				Urgency: "high",
+				Topic:   "order-42",
+			}
+
+			err := sender.Send(context.Background(), subscription, message)
+
+			switch {
+			case !tt.wantError && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tt.wantError && err == nil:
+				t.Fatal("expected error")
+			case tt.wantGone != errors.Is(err, domain.ErrSubscriptionGone):
+				t.Fatalf("ErrSubscriptionGone = %v, want %v (err: %v)", errors.Is(err, domain.ErrSubscriptionGone), tt.wantGone, err)
+			}
+			if tt.status >= 500 && !strings.Contains(err.Error(), "push service says no") {
+				t.Fatalf("error does not carry the response body: %v", err)
+			}
+
+			request := <-requests
+			wantHeaders := map[string]string{
+				"Content-Encoding": "aes128gcm",
+				"Content-Type":     "application/octet-stream",
+				"Ttl":              "90",
+				"Urgency":          "high",
+				"Topic":            "order-42",
+			}
+			for name, want := range wantHeaders {
+				if got := request.header.Get(name); got != want {
+					t.Errorf("header %s = %q, want %q", name, got, want)
+				}
+			}
+
+			authorization := request.header.Get("Authorization")
+			if !strings.HasPrefix(authorization, "vapid t=") || !strings.HasSuffix(authorization, ", k="+sender.keys.PublicKey()) {
+				t.Errorf("unexpected Authorization header %q", authorization)
+			}
+
+			if got := decrypt(t, request.body); string(got) != string(message.Payload) {
+				t.Errorf("decrypted payload = %q, want %q", got, message.Payload)
+			}
+		})
+	}
+}
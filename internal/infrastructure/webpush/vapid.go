@@ -0,0 +1,102 @@
+package webpush
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/url"
+	"time"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+)
+
+// vapidTokenTTL - срок действия подписи VAPID; RFC 8292 допускает не более суток
+const vapidTokenTTL = 12 * time.Hour
+
+// VAPIDKeys - пара ключей P-256 сервера приложения (RFC 8292).
+type VAPIDKeys struct {
+	private *ecdsa.PrivateKey
+	public  []byte
+}
+
+// ParseVAPIDKeys принимает ключи в base64url, как их генерирует
+// `npx web-push generate-vapid-keys`: закрытый - 32 байта скаляра,
+// открытый - 65 байт несжатой точки. Открытый ключ можно не указывать,
+// он вычисляется из закрытого.
+func ParseVAPIDKeys(privateKey string, publicKey string) (*VAPIDKeys, error) {
+	scalar, err := domain.DecodePushKey(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка декодирования закрытого VAPID-ключа: %w", err)
+	}
+
+	key, err := ecdh.P256().NewPrivateKey(scalar)
+	if err != nil {
+		return nil, fmt.Errorf("некорректный закрытый VAPID-ключ: %w", err)
+	}
+
+	public := key.PublicKey().Bytes()
+	if publicKey != "" {
+		configured, err := domain.DecodePushKey(publicKey)
+		if err != nil || string(configured) != string(public) {
+			return nil, fmt.Errorf("открытый VAPID-ключ не соответствует закрытому")
+		}
+	}
+
+	return &VAPIDKeys{
+		private: &ecdsa.PrivateKey{
+			PublicKey: ecdsa.PublicKey{
+				Curve: elliptic.P256(),
+				X:     new(big.Int).SetBytes(public[1:33]),
+				Y:     new(big.Int).SetBytes(public[33:]),
+			},
+			D: new(big.Int).SetBytes(scalar),
+		},
+		public: public,
+	}, nil
+}
+
+// PublicKey возвращает открытый ключ в base64url для applicationServerKey.
+func (k *VAPIDKeys) PublicKey() string {
+	return base64.RawURLEncoding.EncodeToString(k.public)
+}
+
+// authorization формирует заголовок Authorization для push-сервиса endpoint:
+// JWT ES256 с aud - origin push-сервиса и sub - контактом владельца.
+func (k *VAPIDKeys) authorization(endpoint string, subject string, now time.Time) (string, error) {
+	target, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("некорректный endpoint подписки: %w", err)
+	}
+
+	header, _ := json.Marshal(map[string]string{"typ": "JWT", "alg": "ES256"})
+	claims, err := json.Marshal(map[string]interface{}{
+		"aud": target.Scheme + "://" + target.Host,
+		"exp": now.Add(vapidTokenTTL).Unix(),
+		"sub": subject,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+
+	r, s, err := ecdsa.Sign(rand.Reader, k.private, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("ошибка подписи VAPID: %w", err)
+	}
+
+	// JWS хранит подпись ES256 как r||s фиксированной длины, а не в DER
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+
+	token := signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+	return "vapid t=" + token + ", k=" + k.PublicKey(), nil
+}
@@ -0,0 +1,136 @@
+package websocket
+
+import (
+	"fmt"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+	"github.com/anatoly_dev/go-ws-notifications/internal/infrastructure/metrics"
+)
+
+// Subscribe подписывает сокет connectionID на канал. Подписка живёт, пока
+// открыт сокет: после переподключения её нужно повторить.
+func (s *Service) Subscribe(userID string, connectionID string, channel string) error {
+	client, err := s.getConnection(userID, connectionID)
+	if err != nil {
+		return err
+	}
+
+	s.channelsLock.Lock()
+	defer s.channelsLock.Unlock()
+
+	if _, ok := client.channels[channel]; ok {
+		return nil
+	}
+
+	if s.config.MaxChannels > 0 && len(client.channels) >= s.config.MaxChannels {
+		return fmt.Errorf("%w: превышен лимит подписок на каналы (%d)", domain.ErrInvalidInput, s.config.MaxChannels)
+	}
+
+	subscribers, ok := s.channels[channel]
+	if !ok {
+		subscribers = make(map[*Client]struct{})
+		s.channels[channel] = subscribers
+	}
+
+	subscribers[client] = struct{}{}
+	client.channels[channel] = struct{}{}
+	metrics.ChannelSubscriptions.Inc()
+
+	return nil
+}
+
+func (s *Service) Unsubscribe(userID string, connectionID string, channel string) error {
+	client, err := s.getConnection(userID, connectionID)
+	if err != nil {
+		return err
+	}
+
+	s.channelsLock.Lock()
+	defer s.channelsLock.Unlock()
+
+	s.unsubscribeUnsafe(client, channel)
+
+	return nil
+}
+
+// Subscriptions возвращает каналы, на которые подписан текущий сокет пользователя.
+func (s *Service) Subscriptions(userID string) []string {
+	client, err := s.getClient(userID)
+	if err != nil {
+		return []string{}
+	}
+
+	s.channelsLock.RLock()
+	defer s.channelsLock.RUnlock()
+
+	channels := make([]string, 0, len(client.channels))
+	for channel := range client.channels {
+		channels = append(channels, channel)
+	}
+
+	return channels
+}
+
+func (s *Service) PublishToChannel(channel string, message []byte) int {
+	s.channelsLock.RLock()
+	subscribers := make([]*Client, 0, len(s.channels[channel]))
+	for client := range s.channels[channel] {
+		subscribers = append(subscribers, client)
+	}
+	s.channelsLock.RUnlock()
+
+	for _, client := range subscribers {
+		if err := client.Send(message); err != nil {
+			s.logger.WithError(err).WithFields(map[string]interface{}{
+				"userID":  client.userID,
+				"channel": channel,
+			}).Error("Ошибка отправки сообщения канала клиенту")
+		}
+	}
+
+	metrics.ChannelMessagesDelivered.Add(float64(len(subscribers)))
+
+	return len(subscribers)
+}
+
+// getConnection возвращает сокет пользователя, только если это всё ещё
+// connectionID: команда закрытого сокета не должна менять подписки сокета,
+// который его заменил.
+func (s *Service) getConnection(userID string, connectionID string) (*Client, error) {
+	client, err := s.getClient(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if client.id != connectionID {
+		s.logger.WithField("userID", userID).Debug("Команда от заменённого сокета")
+		return nil, domain.ErrUserNotConnected
+	}
+
+	return client, nil
+}
+
+// removeSubscriptions снимает все подписки сокета при его закрытии.
+func (s *Service) removeSubscriptions(client *Client) {
+	s.channelsLock.Lock()
+	defer s.channelsLock.Unlock()
+
+	for channel := range client.channels {
+		s.unsubscribeUnsafe(client, channel)
+	}
+}
+
+func (s *Service) unsubscribeUnsafe(client *Client, channel string) {
+	if _, ok := client.channels[channel]; !ok {
+		return
+	}
+
+	delete(client.channels, channel)
+	metrics.ChannelSubscriptions.Dec()
+
+	subscribers := s.channels[channel]
+	delete(subscribers, client)
+	if len(subscribers) == 0 {
+		delete(s.channels, channel)
+	}
+}
@@ -1,54 +1,196 @@
 package websocket
 
 import (
+	"context"
 	"sync"
 	"time"
 
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+	"github.com/anatoly_dev/go-ws-notifications/internal/infrastructure/metrics"
 	"github.com/anatoly_dev/go-ws-notifications/pkg/logger"
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+const defaultSendBufferSize = 256
+
+var tracer = otel.Tracer("github.com/anatoly_dev/go-ws-notifications/internal/infrastructure/websocket")
+
+type outboundMessage struct {
+	data         []byte
+	notification *domain.Notification
+	priority     int
+	sequence     uint64 // порядок постановки в буфер
+	// Контекст трассировки отправителя, чтобы спан записи фрейма
+	// продолжал трассу от Kafka/REST
+	spanContext trace.SpanContext
+}
+
 type Client struct {
-	conn       *websocket.Conn
-	send       chan []byte
-	userID     string
-	logger     *logger.Logger
-	config     *Config
-	isClosed   bool
-	closeMutex sync.Mutex
+	id          string // отличает сокет от следующих соединений того же пользователя
+	conn        Connection
+	queue       *outboundQueue
+	userID      string
+	attributes  domain.ClientAttributes
+	logger      *logger.Logger
+	config      *Config
+	isClosed    bool
+	closeMutex  sync.Mutex
+	closeCode   int
+	closeReason string
+	done        chan struct{}
+	tracker     domain.DeliveryTracker
+	channels    map[string]struct{} // защищено Service.channelsLock
+	spill       SpillHandler
+	refill      ConnectHandler
+	written     WriteHandler
+	spilled     bool // защищено closeMutex: в офлайн-очереди есть уведомления клиента
 }
 
-func NewClient(conn *websocket.Conn, userID string, config *Config, logger *logger.Logger) *Client {
+// InboundHandler обрабатывает сообщения, присланные клиентом. connectionID
+// указывает сокет, из которого пришло сообщение.
+type InboundHandler func(ctx context.Context, userID string, connectionID string, message []byte)
+
+// ConnectHandler вызывается после регистрации нового соединения.
+type ConnectHandler func(ctx context.Context, userID string, attributes domain.ClientAttributes)
+
+// WriteHandler вызывается после записи фрейма уведомления в соединение.
+type WriteHandler func(notification *domain.Notification)
+
+// SpillHandler принимает уведомление, не поместившееся в буфер клиента
+// при политике spill.
+type SpillHandler func(notification *domain.Notification)
+
+func NewClient(
+	conn Connection,
+	userID string,
+	attributes domain.ClientAttributes,
+	config *Config,
+	tracker domain.DeliveryTracker,
+	logger *logger.Logger,
+) *Client {
 	return &Client{
-		conn:     conn,
-		send:     make(chan []byte, 256),
-		userID:   userID,
-		logger:   logger.WithField("userID", userID),
-		config:   config,
-		isClosed: false,
+		id:         uuid.New().String(),
+		conn:       conn,
+		queue:      newOutboundQueue(sendBufferSize(config)),
+		userID:     userID,
+		attributes: attributes,
+		logger:     logger.WithField("userID", userID),
+		config:     config,
+		isClosed:   false,
+		closeCode:  websocket.CloseNormalClosure,
+		done:       make(chan struct{}),
+		tracker:    tracker,
+		channels:   make(map[string]struct{}),
 	}
 }
 
-func (c *Client) StartListening(unregisterFunc func(userID string)) {
+func (c *Client) StartListening(unregisterFunc func(client *Client), inboundFunc InboundHandler) {
+	metrics.ActiveConnections.Inc()
+
 	go c.writePump()
-	go c.readPump(unregisterFunc)
+	go c.readPump(unregisterFunc, inboundFunc)
 }
 
 func (c *Client) Send(message []byte) error {
+	return c.enqueue(&outboundMessage{data: message, priority: defaultMessagePriority})
+}
+
+func (c *Client) SendNotification(ctx context.Context, notification *domain.Notification, message []byte) error {
+	return c.enqueue(&outboundMessage{
+		data:         message,
+		notification: notification,
+		priority:     notification.Priority,
+		spanContext:  trace.SpanContextFromContext(ctx),
+	})
+}
+
+func (c *Client) enqueue(message *outboundMessage) error {
+	spilled, err := c.enqueueLocked(message)
+
+	// Запись в офлайн-очередь может обращаться к хранилищу, поэтому выполняется без блокировки
+	if spilled != nil {
+		c.spill(spilled)
+	}
+
+	return err
+}
+
+func (c *Client) enqueueLocked(message *outboundMessage) (*domain.Notification, error) {
 	c.closeMutex.Lock()
 	defer c.closeMutex.Unlock()
 
 	if c.isClosed {
-		return nil
+		metrics.MessagesDropped.WithLabelValues("client_closed").Inc()
+		c.track(message, domain.StageFailed, "client_closed")
+		return nil, domain.ErrUserNotConnected
+	}
+
+	if c.queue.push(message) {
+		c.track(message, domain.StageQueued, "")
+		return nil, nil
+	}
+
+	policy := c.config.BackpressurePolicy
+	metrics.BackpressureEvents.WithLabelValues(policy).Inc()
+	// Пользователь пишется только в лог: как метка он дал бы неограниченное число рядов
+	c.logger.WithField("policy", policy).Warn("Буфер сообщений клиента переполнен")
+
+	switch policy {
+	case PolicyDropOldest:
+		c.drop(c.queue.dropOldest(), "dropped_oldest")
+		return nil, c.pushAfterEviction(message)
+	case PolicyDropLowestPriority:
+		evicted := c.queue.dropLowest(message.priority)
+		if evicted == nil {
+			c.drop(message, "dropped_lowest_priority")
+			return nil, domain.ErrMessageDropped
+		}
+		c.drop(evicted, "dropped_lowest_priority")
+		return nil, c.pushAfterEviction(message)
+	case PolicySpill:
+		// Фреймы без уведомления (события, каналы, объявления) сохранить нельзя
+		if message.notification == nil || c.spill == nil {
+			c.drop(message, "buffer_overflow")
+			return nil, domain.ErrMessageDropped
+		}
+		c.track(message, domain.StageSpilled, "")
+		c.spilled = true
+		return message.notification, domain.ErrMessageSpilled
 	}
 
-	select {
-	case c.send <- message:
+	// Клиент переподключится и загрузит историю, поэтому недоставленные
+	// сообщения буфера отбрасываются и учитываются как потерянные
+	metrics.BufferOverflowDisconnects.Inc()
+	c.drop(message, "buffer_overflow")
+	for queued := c.queue.dropOldest(); queued != nil; queued = c.queue.dropOldest() {
+		c.drop(queued, "buffer_overflow")
+	}
+	c.closeWithCodeUnsafe(websocket.CloseTryAgainLater, "send buffer overflow")
+	return nil, domain.ErrMessageDropped
+}
+
+func (c *Client) pushAfterEviction(message *outboundMessage) error {
+	if c.queue.push(message) {
+		c.track(message, domain.StageQueued, "")
 		return nil
-	default:
-		c.logger.Warn("Буфер сообщений клиента переполнен")
-		return c.closeUnsafe()
 	}
+
+	c.drop(message, "buffer_overflow")
+	return domain.ErrMessageDropped
+}
+
+func (c *Client) drop(message *outboundMessage, reason string) {
+	if message == nil {
+		return
+	}
+
+	metrics.MessagesDropped.WithLabelValues(reason).Inc()
+	c.track(message, domain.StageFailed, reason)
 }
 
 func (c *Client) Close() error {
@@ -58,6 +200,34 @@ func (c *Client) Close() error {
 	return c.closeUnsafe()
 }
 
+// Drain прекращает приём новых сообщений, дожидается отправки уже
+// поставленных в буфер и закрывает соединение с указанным кодом.
+func (c *Client) Drain(code int, reason string) {
+	c.closeMutex.Lock()
+	defer c.closeMutex.Unlock()
+
+	c.closeWithCodeUnsafe(code, reason)
+}
+
+// closeWithCodeUnsafe закрывает буфер: writePump допишет оставшиеся в нём
+// сообщения и отправит close-фрейм с кодом code.
+func (c *Client) closeWithCodeUnsafe(code int, reason string) {
+	if c.isClosed {
+		return
+	}
+
+	c.isClosed = true
+	c.closeCode = code
+	c.closeReason = reason
+
+	c.queue.close()
+}
+
+// Done закрывается после завершения отправки сообщений клиенту.
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
 func (c *Client) closeUnsafe() error {
 	if c.isClosed {
 		return nil
@@ -65,37 +235,26 @@ func (c *Client) closeUnsafe() error {
 
 	c.isClosed = true
 
-	close(c.send)
-
-	message := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
-	_ = c.conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(time.Second))
+	c.queue.close()
 
 	return c.conn.Close()
 }
 
-func (c *Client) readPump(unregisterFunc func(userID string)) {
+func (c *Client) readPump(unregisterFunc func(client *Client), inboundFunc InboundHandler) {
 	defer func() {
 		c.logger.Info("Завершение чтения сообщений от клиента")
 		c.Close()
-		unregisterFunc(c.userID)
+		unregisterFunc(c)
+		metrics.ActiveConnections.Dec()
 	}()
 
-	c.conn.SetReadLimit(c.config.MaxMessageSize)
-	_ = c.conn.SetReadDeadline(time.Now().Add(time.Duration(c.config.PongWait) * time.Second))
-
-	c.conn.SetPongHandler(func(string) error {
-		_ = c.conn.SetReadDeadline(time.Now().Add(time.Duration(c.config.PongWait) * time.Second))
-		return nil
-	})
-
-	for {
-		_, _, err := c.conn.ReadMessage()
-		if err != nil {
-			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				c.logger.WithError(err).Error("Неожиданная ошибка при чтении WebSocket сообщения")
-			}
-			break
+	err := c.conn.ReadLoop(func(message []byte) {
+		if inboundFunc != nil {
+			inboundFunc(context.Background(), c.userID, c.id, message)
 		}
+	})
+	if err != nil {
+		c.logger.WithError(err).Error("Неожиданная ошибка при чтении сообщения от клиента")
 	}
 }
 
@@ -105,40 +264,124 @@ func (c *Client) writePump() {
 		c.logger.Info("Завершение отправки сообщений клиенту")
 		ticker.Stop()
 		c.Close()
+		_ = c.conn.Close()
+		close(c.done)
 	}()
 
 	for {
 		select {
-		case message, ok := <-c.send:
-			_ = c.conn.SetWriteDeadline(time.Now().Add(time.Second * 10))
+		case <-c.queue.ready:
+			for {
+				message, ok := c.queue.pop()
+				if message == nil && ok {
+					break
+				}
 
-			if !ok {
-				_ = c.conn.WriteMessage(websocket.CloseMessage, []byte{})
-				return
-			}
+				if !ok {
+					c.closeMutex.Lock()
+					code, reason := c.closeCode, c.closeReason
+					c.closeMutex.Unlock()
 
-			w, err := c.conn.NextWriter(websocket.TextMessage)
-			if err != nil {
-				c.logger.WithError(err).Error("Ошибка получения writer для WebSocket")
-				return
-			}
+					_ = c.conn.WriteClose(code, reason)
+					return
+				}
 
-			_, err = w.Write(message)
-			if err != nil {
-				c.logger.WithError(err).Error("Ошибка записи сообщения в WebSocket")
-				return
-			}
-
-			if err := w.Close(); err != nil {
-				c.logger.WithError(err).Error("Ошибка закрытия writer для WebSocket")
-				return
+				if err := c.write(message); err != nil {
+					return
+				}
+				c.refillSpilled()
 			}
 		case <-ticker.C:
-			_ = c.conn.SetWriteDeadline(time.Now().Add(time.Second * 10))
-			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+			if err := c.conn.WriteHeartbeat(); err != nil {
 				c.logger.WithError(err).Error("Ошибка отправки ping-сообщения")
 				return
 			}
 		}
 	}
 }
+
+// refillSpilled возвращает клиенту уведомления из офлайн-очереди, когда
+// буфер опустился до нижней границы: клиент, который остаётся подключённым,
+// иначе получил бы их только после переподключения.
+func (c *Client) refillSpilled() {
+	if c.refill == nil || c.queue.len() > spillLowWater(c.config) {
+		return
+	}
+
+	c.closeMutex.Lock()
+	spilled := c.spilled && !c.isClosed
+	c.spilled = false
+	c.closeMutex.Unlock()
+
+	if spilled {
+		// Чтение очереди обращается к хранилищу и снова ставит фреймы в буфер
+		go c.refill(context.Background(), c.userID, c.attributes)
+	}
+}
+
+func (c *Client) write(message *outboundMessage) error {
+	// Уведомление могло истечь, пока ожидало в буфере
+	if message.notification != nil && message.notification.IsExpired(time.Now()) {
+		metrics.MessagesDropped.WithLabelValues("expired").Inc()
+		c.track(message, domain.StageFailed, "expired")
+		return nil
+	}
+
+	ctx := trace.ContextWithRemoteSpanContext(context.Background(), message.spanContext)
+	_, span := tracer.Start(ctx, "websocket.write", trace.WithAttributes(
+		attribute.String("user.id", c.userID),
+		attribute.Int("message.size", len(message.data)),
+	))
+	defer span.End()
+
+	start := time.Now()
+
+	var id string
+	if message.notification != nil {
+		id = message.notification.ID
+	}
+
+	if err := c.conn.WriteFrame(message.data, id); err != nil {
+		c.logger.WithError(err).Error("Ошибка записи сообщения клиенту")
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+
+	metrics.WriteDuration.Observe(time.Since(start).Seconds())
+	c.track(message, domain.StageWritten, "")
+
+	if message.notification != nil && c.written != nil {
+		c.written(message.notification)
+	}
+
+	if n := message.notification; n != nil {
+		metrics.MessagesSent.WithLabelValues(string(n.Type)).Inc()
+		if !n.CreatedAt.IsZero() {
+			metrics.DeliveryLatency.WithLabelValues(string(n.Type)).Observe(time.Since(n.CreatedAt).Seconds())
+		}
+	}
+
+	return nil
+}
+
+func (c *Client) track(message *outboundMessage, stage domain.DeliveryStage, details string) {
+	if c.tracker == nil || message.notification == nil {
+		return
+	}
+
+	c.tracker.Track(message.notification.ID, c.userID, stage, details)
+}
+
+// spillLowWater - заполнение буфера, при котором в него возвращаются
+// уведомления из офлайн-очереди.
+func spillLowWater(config *Config) int {
+	return sendBufferSize(config) / 4
+}
+
+func sendBufferSize(config *Config) int {
+	if config.SendBufferSize <= 0 {
+		return defaultSendBufferSize
+	}
+
+	return config.SendBufferSize
+}
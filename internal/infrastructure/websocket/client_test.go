@@ -0,0 +1,225 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+	"github.com/anatoly_dev/go-ws-notifications/internal/infrastructure/metrics"
+	"github.com/anatoly_dev/go-ws-notifications/pkg/logger"
+	"github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// fakeConnection запоминает записанные фреймы и код закрытия.
+type fakeConnection struct {
+	mutex       sync.Mutex
+	frames      []string
+	closed      bool
+	closeCode   int
+	closeReason string
+}
+
+func (c *fakeConnection) WriteFrame(data []byte, id string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.frames = append(c.frames, id)
+	return nil
+}
+
+func (c *fakeConnection) WriteHeartbeat() error {
+	return nil
+}
+
+func (c *fakeConnection) WriteClose(code int, reason string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.closeCode = code
+	c.closeReason = reason
+	return nil
+}
+
+func (c *fakeConnection) ReadLoop(handle func(message []byte)) error {
+	return nil
+}
+
+func (c *fakeConnection) Close() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.closed = true
+	return nil
+}
+
+func newTestClient(t *testing.T, policy string, bufferSize int) (*Client, *fakeConnection) {
+	t.Helper()
+
+	log, err := logger.NewLogger("error", false)
+	if err != nil {
+		t.Fatalf("logger.NewLogger: %v", err)
+	}
+
+	conn := &fakeConnection{}
+	config := &Config{SendBufferSize: bufferSize, BackpressurePolicy: policy, PingPeriod: 60}
+	return NewClient(conn, "user-1", domain.ClientAttributes{}, config, nil, log), conn
+}
+
+func testNotification(id string, priority int) *domain.Notification {
+	return &domain.Notification{ID: id, UserID: "user-1", Type: domain.TypeMessage, Priority: priority}
+}
+
+func TestClientSendNotificationOverflow(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  string
+		spill   bool
+		queued  *domain.Notification
+		sent    *domain.Notification
+		wantErr error
+		// wantSpilled - ID уведомления, переданного в офлайн-очередь
+		wantSpilled string
+		// wantCloseCode - код close-фрейма; 0 - соединение остаётся открытым
+		wantCloseCode int
+		// wantDropped - сколько сообщений учтено в MessagesDropped как buffer_overflow
+		wantDropped float64
+	}{
+		{
+			name:   "drop oldest keeps new message",
+			policy: PolicyDropOldest,
+			queued: testNotification("old", 0),
+			sent:   testNotification("new", 0),
+		},
+		{
+			name:   "drop lowest evicts queued message",
+			policy: PolicyDropLowestPriority,
+			queued: testNotification("low", 0),
+			sent:   testNotification("high", 5),
+		},
+		{
+			name:    "drop lowest drops new message",
+			policy:  PolicyDropLowestPriority,
+			queued:  testNotification("high", 5),
+			sent:    testNotification("low", 0),
+			wantErr: domain.ErrMessageDropped,
+		},
+		{
+			name:        "spill",
+			policy:      PolicySpill,
+			spill:       true,
+			queued:      testNotification("queued", 0),
+			sent:        testNotification("spilled", 0),
+			wantErr:     domain.ErrMessageSpilled,
+			wantSpilled: "spilled",
+		},
+		{
+			name:        "spill without handler",
+			policy:      PolicySpill,
+			queued:      testNotification("queued", 0),
+			sent:        testNotification("dropped", 0),
+			wantErr:     domain.ErrMessageDropped,
+			wantDropped: 1,
+		},
+		{
+			name:          "disconnect",
+			policy:        PolicyDisconnect,
+			queued:        testNotification("queued", 0),
+			sent:          testNotification("dropped", 0),
+			wantErr:       domain.ErrMessageDropped,
+			wantCloseCode: websocket.CloseTryAgainLater,
+			wantDropped:   2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, conn := newTestClient(t, tt.policy, 1)
+			overflow := metrics.MessagesDropped.WithLabelValues("buffer_overflow")
+			droppedBefore := testutil.ToFloat64(overflow)
+
+			var spilled []string
+			if tt.spill {
+				client.spill = func(notification *domain.Notification) {
+					spilled = append(spilled, notification.ID)
+				}
+			}
+
+			if err := client.SendNotification(context.Background(), tt.queued, []byte("{}")); err != nil {
+				t.Fatalf("SendNotification(%s) = %v, want nil", tt.queued.ID, err)
+			}
+
+			err := client.SendNotification(context.Background(), tt.sent, []byte("{}"))
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("SendNotification(%s) = %v, want nil", tt.sent.ID, err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SendNotification(%s) = %v, want %v", tt.sent.ID, err, tt.wantErr)
+			}
+
+			if tt.wantSpilled != "" && (len(spilled) != 1 || spilled[0] != tt.wantSpilled) {
+				t.Errorf("в офлайн-очередь перенесены %v, want [%s]", spilled, tt.wantSpilled)
+			}
+			if dropped := testutil.ToFloat64(overflow) - droppedBefore; dropped != tt.wantDropped {
+				t.Errorf("потеряно сообщений %v, want %v", dropped, tt.wantDropped)
+			}
+
+			if tt.wantCloseCode == 0 {
+				if conn.closed {
+					t.Error("соединение закрыто")
+				}
+				return
+			}
+
+			// Отброшенные сообщения не пишутся, клиент получает код закрытия
+			go client.writePump()
+			<-client.Done()
+			if !conn.closed || conn.closeCode != tt.wantCloseCode || conn.closeReason == "" {
+				t.Errorf("закрытие = %v, %d %q, want код %d с причиной", conn.closed, conn.closeCode, conn.closeReason, tt.wantCloseCode)
+			}
+			if len(conn.frames) != 0 {
+				t.Errorf("записаны отброшенные фреймы %v", conn.frames)
+			}
+		})
+	}
+}
+
+func TestClientSendNotificationClosed(t *testing.T) {
+	client, _ := newTestClient(t, PolicyDropOldest, 1)
+	if err := client.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	err := client.SendNotification(context.Background(), testNotification("n1", 0), []byte("{}"))
+	if !errors.Is(err, domain.ErrUserNotConnected) {
+		t.Errorf("SendNotification = %v, want ErrUserNotConnected", err)
+	}
+}
+
+func TestClientWriteHandler(t *testing.T) {
+	client, conn := newTestClient(t, PolicyDropOldest, 1)
+
+	var written []string
+	client.written = func(notification *domain.Notification) {
+		written = append(written, notification.ID)
+	}
+
+	if err := client.write(&outboundMessage{data: []byte("{}")}); err != nil {
+		t.Fatalf("write события: %v", err)
+	}
+	if len(written) != 0 {
+		t.Errorf("обработчик вызван для фрейма без уведомления: %v", written)
+	}
+
+	if err := client.write(&outboundMessage{data: []byte("{}"), notification: testNotification("n1", 0)}); err != nil {
+		t.Fatalf("write уведомления: %v", err)
+	}
+	if len(written) != 1 || written[0] != "n1" {
+		t.Errorf("записанные уведомления = %v, want [n1]", written)
+	}
+	if len(conn.frames) != 2 {
+		t.Errorf("записано фреймов %d, want 2", len(conn.frames))
+	}
+}
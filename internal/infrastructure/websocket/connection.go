@@ -0,0 +1,90 @@
+package websocket
+
+import (
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const writeWait = 10 * time.Second
+
+// Connection - транспорт, через который клиент получает фреймы. Реестр,
+// буфер и политики переполнения у всех транспортов общие.
+type Connection interface {
+	// WriteFrame записывает один фрейм; id непуст для фреймов уведомлений
+	WriteFrame(data []byte, id string) error
+	WriteHeartbeat() error
+	// WriteClose сообщает клиенту о закрытии соединения сервером
+	WriteClose(code int, reason string) error
+	// ReadLoop блокируется до разрыва соединения, передавая handle входящие сообщения
+	ReadLoop(handle func(message []byte)) error
+	Close() error
+}
+
+type wsConnection struct {
+	conn   *websocket.Conn
+	config *Config
+}
+
+func NewWebSocketConnection(conn *websocket.Conn, config *Config) Connection {
+	return &wsConnection{
+		conn:   conn,
+		config: config,
+	}
+}
+
+func (c *wsConnection) WriteFrame(data []byte, id string) error {
+	_ = c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+
+	w, err := c.conn.NextWriter(websocket.TextMessage)
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+
+	return w.Close()
+}
+
+func (c *wsConnection) WriteHeartbeat() error {
+	_ = c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+	return c.conn.WriteMessage(websocket.PingMessage, nil)
+}
+
+func (c *wsConnection) WriteClose(code int, reason string) error {
+	_ = c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+	return c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason))
+}
+
+func (c *wsConnection) ReadLoop(handle func(message []byte)) error {
+	pongWait := time.Duration(c.config.PongWait) * time.Second
+
+	c.conn.SetReadLimit(c.config.MaxMessageSize)
+	_ = c.conn.SetReadDeadline(time.Now().Add(pongWait))
+
+	c.conn.SetPongHandler(func(string) error {
+		_ = c.conn.SetReadDeadline(time.Now().Add(pongWait))
+		return nil
+	})
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure, websocket.CloseServiceRestart) {
+				return err
+			}
+			return nil
+		}
+
+		handle(data)
+	}
+}
+
+func (c *wsConnection) Close() error {
+	message := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+	_ = c.conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(time.Second))
+
+	return c.conn.Close()
+}
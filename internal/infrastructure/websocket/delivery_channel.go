@@ -0,0 +1,47 @@
+package websocket
+
+import (
+	"context"
+
+	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+)
+
+// TransportChannel доставляет уведомления через активное соединение
+// пользователя. Канал realtime подходит для любого транспорта, websocket,
+// sse и long_poll - только для соединения своего типа.
+type TransportChannel struct {
+	service *Service
+	name    string
+}
+
+func NewTransportChannel(service *Service, name string) *TransportChannel {
+	return &TransportChannel{
+		service: service,
+		name:    name,
+	}
+}
+
+func (c *TransportChannel) Name() string {
+	return c.name
+}
+
+func (c *TransportChannel) Deliver(ctx context.Context, notification *domain.Notification, frame []byte) error {
+	if c.name != domain.ChannelRealtime {
+		if conn, ok := c.service.Connection(notification.UserID); ok && transportName(conn) != c.name {
+			return domain.ErrChannelUnavailable
+		}
+	}
+
+	return c.service.SendNotification(ctx, notification, frame)
+}
+
+func transportName(conn Connection) string {
+	switch conn.(type) {
+	case *sseConnection:
+		return domain.ChannelSSE
+	case *PollConnection:
+		return domain.ChannelLongPoll
+	default:
+		return domain.ChannelWebSocket
+	}
+}
@@ -0,0 +1,173 @@
+package websocket
+
+import "sync"
+
+const (
+	// laneCount соответствует приоритетам уведомлений 0..5
+	laneCount = 6
+
+	// defaultMessagePriority - приоритет фреймов без уведомления: ответов на
+	// команды, служебных событий, сообщений каналов и объявлений
+	defaultMessagePriority = 3
+)
+
+// laneWeights задаёт долю записи для каждого приоритета при взвешенном
+// круговом обходе: при заполненных очередях сообщения приоритета 5 пишутся
+// в 32 раза чаще, чем приоритета 0, но низкие приоритеты не голодают.
+var laneWeights = [laneCount]int{1, 2, 4, 8, 16, 32}
+
+// outboundQueue - буфер исходящих сообщений клиента с отдельной очередью на
+// каждый приоритет. Очереди выбираются сглаженным взвешенным round-robin,
+// поэтому срочное уведомление не ждёт, пока запишется поток низкоприоритетных.
+type outboundQueue struct {
+	mutex    sync.Mutex
+	lanes    [laneCount][]*outboundMessage
+	credits  [laneCount]int
+	size     int
+	capacity int
+	sequence uint64
+	closed   bool
+	// ready получает сигнал, когда в очереди появилось сообщение или она закрыта
+	ready chan struct{}
+}
+
+func newOutboundQueue(capacity int) *outboundQueue {
+	return &outboundQueue{
+		capacity: capacity,
+		ready:    make(chan struct{}, 1),
+	}
+}
+
+// push добавляет сообщение в очередь его приоритета. Возвращает false,
+// если буфер заполнен или очередь закрыта.
+func (q *outboundQueue) push(message *outboundMessage) bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if q.closed || q.size >= q.capacity {
+		return false
+	}
+
+	q.sequence++
+	message.sequence = q.sequence
+
+	lane := laneFor(message.priority)
+	q.lanes[lane] = append(q.lanes[lane], message)
+	q.size++
+	q.signal()
+
+	return true
+}
+
+// pop возвращает следующее сообщение для записи. Для пустой открытой очереди
+// возвращает nil и true, для пустой закрытой - nil и false.
+func (q *outboundQueue) pop() (*outboundMessage, bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if q.size == 0 {
+		return nil, !q.closed
+	}
+
+	selected, total := -1, 0
+	for lane := range q.lanes {
+		if len(q.lanes[lane]) == 0 {
+			continue
+		}
+
+		q.credits[lane] += laneWeights[lane]
+		total += laneWeights[lane]
+		if selected < 0 || q.credits[lane] > q.credits[selected] {
+			selected = lane
+		}
+	}
+	q.credits[selected] -= total
+
+	return q.removeHead(selected), true
+}
+
+// dropOldest удаляет самое давнее сообщение среди всех приоритетов.
+func (q *outboundQueue) dropOldest() *outboundMessage {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	oldest := -1
+	for lane := range q.lanes {
+		if len(q.lanes[lane]) == 0 {
+			continue
+		}
+		if oldest < 0 || q.lanes[lane][0].sequence < q.lanes[oldest][0].sequence {
+			oldest = lane
+		}
+	}
+
+	if oldest < 0 {
+		return nil
+	}
+
+	return q.removeHead(oldest)
+}
+
+// dropLowest удаляет самое давнее сообщение из очереди с наименьшим
+// приоритетом, если он не выше priority. Возвращает nil, если все
+// сообщения в буфере важнее.
+func (q *outboundQueue) dropLowest(priority int) *outboundMessage {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	for lane := 0; lane <= laneFor(priority); lane++ {
+		if len(q.lanes[lane]) > 0 {
+			return q.removeHead(lane)
+		}
+	}
+
+	return nil
+}
+
+func (q *outboundQueue) removeHead(lane int) *outboundMessage {
+	message := q.lanes[lane][0]
+	q.lanes[lane][0] = nil
+	q.lanes[lane] = q.lanes[lane][1:]
+	q.size--
+
+	// Опустевшая очередь не копит кредит, пока в неё ничего не пишут
+	if len(q.lanes[lane]) == 0 {
+		q.credits[lane] = 0
+	}
+
+	return message
+}
+
+// close запрещает добавление; уже поставленные сообщения остаются доступны pop.
+func (q *outboundQueue) close() {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.closed = true
+	q.signal()
+}
+
+func (q *outboundQueue) len() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	return q.size
+}
+
+func (q *outboundQueue) signal() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+func laneFor(priority int) int {
+	switch {
+	case priority < 0:
+		return 0
+	case priority >= laneCount:
+		return laneCount - 1
+	}
+
+	return priority
+}
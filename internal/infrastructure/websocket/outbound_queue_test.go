@@ -0,0 +1,177 @@
+package websocket
+
+import (
+	"fmt"
+	"testing"
+)
+
+// pushLanes ставит в очередь counts[priority] сообщений каждого приоритета.
+// ID сообщения - "p<приоритет>-<номер>".
+func pushLanes(t *testing.T, q *outboundQueue, counts map[int]int) {
+	t.Helper()
+
+	for priority := -1; priority <= laneCount; priority++ {
+		for i := 0; i < counts[priority]; i++ {
+			message := &outboundMessage{data: []byte(fmt.Sprintf("p%d-%d", priority, i)), priority: priority}
+			if !q.push(message) {
+				t.Fatalf("push(%s) = false", message.data)
+			}
+		}
+	}
+}
+
+// popN снимает n сообщений и проверяет, что внутри каждого приоритета
+// сохраняется порядок постановки.
+func popN(t *testing.T, q *outboundQueue, n int) []*outboundMessage {
+	t.Helper()
+
+	var popped []*outboundMessage
+	last := map[int]uint64{}
+	for i := 0; i < n; i++ {
+		message, ok := q.pop()
+		if message == nil || !ok {
+			t.Fatalf("pop #%d = %v, %v, want сообщение", i, message, ok)
+		}
+
+		lane := laneFor(message.priority)
+		if message.sequence < last[lane] {
+			t.Errorf("очередь %d: %s записано после более позднего сообщения", lane, message.data)
+		}
+		last[lane] = message.sequence
+		popped = append(popped, message)
+	}
+
+	return popped
+}
+
+func laneCounts(messages []*outboundMessage) map[int]int {
+	counts := map[int]int{}
+	for _, message := range messages {
+		counts[laneFor(message.priority)]++
+	}
+	return counts
+}
+
+func TestOutboundQueueWeightedOrder(t *testing.T) {
+	tests := []struct {
+		name   string
+		queued map[int]int
+		pops   int
+		// want - сколько сообщений каждой очереди записано за pops снятий
+		want map[int]int
+	}{
+		{
+			name:   "single lane",
+			queued: map[int]int{2: 3},
+			pops:   3,
+			want:   map[int]int{2: 3},
+		},
+		{
+			name:   "highest and lowest",
+			queued: map[int]int{0: 64, 5: 64},
+			pops:   33,
+			want:   map[int]int{0: 1, 5: 32},
+		},
+		{
+			name:   "all lanes share by weight",
+			queued: map[int]int{0: 63, 1: 63, 2: 63, 3: 63, 4: 63, 5: 63},
+			pops:   63,
+			want:   map[int]int{0: 1, 1: 2, 2: 4, 3: 8, 4: 16, 5: 32},
+		},
+		{
+			name:   "two cycles",
+			queued: map[int]int{1: 20, 3: 20},
+			pops:   20,
+			want:   map[int]int{1: 4, 3: 16},
+		},
+		{
+			name:   "priorities out of range",
+			queued: map[int]int{-1: 10, laneCount: 10},
+			pops:   11,
+			want:   map[int]int{0: 1, 5: 10},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := newOutboundQueue(1000)
+			pushLanes(t, q, tt.queued)
+
+			got := laneCounts(popN(t, q, tt.pops))
+			for lane := 0; lane < laneCount; lane++ {
+				if got[lane] != tt.want[lane] {
+					t.Errorf("записано по очередям %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestOutboundQueueEmptyLaneDoesNotStarve(t *testing.T) {
+	tests := []struct {
+		name   string
+		queued map[int]int
+		// lane должна получить запись не позже чем за within снятий
+		lane   int
+		within int
+	}{
+		{
+			name:   "lowest behind full highest",
+			queued: map[int]int{0: 1, 5: 100},
+			lane:   0,
+			within: 33,
+		},
+		{
+			name:   "gap between lanes",
+			queued: map[int]int{1: 1, 4: 100},
+			lane:   1,
+			within: 9,
+		},
+		{
+			name:   "only lowest",
+			queued: map[int]int{0: 1},
+			lane:   0,
+			within: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := newOutboundQueue(1000)
+			pushLanes(t, q, tt.queued)
+
+			if got := laneCounts(popN(t, q, tt.within)); got[tt.lane] != tt.queued[tt.lane] {
+				t.Errorf("за %d снятий из очереди %d записано %d, want %d", tt.within, tt.lane, got[tt.lane], tt.queued[tt.lane])
+			}
+		})
+	}
+}
+
+func TestOutboundQueueCreditsReset(t *testing.T) {
+	q := newOutboundQueue(1000)
+	queued := map[int]int{0: 2, 3: 5, 5: 40}
+
+	pushLanes(t, q, queued)
+	first := laneCounts(popN(t, q, 20))
+
+	// Опустевшая очередь не сохраняет накопленный кредит
+	pushLanes(t, q, map[int]int{1: 1})
+	popN(t, q, q.len())
+	for lane, credit := range q.credits {
+		if credit != 0 {
+			t.Errorf("кредит очереди %d = %d после опустошения, want 0", lane, credit)
+		}
+	}
+
+	// Повторное заполнение идёт тем же порядком, что и в новой очереди
+	pushLanes(t, q, queued)
+	second := laneCounts(popN(t, q, 20))
+	for lane := 0; lane < laneCount; lane++ {
+		if first[lane] != second[lane] {
+			t.Errorf("после опустошения записано %v, в новой очереди %v", second, first)
+			break
+		}
+	}
+
+}
@@ -0,0 +1,293 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+// pollBatchLimit ограничивает количество фреймов в одном ответе
+const pollBatchLimit = 100
+
+var errPollClosed = errors.New("long-poll сессия закрыта")
+
+// PollBatch - ответ на long-poll запрос. Cursor передаётся в следующем
+// запросе и подтверждает получение всех фреймов до него включительно.
+type PollBatch struct {
+	Messages []json.RawMessage `json:"messages"`
+	Cursor   string            `json:"cursor"`
+	Closed   *PollClose        `json:"closed,omitempty"`
+}
+
+// PollClose сообщает, что сессия закрыта сервером и следующий запрос откроет новую.
+type PollClose struct {
+	Code   int    `json:"code"`
+	Reason string `json:"reason"`
+}
+
+type pollFrame struct {
+	sequence uint64
+	data     []byte
+}
+
+// PollConnection - транспорт для клиентов без WebSocket и SSE. Фреймы
+// копятся до подтверждения курсором; сессия считается подключённой, пока
+// клиент опрашивает её чаще, чем раз в idleTimeout.
+type PollConnection struct {
+	session     string
+	idleTimeout time.Duration
+	capacity    int
+
+	mutex      sync.Mutex
+	frames     []pollFrame
+	sequence   uint64
+	acked      uint64
+	polling    int
+	closed     bool
+	closeFrame *PollClose
+	// changed закрывается и пересоздаётся при любом изменении состояния
+	changed chan struct{}
+
+	activity  chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func NewPollConnection(config *Config) *PollConnection {
+	return &PollConnection{
+		session:     uuid.New().String(),
+		idleTimeout: config.LongPollIdleTimeout,
+		capacity:    sendBufferSize(config),
+		changed:     make(chan struct{}),
+		activity:    make(chan struct{}, 1),
+		done:        make(chan struct{}),
+	}
+}
+
+// Poll подтверждает фреймы до cursor и ждёт новых не дольше timeout.
+// Возвращает пустой пакет, если за это время ничего не пришло.
+func (c *PollConnection) Poll(ctx context.Context, cursor string, timeout time.Duration) *PollBatch {
+	c.mutex.Lock()
+	c.ack(c.parseCursor(cursor))
+	c.polling++
+	c.mutex.Unlock()
+	c.touch()
+
+	defer func() {
+		c.mutex.Lock()
+		c.polling--
+		c.mutex.Unlock()
+		c.touch()
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		c.mutex.Lock()
+		if len(c.frames) > 0 || c.closed {
+			batch := c.batch()
+			c.mutex.Unlock()
+			return batch
+		}
+		changed := c.changed
+		c.mutex.Unlock()
+
+		select {
+		case <-changed:
+		case <-timer.C:
+			return c.currentBatch()
+		case <-ctx.Done():
+			return c.currentBatch()
+		}
+	}
+}
+
+// Closed сообщает, что сессия закрыта и новый запрос должен открыть другую.
+func (c *PollConnection) Closed() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.closed
+}
+
+// WriteFrame ждёт места, пока клиент не подтвердит полученные фреймы, -
+// так переполнение доходит до буфера клиента и его политики.
+func (c *PollConnection) WriteFrame(data []byte, id string) error {
+	for {
+		c.mutex.Lock()
+		if c.closed {
+			c.mutex.Unlock()
+			return errPollClosed
+		}
+
+		if len(c.frames) < c.capacity {
+			c.sequence++
+			c.frames = append(c.frames, pollFrame{sequence: c.sequence, data: data})
+			c.notify()
+			c.mutex.Unlock()
+			return nil
+		}
+
+		changed := c.changed
+		c.mutex.Unlock()
+
+		select {
+		case <-changed:
+		case <-c.done:
+		}
+	}
+}
+
+// WriteHeartbeat не нужен: запросы клиента сами служат признаком активности.
+func (c *PollConnection) WriteHeartbeat() error {
+	return nil
+}
+
+func (c *PollConnection) WriteClose(code int, reason string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.closeFrame = &PollClose{Code: code, Reason: reason}
+	c.notify()
+
+	return nil
+}
+
+// ReadLoop завершается, когда сессия закрыта или клиент перестал опрашивать её.
+func (c *PollConnection) ReadLoop(handle func(message []byte)) error {
+	idle := time.NewTimer(c.idleTimeout)
+	defer idle.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return nil
+		case <-c.activity:
+			if !idle.Stop() {
+				select {
+				case <-idle.C:
+				default:
+				}
+			}
+			idle.Reset(c.idleTimeout)
+		case <-idle.C:
+			c.mutex.Lock()
+			polling := c.polling > 0
+			c.mutex.Unlock()
+
+			if !polling {
+				return nil
+			}
+			idle.Reset(c.idleTimeout)
+		}
+	}
+}
+
+// Close оставляет недоставленные фреймы: ожидающий запрос получит их
+// вместе с признаком закрытия.
+func (c *PollConnection) Close() error {
+	c.closeOnce.Do(func() {
+		c.mutex.Lock()
+		c.closed = true
+		c.notify()
+		c.mutex.Unlock()
+
+		close(c.done)
+	})
+
+	return nil
+}
+
+func (c *PollConnection) currentBatch() *PollBatch {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.batch()
+}
+
+func (c *PollConnection) batch() *PollBatch {
+	count := len(c.frames)
+	if count > pollBatchLimit {
+		count = pollBatchLimit
+	}
+
+	batch := &PollBatch{
+		Messages: make([]json.RawMessage, 0, count),
+		Cursor:   c.formatCursor(c.acked),
+	}
+
+	for _, frame := range c.frames[:count] {
+		batch.Messages = append(batch.Messages, frame.data)
+		batch.Cursor = c.formatCursor(frame.sequence)
+	}
+
+	if c.closed && count == len(c.frames) {
+		batch.Closed = c.closeFrame
+		if batch.Closed == nil {
+			batch.Closed = &PollClose{Code: websocket.CloseNormalClosure}
+		}
+	}
+
+	return batch
+}
+
+func (c *PollConnection) ack(sequence uint64) {
+	if sequence > c.sequence {
+		sequence = c.sequence
+	}
+
+	if sequence <= c.acked {
+		return
+	}
+
+	c.acked = sequence
+
+	acked := 0
+	for acked < len(c.frames) && c.frames[acked].sequence <= sequence {
+		acked++
+	}
+
+	if acked > 0 {
+		c.frames = append(c.frames[:0:0], c.frames[acked:]...)
+		c.notify()
+	}
+}
+
+func (c *PollConnection) notify() {
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+func (c *PollConnection) touch() {
+	select {
+	case c.activity <- struct{}{}:
+	default:
+	}
+}
+
+func (c *PollConnection) formatCursor(sequence uint64) string {
+	return c.session + ":" + strconv.FormatUint(sequence, 10)
+}
+
+// parseCursor возвращает 0 для курсора другой сессии: её фреймы здесь не хранятся.
+func (c *PollConnection) parseCursor(cursor string) uint64 {
+	session, sequence, ok := strings.Cut(cursor, ":")
+	if !ok || session != c.session {
+		return 0
+	}
+
+	value, err := strconv.ParseUint(sequence, 10, 64)
+	if err != nil {
+		return 0
+	}
+
+	return value
+}
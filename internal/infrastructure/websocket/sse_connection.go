@@ -0,0 +1,114 @@
+package websocket
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sseConnection доставляет фреймы как Server-Sent Events. Канал
+// однонаправленный: команды от клиента через SSE не принимаются.
+type sseConnection struct {
+	writer     http.ResponseWriter
+	controller *http.ResponseController
+	request    *http.Request
+	closed     chan struct{}
+	closeOnce  sync.Once
+}
+
+// NewSSEConnection отправляет заголовки потока событий. Писать в соединение
+// можно только пока обработчик запроса не вернул управление.
+func NewSSEConnection(w http.ResponseWriter, r *http.Request) (Connection, error) {
+	controller := http.NewResponseController(w)
+
+	header := w.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	// Отключает буферизацию ответа в nginx
+	header.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if err := controller.Flush(); err != nil {
+		return nil, err
+	}
+
+	return &sseConnection{
+		writer:     w,
+		controller: controller,
+		request:    r,
+		closed:     make(chan struct{}),
+	}, nil
+}
+
+func (c *sseConnection) WriteFrame(data []byte, id string) error {
+	var buffer bytes.Buffer
+
+	// id получают только уведомления: по нему клиент возобновляет поток через Last-Event-ID
+	if id != "" {
+		buffer.WriteString("id: " + id + "\n")
+	}
+	writeData(&buffer, data)
+
+	return c.write(buffer.Bytes())
+}
+
+func (c *sseConnection) WriteHeartbeat() error {
+	return c.write([]byte(": ping\n\n"))
+}
+
+func (c *sseConnection) WriteClose(code int, reason string) error {
+	data, err := json.Marshal(map[string]interface{}{
+		"code":   code,
+		"reason": reason,
+	})
+	if err != nil {
+		return err
+	}
+
+	var buffer bytes.Buffer
+	buffer.WriteString("event: close\n")
+	writeData(&buffer, data)
+
+	return c.write(buffer.Bytes())
+}
+
+func (c *sseConnection) ReadLoop(handle func(message []byte)) error {
+	select {
+	case <-c.request.Context().Done():
+	case <-c.closed:
+	}
+
+	return nil
+}
+
+// Close только освобождает ReadLoop: ответ завершается, когда обработчик
+// запроса дождётся окончания записи.
+func (c *sseConnection) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+	})
+
+	return nil
+}
+
+func (c *sseConnection) write(data []byte) error {
+	// WriteTimeout сервера ограничивает весь ответ, поэтому срок продлевается на каждую запись
+	_ = c.controller.SetWriteDeadline(time.Now().Add(writeWait))
+
+	if _, err := c.writer.Write(data); err != nil {
+		return err
+	}
+
+	return c.controller.Flush()
+}
+
+func writeData(buffer *bytes.Buffer, data []byte) {
+	for _, line := range strings.Split(string(data), "\n") {
+		buffer.WriteString("data: " + line + "\n")
+	}
+	buffer.WriteString("\n")
+}
@@ -1,32 +1,117 @@
 package websocket
 
 import (
+	"context"
+	"encoding/json"
+	"math/rand"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/anatoly_dev/go-ws-notifications/internal/domain"
+	"github.com/anatoly_dev/go-ws-notifications/internal/infrastructure/metrics"
 	"github.com/anatoly_dev/go-ws-notifications/pkg/logger"
+	"github.com/gorilla/websocket"
 )
 
 type Service struct {
-	clients     map[string]*Client
-	clientsLock sync.RWMutex
-	logger      *logger.Logger
-	config      *Config
+	clients         map[string]*Client
+	clientsLock     sync.RWMutex
+	logger          *logger.Logger
+	config          *Config
+	draining        atomic.Bool
+	tracker         domain.DeliveryTracker
+	inboundHandler  InboundHandler
+	connectHandlers []ConnectHandler
+	spillHandler    SpillHandler
+	refillHandler   ConnectHandler
+	writeHandler    WriteHandler
+	channels        map[string]map[*Client]struct{}
+	channelsLock    sync.RWMutex
 }
 
 type Config struct {
-	ReadBufferSize  int
-	WriteBufferSize int
-	PongWait        int
-	PingPeriod      int
-	MaxMessageSize  int64
+	ReadBufferSize      int
+	WriteBufferSize     int
+	PongWait            int
+	PingPeriod          int
+	MaxMessageSize      int64
+	DrainRetryMin       time.Duration
+	DrainRetryMax       time.Duration
+	MaxChannels         int           // лимит подписок на каналы для одного сокета
+	SendBufferSize      int           // ёмкость буфера исходящих сообщений клиента
+	BackpressurePolicy  string        // поведение при переполнении буфера
+	LongPollTimeout     time.Duration // максимальное время удержания long-poll запроса
+	LongPollIdleTimeout time.Duration // long-poll сессия без запросов считается отключённой
 }
 
-func NewService(config *Config, logger *logger.Logger) *Service {
+// Политики переполнения буфера клиента: disconnect закрывает соединение,
+// drop_oldest и drop_lowest_priority вытесняют сообщение из буфера,
+// spill отправляет новое уведомление в офлайн-очередь.
+const (
+	PolicyDisconnect         = "disconnect"
+	PolicyDropOldest         = "drop_oldest"
+	PolicyDropLowestPriority = "drop_lowest_priority"
+	PolicySpill              = "spill"
+)
+
+type drainReason struct {
+	Reason       string `json:"reason"`
+	RetryAfterMs int64  `json:"retry_after_ms"`
+}
+
+func NewService(config *Config, tracker domain.DeliveryTracker, logger *logger.Logger) *Service {
 	return &Service{
-		clients: make(map[string]*Client),
-		logger:  logger,
-		config:  config,
+		clients:  make(map[string]*Client),
+		logger:   logger,
+		config:   config,
+		tracker:  tracker,
+		channels: make(map[string]map[*Client]struct{}),
+	}
+}
+
+// SetInboundHandler задаёт обработчик команд, приходящих от клиентов.
+// Должен вызываться до начала приёма соединений.
+func (s *Service) SetInboundHandler(handler InboundHandler) {
+	s.inboundHandler = handler
+}
+
+func (s *Service) HandleInbound(ctx context.Context, userID string, connectionID string, message []byte) {
+	if s.inboundHandler == nil {
+		return
+	}
+
+	s.inboundHandler(ctx, userID, connectionID, message)
+}
+
+// AddConnectHandler добавляет обработчик новых соединений, например для
+// доставки действующих объявлений. Должен вызываться до начала приёма соединений.
+func (s *Service) AddConnectHandler(handler ConnectHandler) {
+	s.connectHandlers = append(s.connectHandlers, handler)
+}
+
+// SetSpillHandler задаёт получателя уведомлений, не поместившихся в буфер
+// клиента при политике spill. Должен вызываться до начала приёма соединений.
+func (s *Service) SetSpillHandler(handler SpillHandler) {
+	s.spillHandler = handler
+}
+
+// SetRefillHandler задаёт обработчик, который возвращает клиенту
+// уведомления из офлайн-очереди, когда его буфер после переполнения
+// освободился. Должен вызываться до начала приёма соединений.
+func (s *Service) SetRefillHandler(handler ConnectHandler) {
+	s.refillHandler = handler
+}
+
+// SetWriteHandler задаёт обработчик записанных клиенту уведомлений,
+// например отметку доставки. Должен вызываться до начала приёма соединений.
+func (s *Service) SetWriteHandler(handler WriteHandler) {
+	s.writeHandler = handler
+}
+
+func (s *Service) HandleConnect(ctx context.Context, client *Client) {
+	for _, handler := range s.connectHandlers {
+		handler(ctx, client.userID, client.attributes)
 	}
 }
 
@@ -34,54 +119,127 @@ func (s *Service) RegisterClient(userID string, client *Client) {
 	s.clientsLock.Lock()
 	defer s.clientsLock.Unlock()
 
+	if s.draining.Load() {
+		s.logger.WithField("userID", userID).Info("Узел в режиме дренирования, соединение отклонено")
+		client.Drain(websocket.CloseServiceRestart, s.drainReason())
+		return
+	}
+
 	if existingClient, ok := s.clients[userID]; ok {
 		s.logger.WithField("userID", userID).Info("Закрытие существующего соединения для пользователя")
 		existingClient.Close()
+		s.removeSubscriptions(existingClient)
 	}
 
+	client.spill = s.spillHandler
+	client.refill = s.refillHandler
+	client.written = s.writeHandler
 	s.clients[userID] = client
+	metrics.ActiveUsers.Set(float64(len(s.clients)))
 	s.logger.WithField("userID", userID).Info("Пользователь подключен к WebSocket")
 }
 
-func (s *Service) UnregisterClient(userID string) {
+// UnregisterClient снимает подписки закрытого сокета на каналы и удаляет
+// клиента из реестра, только если он не был заменён более новым
+// соединением того же пользователя.
+func (s *Service) UnregisterClient(client *Client) {
+	s.removeSubscriptions(client)
+
 	s.clientsLock.Lock()
 	defer s.clientsLock.Unlock()
 
-	if _, ok := s.clients[userID]; ok {
-		delete(s.clients, userID)
-		s.logger.WithField("userID", userID).Info("Пользователь отключен от WebSocket")
+	if current, ok := s.clients[client.userID]; ok && current == client {
+		delete(s.clients, client.userID)
+		metrics.ActiveUsers.Set(float64(len(s.clients)))
+		s.logger.WithField("userID", client.userID).Info("Пользователь отключен от WebSocket")
 	}
 }
 
 func (s *Service) SendToUser(userID string, message []byte) error {
+	client, err := s.getClient(userID)
+	if err != nil {
+		return err
+	}
+
+	return client.Send(message)
+}
+
+func (s *Service) SendNotification(ctx context.Context, notification *domain.Notification, message []byte) error {
+	client, err := s.getClient(notification.UserID)
+	if err != nil {
+		s.tracker.Track(notification.ID, notification.UserID, domain.StageNotConnected, "")
+		return err
+	}
+
+	s.tracker.Track(notification.ID, notification.UserID, domain.StageClientFound, "")
+
+	return client.SendNotification(ctx, notification, message)
+}
+
+func (s *Service) IsConnected(userID string) bool {
+	s.clientsLock.RLock()
+	defer s.clientsLock.RUnlock()
+
+	_, ok := s.clients[userID]
+	return ok
+}
+
+// Connection возвращает транспорт текущего соединения пользователя.
+func (s *Service) Connection(userID string) (Connection, bool) {
+	s.clientsLock.RLock()
+	defer s.clientsLock.RUnlock()
+
+	client, ok := s.clients[userID]
+	if !ok {
+		return nil, false
+	}
+
+	return client.conn, true
+}
+
+func (s *Service) getClient(userID string) (*Client, error) {
 	s.clientsLock.RLock()
 	client, ok := s.clients[userID]
 	s.clientsLock.RUnlock()
 
 	if !ok {
 		s.logger.WithField("userID", userID).Warn("Попытка отправки сообщения отключенному пользователю")
-		return domain.ErrUserNotConnected
+		return nil, domain.ErrUserNotConnected
 	}
 
-	return client.Send(message)
+	return client, nil
 }
 
 func (s *Service) BroadcastMessage(message []byte) error {
+	s.BroadcastToAudience(nil, message)
+	return nil
+}
+
+// BroadcastToAudience отправляет сообщение подключённым клиентам, чьи
+// атрибуты подходят под audience (nil - всем), и возвращает их количество.
+func (s *Service) BroadcastToAudience(audience *domain.Audience, message []byte) int {
 	s.clientsLock.RLock()
 	defer s.clientsLock.RUnlock()
 
 	s.logger.WithField("clients_count", len(s.clients)).Info("Отправка широковещательного сообщения")
 
+	sent := 0
 	for userID, client := range s.clients {
+		if !audience.Matches(client.attributes) {
+			continue
+		}
+
 		if err := client.Send(message); err != nil {
 			s.logger.WithFields(map[string]interface{}{
 				"userID": userID,
 				"error":  err.Error(),
 			}).Error("Ошибка отправки сообщения клиенту")
+			continue
 		}
+		sent++
 	}
 
-	return nil
+	return sent
 }
 
 func (s *Service) GetClientCount() int {
@@ -89,3 +247,57 @@ func (s *Service) GetClientCount() int {
 	defer s.clientsLock.RUnlock()
 	return len(s.clients)
 }
+
+func (s *Service) IsDraining() bool {
+	return s.draining.Load()
+}
+
+// Drain переводит сервис в режим дренирования: новые подключения отклоняются,
+// а всем клиентам после отправки буферизованных сообщений отправляется
+// close-фрейм с просьбой переподключиться к другому узлу.
+func (s *Service) Drain(ctx context.Context) error {
+	s.draining.Store(true)
+
+	s.clientsLock.RLock()
+	clients := make([]*Client, 0, len(s.clients))
+	for _, client := range s.clients {
+		clients = append(clients, client)
+	}
+	s.clientsLock.RUnlock()
+
+	s.logger.WithField("clients_count", len(clients)).Info("Дренирование WebSocket соединений")
+
+	for _, client := range clients {
+		client.Drain(websocket.CloseServiceRestart, s.drainReason())
+	}
+
+	for _, client := range clients {
+		select {
+		case <-client.Done():
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	s.logger.Info("Все WebSocket соединения закрыты")
+	return nil
+}
+
+// RetryAfter возвращает случайную задержку переподключения из настроенного
+// окна, чтобы клиенты дренируемого узла не возвращались одновременно.
+func (s *Service) RetryAfter() time.Duration {
+	retryAfter := s.config.DrainRetryMin
+	if spread := s.config.DrainRetryMax - s.config.DrainRetryMin; spread > 0 {
+		retryAfter += time.Duration(rand.Int63n(int64(spread)))
+	}
+	return retryAfter
+}
+
+func (s *Service) drainReason() string {
+	reason, _ := json.Marshal(drainReason{
+		Reason:       "reconnect_elsewhere",
+		RetryAfterMs: s.RetryAfter().Milliseconds(),
+	})
+
+	return string(reason)
+}
@@ -1,26 +1,67 @@
 package logger
 
 import (
+	"sort"
+	"sync"
+
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
+type Config struct {
+	Level       string
+	Encoding    string
+	OutputPaths []string
+	Sampling    *SamplingConfig
+	DebugUsers  []string
+}
+
+type SamplingConfig struct {
+	Initial    int
+	Thereafter int
+}
+
 type Logger struct {
 	*zap.Logger
+	state *state
+}
+
+// state разделяется всеми логгерами, порождёнными от одного корневого,
+// поэтому изменение уровня через AtomicLevel() применяется сразу ко всем.
+type state struct {
+	level      zap.AtomicLevel
+	debugUsers map[string]struct{}
+	mutex      sync.RWMutex
+}
+
+func (s *state) isDebugUser(userID string) bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	_, ok := s.debugUsers[userID]
+	return ok
 }
 
 func NewLogger(level string, isProduction bool) (*Logger, error) {
+	encoding := "console"
+	if isProduction {
+		encoding = "json"
+	}
+
+	return New(Config{
+		Level:    level,
+		Encoding: encoding,
+	})
+}
+
+func New(cfg Config) (*Logger, error) {
 	var logLevel zapcore.Level
-	err := logLevel.UnmarshalText([]byte(level))
+	err := logLevel.UnmarshalText([]byte(cfg.Level))
 	if err != nil {
 		logLevel = zapcore.InfoLevel
 	}
 
-	var config zap.Config
-	if isProduction {
-		config = zap.NewProductionConfig()
-	} else {
-		config = zap.NewProductionConfig()
+	config := zap.NewProductionConfig()
+	if cfg.Encoding != "json" {
 		config.EncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
 		config.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
 		config.OutputPaths = []string{"stdout"}
@@ -31,29 +72,87 @@ func NewLogger(level string, isProduction bool) (*Logger, error) {
 		config.Encoding = "console"
 	}
 
-	config.Level = zap.NewAtomicLevelAt(logLevel)
+	if len(cfg.OutputPaths) > 0 {
+		config.OutputPaths = cfg.OutputPaths
+	}
+
+	config.Sampling = nil
+	if cfg.Sampling != nil {
+		config.Sampling = &zap.SamplingConfig{
+			Initial:    cfg.Sampling.Initial,
+			Thereafter: cfg.Sampling.Thereafter,
+		}
+	}
+
+	s := &state{
+		level:      zap.NewAtomicLevelAt(logLevel),
+		debugUsers: make(map[string]struct{}),
+	}
+	for _, userID := range cfg.DebugUsers {
+		s.debugUsers[userID] = struct{}{}
+	}
+
+	// Ядро пропускает все уровни, а фильтрация выполняется в levelFilterCore:
+	// так отладочные записи отдельных пользователей не требуют понижения
+	// глобального уровня.
+	config.Level = zap.NewAtomicLevelAt(zapcore.DebugLevel)
 	zapLogger, err := config.Build(
 		zap.AddCaller(),
 		zap.AddCallerSkip(1),
 		zap.AddStacktrace(zapcore.ErrorLevel),
+		zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			return &levelFilterCore{Core: core, state: s}
+		}),
 	)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Logger{Logger: zapLogger}, nil
+	return &Logger{Logger: zapLogger, state: s}, nil
+}
+
+// AtomicLevel возвращает атомарный уровень логирования. zap.AtomicLevel
+// реализует http.Handler, что позволяет менять уровень во время работы.
+func (l *Logger) AtomicLevel() zap.AtomicLevel {
+	return l.state.level
+}
+
+// SetUserDebug включает или выключает отладочное логирование
+// для одного пользователя без изменения глобального уровня.
+func (l *Logger) SetUserDebug(userID string, enabled bool) {
+	l.state.mutex.Lock()
+	defer l.state.mutex.Unlock()
+
+	if enabled {
+		l.state.debugUsers[userID] = struct{}{}
+	} else {
+		delete(l.state.debugUsers, userID)
+	}
+}
+
+func (l *Logger) DebugUsers() []string {
+	l.state.mutex.RLock()
+	defer l.state.mutex.RUnlock()
+
+	users := make([]string, 0, len(l.state.debugUsers))
+	for userID := range l.state.debugUsers {
+		users = append(users, userID)
+	}
+	sort.Strings(users)
+
+	return users
 }
 
 func (l *Logger) WithContext(fields ...zapcore.Field) *Logger {
-	return &Logger{Logger: l.With(fields...)}
+	return &Logger{Logger: l.With(fields...), state: l.state}
 }
 
 func (l *Logger) WithError(err error) *Logger {
-	return &Logger{Logger: l.With(zap.Error(err))}
+	return &Logger{Logger: l.With(zap.Error(err)), state: l.state}
 }
 
 func (l *Logger) WithField(key string, value interface{}) *Logger {
-	return &Logger{Logger: l.With(zap.Any(key, value))}
+	return l.withUser(key, value, &Logger{Logger: l.With(zap.Any(key, value)), state: l.state})
 }
 
 func (l *Logger) WithFields(fields map[string]interface{}) *Logger {
@@ -61,7 +160,29 @@ func (l *Logger) WithFields(fields map[string]interface{}) *Logger {
 	for k, v := range fields {
 		zapFields = append(zapFields, zap.Any(k, v))
 	}
-	return &Logger{Logger: l.With(zapFields...)}
+	result := &Logger{Logger: l.With(zapFields...), state: l.state}
+	if userID, ok := fields[userIDKey]; ok {
+		result = l.withUser(userIDKey, userID, result)
+	}
+	return result
+}
+
+const userIDKey = "userID"
+
+func (l *Logger) withUser(key string, value interface{}, logger *Logger) *Logger {
+	userID, ok := value.(string)
+	if key != userIDKey || !ok || userID == "" {
+		return logger
+	}
+
+	logger.Logger = logger.Logger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		if filter, ok := core.(*levelFilterCore); ok {
+			return &levelFilterCore{Core: filter.Core, state: filter.state, userID: userID}
+		}
+		return core
+	}))
+
+	return logger
 }
 
 func (l *Logger) Info(msg string, fields ...zapcore.Field) {
@@ -87,3 +208,27 @@ func (l *Logger) Fatal(msg string, fields ...zapcore.Field) {
 func (l *Logger) Sync() {
 	_ = l.Logger.Sync()
 }
+
+type levelFilterCore struct {
+	zapcore.Core
+	state  *state
+	userID string
+}
+
+func (c *levelFilterCore) Enabled(level zapcore.Level) bool {
+	if c.state.level.Enabled(level) {
+		return true
+	}
+	return c.userID != "" && c.state.isDebugUser(c.userID)
+}
+
+func (c *levelFilterCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelFilterCore{Core: c.Core.With(fields), state: c.state, userID: c.userID}
+}
+
+func (c *levelFilterCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Enabled(entry.Level) {
+		return checked
+	}
+	return c.Core.Check(entry, checked)
+}